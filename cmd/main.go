@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"syscall"
 
 	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/sbi"
 	"github.com/Alonza0314/nf-example/pkg/factory"
 	"github.com/Alonza0314/nf-example/pkg/service"
 	"github.com/urfave/cli"
@@ -40,6 +42,23 @@ func main() {
 			Usage: "Output NF log to `FILE`",
 		},
 	}
+	app.Commands = []cli.Command{
+		{
+			Name:   "openapi",
+			Usage:  "Dump the OpenAPI 3 document of the SBI",
+			Action: dumpOpenAPI,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "config, c",
+					Usage: "Describe the servers and security of configuration `FILE`",
+				},
+				cli.StringFlag{
+					Name:  "output, o",
+					Usage: "Write the document to `FILE` instead of stdout",
+				},
+			},
+		},
+	}
 	if err := app.Run(os.Args); err != nil {
 		logger.MainLog.Errorf("ANYA Run Error: %v\n", err)
 	}
@@ -62,22 +81,52 @@ func action(cliCtx *cli.Context) error {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 
-	go func() {
-		<-sigCh  // Wait for interrupt signal to gracefully shutdown
-		cancel() // Notify each goroutine and wait them stopped
-	}()
-
 	nf, err := service.NewApp(ctx, cfg, tlsKeyLogPath)
 	if err != nil {
+		cancel()
 		return err
 	}
 	NF = nf
 
+	go func() {
+		<-sigCh  // Wait for interrupt signal to gracefully shutdown
+		cancel() // Notify each goroutine and wait them stopped
+		<-sigCh  // A second signal skips the rest of the drain period
+		nf.EndDrain()
+	}()
+
 	nf.Start()
 
 	return nil
 }
 
+func dumpOpenAPI(cliCtx *cli.Context) error {
+	cfgPath := cliCtx.String("config")
+	if cfgPath == "" {
+		cfgPath = cliCtx.GlobalString("config")
+	}
+
+	var cfg *factory.Config
+	if cfgPath != "" {
+		var err error
+		if cfg, err = factory.ReadConfig(cfgPath); err != nil {
+			return err
+		}
+	}
+
+	doc, err := json.MarshalIndent(sbi.OpenAPIDocument(cfg), "", "  ")
+	if err != nil {
+		return err
+	}
+	doc = append(doc, '\n')
+
+	if output := cliCtx.String("output"); output != "" {
+		return os.WriteFile(output, doc, 0o644)
+	}
+	_, err = os.Stdout.Write(doc)
+	return err
+}
+
 func initLogFile(logNfPath []string) (string, error) {
 	logTlsKeyPath := ""
 
@@ -1,19 +1,213 @@
 package context
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
-	"sync"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/storage"
 	"github.com/Alonza0314/nf-example/pkg/factory"
 	"github.com/google/uuid"
 
 	"github.com/free5gc/openapi/models"
 )
 
+const (
+	SpyFamilyCollection         = "spyfamily"
+	MessageRecordCollection     = "messageRecord"
+	TaskCollection              = "tasks"
+	MessageCollection           = "messages"
+	DragonBallCollection        = "dragonball"
+	TournamentCollection        = "dragonballTournaments"
+	FortuneCollection           = "fortunes"
+	AttendanceCollection        = "attendance"
+	AttendanceSessionCollection = "attendanceSessions"
+	TimeZoneCollection          = "timezone"
+	OnePieceCollection          = "onepiece"
+)
+
+type TaskStatus string
+
+const (
+	TaskStatusTodo  TaskStatus = "todo"
+	TaskStatusDoing TaskStatus = "doing"
+	TaskStatusDone  TaskStatus = "done"
+)
+
+func (s TaskStatus) IsValid() bool {
+	switch s {
+	case TaskStatusTodo, TaskStatusDoing, TaskStatusDone:
+		return true
+	}
+	return false
+}
+
 type Task struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Status      TaskStatus `json:"status"`
+	Priority    int        `json:"priority"`
+	DueDate     *time.Time `json:"dueDate,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// FortuneRank is the omikuji rank of a fortune, FortuneRanks lists them from the best one.
+type FortuneRank string
+
+const (
+	FortuneRankDaikichi FortuneRank = "大吉"
+	FortuneRankChukichi FortuneRank = "中吉"
+	FortuneRankShokichi FortuneRank = "小吉"
+	FortuneRankKichi    FortuneRank = "吉"
+	FortuneRankSuekichi FortuneRank = "末吉"
+	FortuneRankKyo      FortuneRank = "凶"
+	FortuneRankDaikyo   FortuneRank = "大凶"
+)
+
+var FortuneRanks = []FortuneRank{
+	FortuneRankDaikichi,
+	FortuneRankChukichi,
+	FortuneRankShokichi,
+	FortuneRankKichi,
+	FortuneRankSuekichi,
+	FortuneRankKyo,
+	FortuneRankDaikyo,
+}
+
+func (r FortuneRank) IsValid() bool {
+	for _, rank := range FortuneRanks {
+		if r == rank {
+			return true
+		}
+	}
+	return false
+}
+
+// SpyFamilyCharacter is stored under its first name.
+type SpyFamilyCharacter struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	// Family defaults to the last name
+	Family      string `json:"family"`
+	Affiliation string `json:"affiliation,omitempty"`
+	Codename    string `json:"codename,omitempty"`
+}
+
+func (c SpyFamilyCharacter) FullName() string {
+	return strings.TrimSpace(c.FirstName + " " + c.LastName)
+}
+
+// UnmarshalJSON also reads the last names characters were stored as before they had records,
+// the first name of such a character is its key.
+func (c *SpyFamilyCharacter) UnmarshalJSON(data []byte) error {
+	var lastName string
+	if err := json.Unmarshal(data, &lastName); err == nil {
+		*c = SpyFamilyCharacter{LastName: lastName, Family: lastName}
+		return nil
+	}
+	type character SpyFamilyCharacter
+	return json.Unmarshal(data, (*character)(c))
+}
+
+// Fortune is stored under its ID. Weight is its relative chance of being drawn.
+type Fortune struct {
+	ID     int         `json:"id"`
+	Rank   FortuneRank `json:"rank"`
+	Text   string      `json:"text"`
+	Weight int         `json:"weight"`
+}
+
+// ParseFortuneText splits a "大吉: text" fortune into its rank and text, a text without
+// a rank prefix gets the fallback rank.
+func ParseFortuneText(text string, fallback FortuneRank) (FortuneRank, string) {
+	if prefix, rest, ok := strings.Cut(text, ":"); ok && FortuneRank(prefix).IsValid() {
+		return FortuneRank(prefix), strings.TrimSpace(rest)
+	}
+	return fallback, strings.TrimSpace(text)
+}
+
+// UnmarshalJSON also reads the plain strings fortunes were stored as before they had a rank.
+func (f *Fortune) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		f.Rank, f.Text = ParseFortuneText(text, FortuneRankKichi)
+		f.Weight = 1
+		return nil
+	}
+	type fortune Fortune
+	return json.Unmarshal(data, (*fortune)(f))
+}
+
+// Tournament is a single-elimination bracket resolved by power level, stored under its ID.
+type Tournament struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Entrants are ordered by seed, the strongest character is seed 1
+	Entrants []TournamentEntrant `json:"entrants"`
+	Rounds   []TournamentRound   `json:"rounds"`
+	Champion TournamentEntrant   `json:"champion"`
+}
+
+type TournamentEntrant struct {
+	Seed       int    `json:"seed"`
+	Name       string `json:"name"`
+	PowerLevel int32  `json:"powerLevel"`
+}
+
+type TournamentRound struct {
+	Round   int               `json:"round"`
+	Name    string            `json:"name"`
+	Matches []TournamentMatch `json:"matches"`
+}
+
+// TournamentMatch is a fight of the bracket, a bye has no second fighter.
+type TournamentMatch struct {
+	Match    int                `json:"match"`
+	Fighter1 TournamentEntrant  `json:"fighter1"`
+	Fighter2 *TournamentEntrant `json:"fighter2,omitempty"`
+	Winner   string             `json:"winner"`
+	// Decision tells how the winner was chosen: power, seed (equal power levels) or bye
+	Decision string `json:"decision"`
+}
+
+// CrewMember is a member of the Straw Hat crew, stored under the lower-case name so a
+// name differing only in case is the same member.
+type CrewMember struct {
+	Name     string    `json:"name"`
+	Role     string    `json:"role,omitempty"`
+	Bounty   int64     `json:"bounty"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// AttendanceSession is a named attendance list, such as a class on a day, stored under its name.
+type AttendanceSession struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Records are ordered by check-in, a person checking in again after a check-out gets a new record
+	Records []AttendanceRecord `json:"records"`
+}
+
+// AttendanceRecord is the stay of a person in a session, CheckOut is nil while the person is present.
+type AttendanceRecord struct {
+	Name     string     `json:"name"`
+	CheckIn  time.Time  `json:"checkIn"`
+	CheckOut *time.Time `json:"checkOut,omitempty"`
+}
+
+// Present returns the index of the open record of the person, -1 if the person is not checked in.
+func (s *AttendanceSession) Present(name string) int {
+	for i := len(s.Records) - 1; i >= 0; i-- {
+		if s.Records[i].Name == name && s.Records[i].CheckOut == nil {
+			return i
+		}
+	}
+	return -1
 }
 
 type NFContext struct {
@@ -21,26 +215,32 @@ type NFContext struct {
 	Name        string
 	UriScheme   models.UriScheme
 	BindingIPv4 string
-	SBIPort     int
-
-	SpyFamilyData map[string]string
+	// BindingIPs lists every SBI address, BindingIPv4 first when set
+	BindingIPs []string
+	SBIPort    int
 
-	MessageRecord []string
-	MessageMu     sync.Mutex
+	NrfUri          string
+	HeartbeatTimer  int32
+	ServiceNameList []string
 
-	Tasks      []Task
-	TaskMutex  sync.RWMutex
-	NextTaskID uint64
+	// Storage holds the domain data of every route group, see the *Collection constants.
+	// Handlers go through the domain stores below, set together with Storage by UseStorage.
+	Storage storage.Storage
 
-	Messages       []Message
-	DragonBallData map[string]int32
+	SpyFamily      *Store[SpyFamilyCharacter]
+	MessageRecords *Store[string]
+	Tasks          *Store[Task]
+	Messages       *Store[Message]
+	DragonBall     *Store[int32]
+	Tournaments    *Store[Tournament]
+	Fortunes       *Store[Fortune]
+	Attendance     *Store[string]
+	Sessions       *Store[AttendanceSession]
+	TimeZones      *Store[string]
+	Crew           *Store[CrewMember]
 
-	Fortunes     []string
-	FortuneMutex sync.RWMutex
-
-	AttendanceData []string
-
-	TimeZoneData map[string]string
+	// MessageFeed streams the messages posted to /msg, nil disables the live feed
+	MessageFeed *MessageFeed
 }
 
 type Message struct {
@@ -48,11 +248,46 @@ type Message struct {
 	Content string `json:"content"`
 	Author  string `json:"author"`
 	Time    string `json:"time"`
+
+	// ParentID is the message this one replies to, empty for the first message of a thread
+	ParentID string `json:"parentId,omitempty"`
+	// ThreadID is the ID of the first message of the thread
+	ThreadID string `json:"threadId,omitempty"`
+
+	EditedAt string        `json:"editedAt,omitempty"`
+	History  []MessageEdit `json:"history,omitempty"`
+
+	// Deleted messages are kept so their replies stay in the thread, their content is not served
+	Deleted   bool   `json:"deleted,omitempty"`
+	DeletedAt string `json:"deletedAt,omitempty"`
+}
+
+// MessageEdit is a previous content of a message, replaced at EditedAt.
+type MessageEdit struct {
+	Content  string `json:"content"`
+	EditedAt string `json:"editedAt"`
+}
+
+// Thread returns the thread of the message, messages stored before threads existed start their own.
+func (m *Message) Thread() string {
+	if m.ThreadID == "" {
+		return m.ID
+	}
+	return m.ThreadID
+}
+
+// Redacted hides the content and edit history of a deleted message.
+func (m Message) Redacted() Message {
+	if m.Deleted {
+		m.Content = ""
+		m.History = nil
+	}
+	return m
 }
 
 var nfContext = NFContext{}
 
-func InitNfContext() {
+func InitNfContext() error {
 	cfg := factory.NfConfig
 
 	nfContext.NfId = uuid.New().String()
@@ -65,69 +300,58 @@ func InitNfContext() {
 		logger.CtxLog.Info("Parsing ServerIPv4 address from ENV Variable.")
 	} else {
 		nfContext.BindingIPv4 = cfg.Configuration.Sbi.BindingIPv4
-		if nfContext.BindingIPv4 == "" {
+		if nfContext.BindingIPv4 == "" && len(cfg.Configuration.Sbi.GetBindingIPs()) == 0 {
 			logger.CtxLog.Warn("Error parsing ServerIPv4 address as string. Using the 0.0.0.0 address as default.")
 			nfContext.BindingIPv4 = "0.0.0.0"
 		}
 	}
-	nfContext.SpyFamilyData = map[string]string{
-		"Loid":   "Forger",
-		"Anya":   "Forger",
-		"Yor":    "Forger",
-		"Bond":   "Forger",
-		"Becky":  "Blackbell",
-		"Damian": "Desmond",
-		"Franky": "Franklin",
-		"Fiona":  "Frost",
-		"Sylvia": "Sherwood",
-		"Yuri":   "Briar",
-		"Millie": "Manis",
-		"Ewen":   "Egeburg",
-		"Emile":  "Elman",
-		"Henry":  "Henderson",
-		"Martha": "Marriott",
+	nfContext.BindingIPs = nil
+	if nfContext.BindingIPv4 != "" {
+		nfContext.BindingIPs = append(nfContext.BindingIPs, nfContext.BindingIPv4)
+	}
+	for _, ip := range cfg.Configuration.Sbi.GetBindingIPs() {
+		if ip != cfg.Configuration.Sbi.BindingIPv4 && !slices.Contains(nfContext.BindingIPs, ip) {
+			nfContext.BindingIPs = append(nfContext.BindingIPs, ip)
+		}
 	}
-	nfContext.AttendanceData = []string{}
-
-	nfContext.MessageRecord = []string{}
-
-	nfContext.Tasks = make([]Task, 0)
-	nfContext.NextTaskID = 0
-
-	nfContext.Messages = make([]Message, 0)
 
-	nfContext.DragonBallData = map[string]int32{
-		"Goku":    7,
-		"Vegeta":  6,
-		"Gohan":   5,
-		"Trunks":  4,
-		"Piccolo": 3,
-		"Krillin": 2,
-		"Yamcha":  1,
+	nfContext.NrfUri = cfg.GetNrfUri()
+	nfContext.HeartbeatTimer = cfg.GetHeartbeatTimer()
+	nfContext.ServiceNameList = make([]string, 0, len(factory.AllServiceNames))
+	for _, serviceName := range factory.AllServiceNames {
+		if cfg.IsServiceEnabled(serviceName) {
+			nfContext.ServiceNameList = append(nfContext.ServiceNameList, serviceName)
+		}
 	}
 
-	nfContext.Fortunes = []string{
-		"大吉: All your endeavors will be successful.",
-		"中吉: You will have good luck, but be cautious.",
-		"小吉: A small amount of luck is coming your way.",
-		"吉: Good fortune is with you.",
-		"末吉: Your luck is gradually improving.",
-		"凶: Be careful, misfortune may be ahead.",
-		"大凶: A great misfortune is coming. Be prepared.",
+	st, err := storage.New(cfg.Configuration.Storage)
+	if err != nil {
+		return fmt.Errorf("open storage: %w", err)
 	}
+	nfContext.UseStorage(st)
+	nfContext.MessageFeed = NewMessageFeed(MessageFeedCapacity)
 
-	nfContext.TimeZoneData = map[string]string{
-		"Taipei":  "UTC+8",
-		"Tokyo":   "UTC+9",
-		"Seoul":   "UTC+9",
-		"NewYork": "UTC-5",
-		"Paris":   "UTC+2",
-		"London":  "UTC+1",
-		"Berlin":  "UTC+2",
-		"Sydney":  "UTC+10",
-		"Moscow":  "UTC+3",
-		"Dubai":   "UTC+4",
+	data, err := LoadSeedData(cfg.GetSeed())
+	if err != nil {
+		return err
 	}
+	return nfContext.SeedWith(data)
+}
+
+// UseStorage keeps the domain data in st, each domain store locks its own collection.
+func (c *NFContext) UseStorage(st storage.Storage) {
+	c.Storage = st
+	c.SpyFamily = NewStore[SpyFamilyCharacter](st, SpyFamilyCollection)
+	c.MessageRecords = NewStore[string](st, MessageRecordCollection)
+	c.Tasks = NewStore[Task](st, TaskCollection)
+	c.Messages = NewStore[Message](st, MessageCollection)
+	c.DragonBall = NewStore[int32](st, DragonBallCollection)
+	c.Tournaments = NewStore[Tournament](st, TournamentCollection)
+	c.Fortunes = NewStore[Fortune](st, FortuneCollection)
+	c.Attendance = NewStore[string](st, AttendanceCollection)
+	c.Sessions = NewStore[AttendanceSession](st, AttendanceSessionCollection)
+	c.TimeZones = NewStore[string](st, TimeZoneCollection)
+	c.Crew = NewStore[CrewMember](st, OnePieceCollection)
 }
 
 func GetSelf() *NFContext {
@@ -0,0 +1,157 @@
+package context
+
+import (
+	"sync"
+)
+
+const (
+	// MessageFeedCapacity is how many recent messages a reconnecting subscriber can resume from
+	MessageFeedCapacity = 100
+	// messageSubscriberBuffer is how many events a subscriber may lag behind before it is dropped
+	messageSubscriberBuffer = 16
+)
+
+// MessageEvent is a posted message numbered in publishing order, the ID is the SSE event ID.
+type MessageEvent struct {
+	ID      uint64
+	Message Message
+}
+
+// MessageSubscription receives the events published after Subscribe. C is closed when the
+// subscriber falls behind or the feed is closed, the client then resumes with the last event ID.
+type MessageSubscription struct {
+	C <-chan MessageEvent
+
+	ch     chan MessageEvent
+	accept func(*Message) bool
+}
+
+// MessageFeed fans new messages out to the live subscribers and keeps the most recent
+// ones in a ring buffer for subscribers resuming after a disconnect.
+type MessageFeed struct {
+	mu sync.Mutex
+
+	lastID uint64
+	ring   []MessageEvent
+	// next is the ring slot written by the next Publish
+	next int
+	full bool
+
+	subscribers map[*MessageSubscription]struct{}
+	closed      bool
+}
+
+func NewMessageFeed(capacity int) *MessageFeed {
+	return &MessageFeed{
+		ring:        make([]MessageEvent, capacity),
+		subscribers: make(map[*MessageSubscription]struct{}),
+	}
+}
+
+// Publish numbers the message and delivers it to the subscribers accepting it.
+// A nil feed discards the message.
+func (f *MessageFeed) Publish(message Message) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+
+	f.lastID++
+	event := MessageEvent{ID: f.lastID, Message: message}
+	if len(f.ring) > 0 {
+		f.ring[f.next] = event
+		f.next = (f.next + 1) % len(f.ring)
+		f.full = f.full || f.next == 0
+	}
+
+	for sub := range f.subscribers {
+		if !sub.accept(&event.Message) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// never block the poster on a slow client
+			delete(f.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe registers a subscriber for the messages accepted by accept, nil accepts all.
+// When resuming, the buffered events after lastEventID are returned for replay and
+// complete reports whether none of the missed events already left the ring buffer.
+func (f *MessageFeed) Subscribe(accept func(*Message) bool, resume bool, lastEventID uint64) (
+	sub *MessageSubscription, backlog []MessageEvent, complete bool,
+) {
+	if accept == nil {
+		accept = func(*Message) bool { return true }
+	}
+	ch := make(chan MessageEvent, messageSubscriberBuffer)
+	sub = &MessageSubscription{C: ch, ch: ch, accept: accept}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		close(ch)
+		return sub, nil, true
+	}
+	f.subscribers[sub] = struct{}{}
+	if !resume {
+		return sub, nil, true
+	}
+
+	complete = true
+	if lastEventID > f.lastID {
+		// the ID comes from before a restart, replay everything still buffered
+		lastEventID, complete = 0, false
+	}
+	buffered := f.buffered()
+	if len(buffered) > 0 && lastEventID+1 < buffered[0].ID {
+		complete = false
+	}
+	for _, event := range buffered {
+		if event.ID > lastEventID && accept(&event.Message) {
+			backlog = append(backlog, event)
+		}
+	}
+	return sub, backlog, complete
+}
+
+// buffered returns the ring content from the oldest to the newest event.
+func (f *MessageFeed) buffered() []MessageEvent {
+	if !f.full {
+		return f.ring[:f.next]
+	}
+	return append(append([]MessageEvent{}, f.ring[f.next:]...), f.ring[:f.next]...)
+}
+
+func (f *MessageFeed) Unsubscribe(sub *MessageSubscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.subscribers[sub]; ok {
+		delete(f.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// Close ends every subscription, so open streams return before the server shuts down.
+func (f *MessageFeed) Close() {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for sub := range f.subscribers {
+		delete(f.subscribers, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,88 @@
+package context_test
+
+import (
+	"testing"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func publish(feed *nf_context.MessageFeed, authors ...string) {
+	for _, author := range authors {
+		feed.Publish(nf_context.Message{Author: author})
+	}
+}
+
+func eventIDs(events []nf_context.MessageEvent) []uint64 {
+	ids := make([]uint64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func Test_MessageFeedLive(t *testing.T) {
+	feed := nf_context.NewMessageFeed(4)
+	publish(feed, "Anya")
+
+	onlyAnya := func(m *nf_context.Message) bool { return m.Author == "Anya" }
+	sub, backlog, complete := feed.Subscribe(onlyAnya, false, 0)
+	assert.Empty(t, backlog, "a new subscriber only gets new messages")
+	assert.True(t, complete)
+
+	publish(feed, "Loid", "Anya")
+	event := <-sub.C
+	assert.Equal(t, uint64(3), event.ID)
+	assert.Equal(t, "Anya", event.Message.Author)
+
+	feed.Unsubscribe(sub)
+	_, ok := <-sub.C
+	assert.False(t, ok)
+}
+
+func Test_MessageFeedResume(t *testing.T) {
+	feed := nf_context.NewMessageFeed(3)
+	publish(feed, "Anya", "Loid", "Yor", "Bond", "Anya") // the ring keeps the events 3 to 5
+
+	tests := []struct {
+		name             string
+		lastEventID      uint64
+		expectedIDs      []uint64
+		expectedComplete bool
+	}{
+		{name: "Within the ring", lastEventID: 3, expectedIDs: []uint64{4, 5}, expectedComplete: true},
+		{name: "Right before the ring", lastEventID: 2, expectedIDs: []uint64{3, 4, 5}, expectedComplete: true},
+		{name: "Older than the ring", lastEventID: 1, expectedIDs: []uint64{3, 4, 5}, expectedComplete: false},
+		{name: "Up to date", lastEventID: 5, expectedIDs: []uint64{}, expectedComplete: true},
+		{name: "Before a restart", lastEventID: 42, expectedIDs: []uint64{3, 4, 5}, expectedComplete: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, backlog, complete := feed.Subscribe(nil, true, tt.lastEventID)
+			defer feed.Unsubscribe(sub)
+			assert.Equal(t, tt.expectedIDs, eventIDs(backlog))
+			assert.Equal(t, tt.expectedComplete, complete)
+		})
+	}
+}
+
+func Test_MessageFeedDropsSlowSubscriber(t *testing.T) {
+	feed := nf_context.NewMessageFeed(4)
+	sub, _, _ := feed.Subscribe(nil, false, 0)
+
+	// publishing never blocks, the subscriber is dropped once its buffer is full
+	for i := 0; i < 100; i++ {
+		publish(feed, "Anya")
+	}
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.Less(t, received, 100)
+
+	feed.Close()
+	sub, _, _ = feed.Subscribe(nil, false, 0)
+	_, ok := <-sub.C
+	require.False(t, ok, "a closed feed ends subscriptions at once")
+}
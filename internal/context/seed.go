@@ -0,0 +1,313 @@
+package context
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"gopkg.in/yaml.v2"
+)
+
+// FortuneMaxWeight bounds the relative chance of drawing a fortune.
+const FortuneMaxWeight = 100
+
+// builtinSeed holds the data of every domain without a seed file in the configuration.
+//
+//go:embed seed/*.json
+var builtinSeed embed.FS
+
+// SeedData is stored in the domain collections that are empty. The files of
+// configuration.seed use the same fields as the JSON of the built-in data.
+type SeedData struct {
+	SpyFamily  []SpyFamilyCharacter
+	DragonBall []DragonBallSeed
+	Fortunes   []Fortune
+	TimeZones  []TimeZoneSeed
+}
+
+type DragonBallSeed struct {
+	Name       string `json:"name"`
+	PowerLevel int32  `json:"powerLevel"`
+}
+
+type TimeZoneSeed struct {
+	City     string `json:"city"`
+	TimeZone string `json:"timeZone"`
+}
+
+// LoadSeedData reads and validates the seed file of every domain, files is nil when every
+// domain uses the built-in data. Nothing is stored, so a bad file is refused before any write.
+func LoadSeedData(files *factory.Seed) (*SeedData, error) {
+	if files == nil {
+		files = &factory.Seed{}
+	}
+	data := &SeedData{}
+	if err := loadSeed(files.SpyFamily, "spyfamily.json", &data.SpyFamily, validateSpyFamilySeed); err != nil {
+		return nil, err
+	}
+	if err := loadSeed(files.DragonBall, "dragonball.json", &data.DragonBall, validateDragonBallSeed); err != nil {
+		return nil, err
+	}
+	if err := loadSeed(files.Fortune, "fortune.json", &data.Fortunes, validateFortuneSeed); err != nil {
+		return nil, err
+	}
+	if err := loadSeed(files.TimeZone, "timezone.json", &data.TimeZones, validateTimeZoneSeed); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// loadSeed decodes the file at path, or the built-in file when path is empty, into values.
+func loadSeed[T any](path string, builtin string, values *[]T, validate func([]T) error) error {
+	source := "built-in " + builtin
+	var (
+		content []byte
+		err     error
+	)
+	if path == "" {
+		content, err = builtinSeed.ReadFile("seed/" + builtin)
+	} else {
+		source = path
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("read seed [%s]: %w", source, err)
+	}
+
+	if ext := strings.ToLower(filepath.Ext(source)); ext == ".yaml" || ext == ".yml" {
+		if content, err = yamlToJSON(content); err != nil {
+			return fmt.Errorf("decode seed [%s]: %w", source, err)
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(values); err != nil {
+		return fmt.Errorf("decode seed [%s]: %w", source, err)
+	}
+	if err = validate(*values); err != nil {
+		return fmt.Errorf("invalid seed [%s]: %w", source, err)
+	}
+	logger.CtxLog.Debugf("Read %d seed entries from [%s]", len(*values), source)
+	return nil
+}
+
+// yamlToJSON converts the document so the JSON field names and decoders apply to YAML too.
+func yamlToJSON(content []byte) ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	var convert func(v any) (any, error)
+	convert = func(v any) (any, error) {
+		switch v := v.(type) {
+		case map[any]any:
+			m := make(map[string]any, len(v))
+			for key, value := range v {
+				name, ok := key.(string)
+				if !ok {
+					return nil, fmt.Errorf("key %v is not a string", key)
+				}
+				var err error
+				if m[name], err = convert(value); err != nil {
+					return nil, err
+				}
+			}
+			return m, nil
+		case []any:
+			for i, value := range v {
+				var err error
+				if v[i], err = convert(value); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return v, nil
+	}
+	doc, err := convert(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+func validateSpyFamilySeed(characters []SpyFamilyCharacter) error {
+	seen := make(map[string]bool, len(characters))
+	for i := range characters {
+		character := &characters[i]
+		if character.FirstName == "" || character.LastName == "" {
+			return fmt.Errorf("entry %d: firstName and lastName are required", i+1)
+		}
+		if seen[character.FirstName] {
+			return fmt.Errorf("entry %d: %s is listed twice", i+1, character.FirstName)
+		}
+		seen[character.FirstName] = true
+		if character.Family == "" {
+			character.Family = character.LastName
+		}
+	}
+	return nil
+}
+
+func validateDragonBallSeed(characters []DragonBallSeed) error {
+	seen := make(map[string]bool, len(characters))
+	for i, character := range characters {
+		if character.Name == "" {
+			return fmt.Errorf("entry %d: name is required", i+1)
+		}
+		if seen[character.Name] {
+			return fmt.Errorf("entry %d: %s is listed twice", i+1, character.Name)
+		}
+		seen[character.Name] = true
+	}
+	return nil
+}
+
+// validateFortuneSeed also accepts the "大吉: text" fortunes of POST /fortune/, a fortune
+// without a rank is 吉 and without a weight is drawn with weight 1.
+func validateFortuneSeed(fortunes []Fortune) error {
+	seen := make(map[string]bool, len(fortunes))
+	for i := range fortunes {
+		fortune := &fortunes[i]
+		fallback := fortune.Rank
+		if fallback == "" {
+			fallback = FortuneRankKichi
+		}
+		rank, text := ParseFortuneText(fortune.Text, fallback)
+		if fortune.Rank != "" && rank != fortune.Rank {
+			return fmt.Errorf("entry %d: rank %s does not match the %s prefix of the fortune", i+1, fortune.Rank, rank)
+		}
+		if !rank.IsValid() {
+			return fmt.Errorf("entry %d: invalid rank %q", i+1, rank)
+		}
+		if text == "" {
+			return fmt.Errorf("entry %d: text is required", i+1)
+		}
+		if fortune.Weight == 0 {
+			fortune.Weight = 1
+		}
+		if fortune.Weight < 1 || fortune.Weight > FortuneMaxWeight {
+			return fmt.Errorf("entry %d: weight must be between 1 and %d", i+1, FortuneMaxWeight)
+		}
+		key := strings.ToLower(strings.Join(strings.Fields(text), " "))
+		if seen[key] {
+			return fmt.Errorf("entry %d: %q is listed twice", i+1, text)
+		}
+		seen[key] = true
+		fortune.Rank, fortune.Text = rank, text
+	}
+	return nil
+}
+
+func validateTimeZoneSeed(zones []TimeZoneSeed) error {
+	seen := make(map[string]bool, len(zones))
+	for i, zone := range zones {
+		if zone.City == "" {
+			return fmt.Errorf("entry %d: city is required", i+1)
+		}
+		if seen[zone.City] {
+			return fmt.Errorf("entry %d: %s is listed twice", i+1, zone.City)
+		}
+		seen[zone.City] = true
+		if _, err := time.LoadLocation(zone.TimeZone); err != nil || zone.TimeZone == "" {
+			return fmt.Errorf("entry %d: %q of %s is not an IANA time zone", i+1, zone.TimeZone, zone.City)
+		}
+	}
+	return nil
+}
+
+// Seed fills every empty collection with the built-in data, stored entries are never overwritten.
+func (c *NFContext) Seed() error {
+	data, err := LoadSeedData(nil)
+	if err != nil {
+		return err
+	}
+	return c.SeedWith(data)
+}
+
+// SeedWith fills every empty collection with its data, stored entries are never overwritten.
+func (c *NFContext) SeedWith(data *SeedData) error {
+	spyFamily := make(map[string]SpyFamilyCharacter, len(data.SpyFamily))
+	for _, character := range data.SpyFamily {
+		spyFamily[character.FirstName] = character
+	}
+	if err := seedCollection(c.SpyFamily, spyFamily); err != nil {
+		return err
+	}
+
+	dragonBall := make(map[string]int32, len(data.DragonBall))
+	for _, character := range data.DragonBall {
+		dragonBall[character.Name] = character.PowerLevel
+	}
+	if err := seedCollection(c.DragonBall, dragonBall); err != nil {
+		return err
+	}
+
+	if err := seedList(c.Fortunes, data.Fortunes, func(fortune Fortune, seq uint64) Fortune {
+		fortune.ID = int(seq)
+		return fortune
+	}); err != nil {
+		return err
+	}
+
+	timeZones := make(map[string]string, len(data.TimeZones))
+	for _, zone := range data.TimeZones {
+		timeZones[zone.City] = zone.TimeZone
+	}
+	return seedCollection(c.TimeZones, timeZones)
+}
+
+func isSeeded[T any](tx *StoreTx[T]) (bool, error) {
+	n, err := tx.Len()
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		logger.CtxLog.Infof("Keep %d stored entries of [%s]", n, tx.s.collection)
+	}
+	return n > 0, nil
+}
+
+// seedCollection holds the store lock, a reload seeding while handlers write cannot mix the two.
+func seedCollection[T any](s *Store[T], data map[string]T) error {
+	return s.Do(func(tx *StoreTx[T]) error {
+		if seeded, err := isSeeded(tx); err != nil || seeded {
+			return err
+		}
+
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := tx.Put(key, data[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// seedList stores values under sequence keys, keeping their order. keyed records the key in the value.
+func seedList[T any](s *Store[T], values []T, keyed func(value T, seq uint64) T) error {
+	return s.Do(func(tx *StoreTx[T]) error {
+		if seeded, err := isSeeded(tx); err != nil || seeded {
+			return err
+		}
+
+		for _, value := range values {
+			if _, err := tx.Append(func(seq uint64) T { return keyed(value, seq) }); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
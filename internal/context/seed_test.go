@@ -0,0 +1,148 @@
+package context_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/storage"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSeedFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func Test_LoadSeedData(t *testing.T) {
+	t.Run("Built-in", func(t *testing.T) {
+		data, err := nf_context.LoadSeedData(nil)
+		require.NoError(t, err)
+		assert.Len(t, data.SpyFamily, 15)
+		assert.Len(t, data.DragonBall, 7)
+		assert.Len(t, data.Fortunes, 7)
+		assert.Len(t, data.TimeZones, 10)
+	})
+
+	t.Run("Files", func(t *testing.T) {
+		files := &factory.Seed{
+			DragonBall: writeSeedFile(t, "dragonball.yaml", `
+- name: Beerus
+  powerLevel: 10
+- name: Whis
+  powerLevel: 11
+`),
+			Fortune: writeSeedFile(t, "fortune.yml", `
+- "大吉: Jackpot."
+- text: Rainy days pass.
+  rank: 末吉
+  weight: 5
+`),
+			TimeZone: writeSeedFile(t, "timezone.json", `[{"city": "Lima", "timeZone": "America/Lima"}]`),
+		}
+		data, err := nf_context.LoadSeedData(files)
+		require.NoError(t, err)
+
+		nfCtx := &nf_context.NFContext{}
+		nfCtx.UseStorage(storage.NewMemoryStorage())
+		require.NoError(t, nfCtx.SeedWith(data))
+
+		dragonBall, err := nfCtx.DragonBall.Entries()
+		require.NoError(t, err)
+		assert.Equal(t, []nf_context.StoreEntry[int32]{{Key: "Beerus", Value: 10}, {Key: "Whis", Value: 11}}, dragonBall)
+
+		fortunes, err := nfCtx.Fortunes.All()
+		require.NoError(t, err)
+		assert.Equal(t, []nf_context.Fortune{
+			{ID: 1, Rank: nf_context.FortuneRankDaikichi, Text: "Jackpot.", Weight: 1},
+			{ID: 2, Rank: nf_context.FortuneRankSuekichi, Text: "Rainy days pass.", Weight: 5},
+		}, fortunes)
+
+		zones, err := nfCtx.TimeZones.All()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"America/Lima"}, zones)
+
+		// the domains without a file get the built-in data
+		characters, err := nfCtx.SpyFamily.Len()
+		require.NoError(t, err)
+		assert.Equal(t, 15, characters)
+	})
+
+	t.Run("Stored Entries Are Kept", func(t *testing.T) {
+		nfCtx := &nf_context.NFContext{}
+		nfCtx.UseStorage(storage.NewMemoryStorage())
+		require.NoError(t, nfCtx.DragonBall.Put("Frieza", 9))
+
+		data, err := nf_context.LoadSeedData(&factory.Seed{
+			DragonBall: writeSeedFile(t, "dragonball.json", `[{"name": "Cell", "powerLevel": 8}]`),
+		})
+		require.NoError(t, err)
+		require.NoError(t, nfCtx.SeedWith(data))
+
+		dragonBall, err := nfCtx.DragonBall.Entries()
+		require.NoError(t, err)
+		assert.Equal(t, []nf_context.StoreEntry[int32]{{Key: "Frieza", Value: 9}}, dragonBall)
+	})
+
+	invalid := []struct {
+		name  string
+		files func(t *testing.T) *factory.Seed
+		err   string
+	}{
+		{
+			name: "Missing File",
+			files: func(t *testing.T) *factory.Seed {
+				return &factory.Seed{SpyFamily: filepath.Join(t.TempDir(), "missing.yaml")}
+			},
+			err: "read seed",
+		},
+		{
+			name: "Unknown Field",
+			files: func(t *testing.T) *factory.Seed {
+				return &factory.Seed{DragonBall: writeSeedFile(t, "dragonball.yaml", "- name: Goku\n  power: 9000\n")}
+			},
+			err: `json: unknown field "power"`,
+		},
+		{
+			name: "Duplicate Character",
+			files: func(t *testing.T) *factory.Seed {
+				return &factory.Seed{SpyFamily: writeSeedFile(t, "spyfamily.json",
+					`[{"firstName": "Anya", "lastName": "Forger"}, {"firstName": "Anya", "lastName": "Desmond"}]`)}
+			},
+			err: "entry 2: Anya is listed twice",
+		},
+		{
+			name: "Invalid Rank",
+			files: func(t *testing.T) *factory.Seed {
+				return &factory.Seed{Fortune: writeSeedFile(t, "fortune.yaml", "- rank: 超吉\n  text: Maybe\n")}
+			},
+			err: `entry 1: invalid rank "超吉"`,
+		},
+		{
+			name: "Invalid Weight",
+			files: func(t *testing.T) *factory.Seed {
+				return &factory.Seed{Fortune: writeSeedFile(t, "fortune.yaml", "- text: Heavy\n  weight: 101\n")}
+			},
+			err: "entry 1: weight must be between 1 and 100",
+		},
+		{
+			name: "Unknown Time Zone",
+			files: func(t *testing.T) *factory.Seed {
+				return &factory.Seed{TimeZone: writeSeedFile(t, "timezone.yaml", "- city: Atlantis\n  timeZone: Ocean/Atlantis\n")}
+			},
+			err: `entry 1: "Ocean/Atlantis" of Atlantis is not an IANA time zone`,
+		},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := nf_context.LoadSeedData(tc.files(t))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
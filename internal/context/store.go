@@ -0,0 +1,167 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/Alonza0314/nf-example/internal/storage"
+)
+
+// Store is the typed view of one domain collection. The storage backend only makes single
+// operations safe, the store lock also makes the read-modify-write sequences of the domain
+// atomic, such as refusing a duplicate or appending to an entry.
+type Store[T any] struct {
+	mu         sync.RWMutex
+	st         storage.Storage
+	collection string
+}
+
+// StoreEntry is a value of the store with its key.
+type StoreEntry[T any] struct {
+	Key   string
+	Value T
+}
+
+// StoreTx reads and writes the store while Do holds its lock.
+type StoreTx[T any] struct {
+	s *Store[T]
+}
+
+func NewStore[T any](st storage.Storage, collection string) *Store[T] {
+	return &Store[T]{st: st, collection: collection}
+}
+
+func (s *Store[T]) Collection() string {
+	return s.collection
+}
+
+func (s *Store[T]) Get(key string) (T, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return storage.Load[T](s.st, s.collection, key)
+}
+
+// All returns the values in insertion order.
+func (s *Store[T]) All() ([]T, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return storage.LoadAll[T](s.st, s.collection)
+}
+
+// Entries returns the values with their keys in insertion order.
+func (s *Store[T]) Entries() ([]StoreEntry[T], error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.entries()
+}
+
+func (s *Store[T]) entries() ([]StoreEntry[T], error) {
+	entries, err := s.st.List(s.collection)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]StoreEntry[T], len(entries))
+	for i, entry := range entries {
+		result[i].Key = entry.Key
+		if err = json.Unmarshal(entry.Value, &result[i].Value); err != nil {
+			return nil, fmt.Errorf("decode %s/%s: %w", s.collection, entry.Key, err)
+		}
+	}
+	return result, nil
+}
+
+func (s *Store[T]) Len() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.st.Len(s.collection)
+}
+
+func (s *Store[T]) Put(key string, value T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return storage.Store(s.st, s.collection, key, value)
+}
+
+// Insert stores value unless the key exists, the stored value is returned instead.
+func (s *Store[T]) Insert(key string, value T) (existing T, inserted bool, err error) {
+	err = s.Do(func(tx *StoreTx[T]) error {
+		var found bool
+		if existing, found, err = tx.Get(key); err != nil || found {
+			return err
+		}
+		inserted = true
+		return tx.Put(key, value)
+	})
+	return existing, inserted && err == nil, err
+}
+
+// Append stores the value built from the next sequence number under that number.
+func (s *Store[T]) Append(build func(seq uint64) T) (T, error) {
+	var value T
+	err := s.Do(func(tx *StoreTx[T]) error {
+		var err error
+		value, err = tx.Append(build)
+		return err
+	})
+	return value, err
+}
+
+// Update applies modify to the stored value and writes the result back. found is false
+// when the key does not exist, an error of modify is returned without writing anything.
+func (s *Store[T]) Update(key string, modify func(value *T) error) (value T, found bool, err error) {
+	err = s.Do(func(tx *StoreTx[T]) error {
+		if value, found, err = tx.Get(key); err != nil || !found {
+			return err
+		}
+		if err = modify(&value); err != nil {
+			return err
+		}
+		return tx.Put(key, value)
+	})
+	return value, found, err
+}
+
+func (s *Store[T]) Delete(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.st.Delete(s.collection, key)
+}
+
+// Do runs fn with the store locked, so the entries it reads do not change before it writes.
+func (s *Store[T]) Do(fn func(tx *StoreTx[T]) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(&StoreTx[T]{s: s})
+}
+
+func (tx *StoreTx[T]) Get(key string) (T, bool, error) {
+	return storage.Load[T](tx.s.st, tx.s.collection, key)
+}
+
+func (tx *StoreTx[T]) Entries() ([]StoreEntry[T], error) {
+	return tx.s.entries()
+}
+
+func (tx *StoreTx[T]) Len() (int, error) {
+	return tx.s.st.Len(tx.s.collection)
+}
+
+func (tx *StoreTx[T]) Put(key string, value T) error {
+	return storage.Store(tx.s.st, tx.s.collection, key, value)
+}
+
+func (tx *StoreTx[T]) Append(build func(seq uint64) T) (T, error) {
+	seq, err := tx.s.st.NextSequence(tx.s.collection)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	value := build(seq)
+	return value, tx.Put(strconv.FormatUint(seq, 10), value)
+}
+
+func (tx *StoreTx[T]) Delete(key string) (bool, error) {
+	return tx.s.st.Delete(tx.s.collection, key)
+}
@@ -0,0 +1,92 @@
+package context_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_StoreOperations(t *testing.T) {
+	store := nf_context.NewStore[int32](storage.NewMemoryStorage(), "dragonball")
+
+	existing, inserted, err := store.Insert("Goku", 7)
+	require.NoError(t, err)
+	assert.True(t, inserted)
+	existing, inserted, err = store.Insert("Goku", 9)
+	require.NoError(t, err)
+	assert.False(t, inserted)
+	assert.Equal(t, int32(7), existing)
+
+	value, found, err := store.Update("Goku", func(pl *int32) error {
+		*pl++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int32(8), value)
+
+	_, found, err = store.Update("Vegeta", func(*int32) error { return nil })
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// an error of modify leaves the stored value untouched
+	errRefused := errors.New("refused")
+	_, _, err = store.Update("Goku", func(pl *int32) error {
+		*pl = 0
+		return errRefused
+	})
+	assert.ErrorIs(t, err, errRefused)
+	value, _, err = store.Get("Goku")
+	require.NoError(t, err)
+	assert.Equal(t, int32(8), value)
+
+	appended, err := store.Append(func(seq uint64) int32 { return int32(seq) })
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), appended)
+
+	entries, err := store.Entries()
+	require.NoError(t, err)
+	assert.Equal(t, []nf_context.StoreEntry[int32]{{Key: "Goku", Value: 8}, {Key: "1", Value: 1}}, entries)
+}
+
+func Test_StoreConcurrentUpdates(t *testing.T) {
+	const workers, iterations = 16, 200
+	store := nf_context.NewStore[int](storage.NewMemoryStorage(), "counter")
+	require.NoError(t, store.Put("n", 0))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	inserted := 0
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				_, _, err := store.Update("n", func(n *int) error {
+					*n++
+					return nil
+				})
+				assert.NoError(t, err)
+
+				_, ok, err := store.Insert("once", i)
+				assert.NoError(t, err)
+				if ok {
+					mu.Lock()
+					inserted++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, _, err := store.Get("n")
+	require.NoError(t, err)
+	assert.Equal(t, workers*iterations, n, "no update is lost")
+	assert.Equal(t, 1, inserted, "a key is inserted once")
+}
@@ -14,8 +14,10 @@ var (
 	CfgLog  *logrus.Entry
 	CtxLog  *logrus.Entry
 
-	GinLog *logrus.Entry
-	SBILog *logrus.Entry
+	GinLog      *logrus.Entry
+	SBILog      *logrus.Entry
+	ConsumerLog *logrus.Entry
+	MetricsLog  *logrus.Entry
 )
 
 func init() {
@@ -33,4 +35,6 @@ func init() {
 
 	GinLog = NfLog.WithField(logger_util.FieldCategory, "GIN")
 	SBILog = NfLog.WithField(logger_util.FieldCategory, "SBI")
+	ConsumerLog = NfLog.WithField(logger_util.FieldCategory, "Consumer")
+	MetricsLog = NfLog.WithField(logger_util.FieldCategory, "Metrics")
 }
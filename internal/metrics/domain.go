@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// domainCollections are reported by the domain size gauge, keyed by the collection label.
+var domainCollections = []string{
+	nf_context.TaskCollection,
+	nf_context.MessageCollection,
+	nf_context.MessageRecordCollection,
+	nf_context.FortuneCollection,
+	nf_context.TimeZoneCollection,
+	nf_context.SpyFamilyCollection,
+	nf_context.DragonBallCollection,
+	nf_context.TournamentCollection,
+	nf_context.AttendanceCollection,
+	nf_context.AttendanceSessionCollection,
+	nf_context.OnePieceCollection,
+}
+
+// domainCollector reads the collection sizes from the storage on every scrape,
+// so the gauges never drift from the stored data.
+type domainCollector struct {
+	nfCtx *nf_context.NFContext
+	size  *prometheus.Desc
+}
+
+func newDomainCollector(namespace string, nfCtx *nf_context.NFContext) *domainCollector {
+	return &domainCollector{
+		nfCtx: nfCtx,
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "context", "entries"),
+			"Number of entries stored in each NF context collection.",
+			[]string{"collection"}, nil,
+		),
+	}
+}
+
+func (d *domainCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- d.size
+}
+
+func (d *domainCollector) Collect(ch chan<- prometheus.Metric) {
+	if d.nfCtx.Storage == nil {
+		return
+	}
+	for _, collection := range domainCollections {
+		count, err := d.nfCtx.Storage.Len(collection)
+		if err != nil {
+			logger.MetricsLog.Warnf("Count collection [%s] failed: %+v", collection, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(d.size, prometheus.GaugeValue, float64(count), collection)
+	}
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := NewMetrics("anya", &nf_context.NFContext{Storage: storage.NewMemoryStorage()})
+	router := gin.New()
+	router.GET("/tasks/:id", m.Middleware("Get Task"), func(c *gin.Context) {
+		if c.Param("id") == "0" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/tasks/1", "/tasks/2", "/tasks/0"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("Get Task", http.MethodGet, "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("Get Task", http.MethodGet, "404")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.duration, "anya_sbi_request_duration_seconds"))
+}
+
+func Test_DomainCollector(t *testing.T) {
+	st := storage.NewMemoryStorage()
+	require.NoError(t, storage.Store(st, nf_context.TaskCollection, "1", nf_context.Task{ID: 1}))
+	require.NoError(t, storage.Store(st, nf_context.FortuneCollection, "1", "good luck"))
+	require.NoError(t, storage.Store(st, nf_context.FortuneCollection, "2", "bad luck"))
+
+	m := NewMetrics("anya", &nf_context.NFContext{Storage: st})
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+
+	lines := strings.Split(string(body), "\n")
+	assert.Contains(t, lines, `anya_context_entries{collection="tasks"} 1`)
+	assert.Contains(t, lines, `anya_context_entries{collection="fortunes"} 2`)
+	assert.Contains(t, lines, `anya_context_entries{collection="timezone"} 0`)
+}
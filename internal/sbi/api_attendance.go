@@ -3,27 +3,97 @@ package sbi
 import (
 	"io"
 	"net/http"
+	"strings"
 
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
 )
 
 func (s *Server) getAttendanceRoute() []Route {
 	return []Route{
 		{
-			Name:    "Get Attendance",
-			Method:  http.MethodGet,
-			Pattern: "/",
-			APIFunc: s.GetAttendance,
+			Name:     "Get Attendance",
+			Method:   http.MethodGet,
+			Pattern:  "/",
+			APIFunc:  s.GetAttendance,
+			Response: plainText(""),
 		},
 		// curl -X GET http://127.0.0.163:8000/attendance/ -w "\n"
 		{
-			Name:    "Post Attendance",
-			Method:  http.MethodPost,
-			Pattern: "/",
-			APIFunc: s.PostAttendance,
+			Name:     "Post Attendance",
+			Method:   http.MethodPost,
+			Pattern:  "/",
+			APIFunc:  s.PostAttendance,
+			Request:  plainText(""),
+			Response: plainText(""),
 		},
 		// curl -X POST http://127.0.0.163:8000/attendance/ -d 'John' -w "\n"
+		{
+			Name:     "Create Attendance Session",
+			Method:   http.MethodPost,
+			Pattern:  "/sessions",
+			APIFunc:  s.HTTPCreateAttendanceSession,
+			Request:  processor.AttendanceNameRequest{},
+			Response: nf_context.AttendanceSession{},
+			Status:   http.StatusCreated,
+		},
+		// curl -X POST http://127.0.0.163:8000/attendance/sessions -d '{"name": "math-2025-01-06"}'
+		{
+			Name:     "List Attendance Sessions",
+			Method:   http.MethodGet,
+			Pattern:  "/sessions",
+			APIFunc:  s.HTTPListAttendanceSessions,
+			Response: []processor.AttendanceSessionSummary{},
+			Query:    []string{"offset", "limit"},
+		},
+		// curl -X GET "http://127.0.0.163:8000/attendance/sessions?offset=0&limit=10"
+		{
+			Name:     "Get Attendance Session",
+			Method:   http.MethodGet,
+			Pattern:  "/sessions/:session",
+			APIFunc:  s.HTTPGetAttendanceSession,
+			Response: nf_context.AttendanceSession{},
+			Query:    []string{"present"},
+		},
+		// curl -X GET "http://127.0.0.163:8000/attendance/sessions/math-2025-01-06?present=true"
+		{
+			Name:     "Check In Attendance",
+			Method:   http.MethodPost,
+			Pattern:  "/sessions/:session/check-in",
+			APIFunc:  s.HTTPCheckInAttendance,
+			Request:  processor.AttendanceNameRequest{},
+			Response: nf_context.AttendanceRecord{},
+			Status:   http.StatusCreated,
+		},
+		// curl -X POST http://127.0.0.163:8000/attendance/sessions/math-2025-01-06/check-in -d '{"name": "John"}'
+		{
+			Name:     "Check Out Attendance",
+			Method:   http.MethodPost,
+			Pattern:  "/sessions/:session/check-out",
+			APIFunc:  s.HTTPCheckOutAttendance,
+			Request:  processor.AttendanceNameRequest{},
+			Response: nf_context.AttendanceRecord{},
+		},
+		// curl -X POST http://127.0.0.163:8000/attendance/sessions/math-2025-01-06/check-out -d '{"name": "John"}'
+		{
+			Name:     "Export Attendance Session",
+			Method:   http.MethodGet,
+			Pattern:  "/sessions/:session/export",
+			APIFunc:  s.HTTPExportAttendanceSession,
+			Response: csvText(""),
+		},
+		// curl -X GET http://127.0.0.163:8000/attendance/sessions/math-2025-01-06/export
+		{
+			Name:     "Get Person Attendance",
+			Method:   http.MethodGet,
+			Pattern:  "/people/:name",
+			APIFunc:  s.HTTPGetPersonAttendance,
+			Response: []processor.PersonAttendanceRecord{},
+		},
+		// curl -X GET http://127.0.0.163:8000/attendance/people/John
 	}
 }
 
@@ -38,14 +108,83 @@ func (s *Server) PostAttendance(c *gin.Context) {
 
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, "failed to read body")
 		return
 	}
 
 	targetName := string(body)
 	if targetName == "" {
-		c.String(http.StatusBadRequest, "error: no name provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "no name provided")
 		return
 	}
 	s.Processor().PostAttendance(c, targetName)
 }
+
+// bindAttendanceName reads the name of the body, it answers the problem itself when the body is invalid.
+func bindAttendanceName(c *gin.Context) (string, bool) {
+	var requestbody processor.AttendanceNameRequest
+	if err := c.ShouldBindBodyWithJSON(&requestbody); err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, err.Error())
+		return "", false
+	}
+	name := strings.TrimSpace(requestbody.Name)
+	if name == "" {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "no name provided")
+		return "", false
+	}
+	return name, true
+}
+
+func (s *Server) HTTPCreateAttendanceSession(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPCreateAttendanceSession")
+
+	name, ok := bindAttendanceName(c)
+	if !ok {
+		return
+	}
+	s.Processor().CreateAttendanceSession(c, name)
+}
+
+func (s *Server) HTTPListAttendanceSessions(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPListAttendanceSessions")
+
+	s.Processor().ListAttendanceSessions(c)
+}
+
+func (s *Server) HTTPGetAttendanceSession(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPGetAttendanceSession")
+
+	s.Processor().GetAttendanceSession(c, c.Param("session"))
+}
+
+func (s *Server) HTTPCheckInAttendance(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPCheckInAttendance")
+
+	name, ok := bindAttendanceName(c)
+	if !ok {
+		return
+	}
+	s.Processor().CheckInAttendance(c, c.Param("session"), name)
+}
+
+func (s *Server) HTTPCheckOutAttendance(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPCheckOutAttendance")
+
+	name, ok := bindAttendanceName(c)
+	if !ok {
+		return
+	}
+	s.Processor().CheckOutAttendance(c, c.Param("session"), name)
+}
+
+func (s *Server) HTTPExportAttendanceSession(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPExportAttendanceSession")
+
+	s.Processor().ExportAttendanceSession(c, c.Param("session"))
+}
+
+func (s *Server) HTTPGetPersonAttendance(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPGetPersonAttendance")
+
+	s.Processor().GetPersonAttendance(c, c.Param("name"))
+}
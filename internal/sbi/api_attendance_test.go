@@ -28,7 +28,7 @@ func Test_Attendance(t *testing.T) {
 
 	t.Run("No attendance name provided", func(t *testing.T) {
 		const EXPECTED_STATUS = http.StatusBadRequest
-		const EXPECTED_BODY = "error: no name provided"
+		const EXPECTED_CAUSE = "MANDATORY_IE_MISSING"
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
 		var err error
@@ -42,8 +42,29 @@ func Test_Attendance(t *testing.T) {
 		if httpRecorder.Code != EXPECTED_STATUS {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
+		}
+	})
+	t.Run("No check-in name provided", func(t *testing.T) {
+		const EXPECTED_STATUS = http.StatusBadRequest
+		const EXPECTED_CAUSE = "MANDATORY_IE_MISSING"
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		ginCtx.Params = gin.Params{{Key: "session", Value: "math"}}
+		var err error
+		ginCtx.Request, err = http.NewRequest("POST", "/attendance/sessions/math/check-in", strings.NewReader(`{"name": "  "}`))
+		if err != nil {
+			t.Errorf("Failed to create request: %s", err)
+			return
+		}
+
+		server.HTTPCheckInAttendance(ginCtx)
+		if httpRecorder.Code != EXPECTED_STATUS {
+			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
+		}
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 	})
 }
@@ -15,6 +15,7 @@ func (s *Server) getDefaultRoute() []Route {
 			APIFunc: func(c *gin.Context) {
 				c.JSON(http.StatusOK, "Hello free5GC!")
 			},
+			Response: "",
 			// Use
 			// curl -X GET http://127.0.0.163:8000/default/ -w "\n"
 		},
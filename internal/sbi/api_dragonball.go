@@ -1,9 +1,14 @@
 package sbi
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,50 +21,152 @@ func (s *Server) getDragonBallRoute() []Route {
 			APIFunc: func(c *gin.Context) {
 				c.JSON(http.StatusOK, "Hello Dragon Ball!")
 			},
+			Response: "",
 			// Use
 			// curl -X GET http://127.0.0.163:8000/dragonball/
 		},
 		{
-			Name:    "Dragon Ball Search Character",
-			Method:  http.MethodGet,
-			Pattern: "/character/:name",
-			APIFunc: s.HTTPSearchDragonBallCharacter,
+			Name:     "Dragon Ball Search Character",
+			Method:   http.MethodGet,
+			Pattern:  "/character/:name",
+			APIFunc:  s.HTTPSearchDragonBallCharacter,
+			Response: plainText(""),
 			// Use
 			// curl -X GET http://127.0.0.163:8000/dragonball/character/Goku
 		},
 		{
-			Name:    "Dragon Ball Fight",
-			Method:  http.MethodPost,
-			Pattern: "/battle",
-			APIFunc: s.HTTPDragonBallFight,
+			Name:     "Dragon Ball Fight",
+			Method:   http.MethodPost,
+			Pattern:  "/battle",
+			APIFunc:  s.HTTPDragonBallFight,
+			Request:  DragonBallFightRequest{},
+			Response: plainText(""),
 			// Use
 			// curl -X POST "http://127.0.0.163:8000/dragonball/battle" -d '{"name1": "Goku", "name2": "Vegeta"}'
 		},
 		{
-			Name:    "Add Dragon Ball Character",
-			Method:  http.MethodPost,
-			Pattern: "/character",
-			APIFunc: s.HTTPAddDragonBallCharacter,
+			Name:     "Add Dragon Ball Character",
+			Method:   http.MethodPost,
+			Pattern:  "/character",
+			APIFunc:  s.HTTPAddDragonBallCharacter,
+			Request:  DragonBallCharacterRequest{},
+			Response: plainText(""),
+			Status:   http.StatusCreated,
 			// Use
 			// curl -X POST "http://127.0.0.163:8000/dragonball/character" -d '{"Name": "Saitama", "Powerlevel": 10000}'
 		},
 		{
-			Name:    "Update Dragon Ball Character's Powerlevel",
-			Method:  http.MethodPut,
-			Pattern: "/character/:name",
-			APIFunc: s.HTTPUpdateDragonBallCharacter,
+			Name:     "Update Dragon Ball Character's Powerlevel",
+			Method:   http.MethodPut,
+			Pattern:  "/character/:name",
+			APIFunc:  s.HTTPUpdateDragonBallCharacter,
+			Request:  DragonBallPowerLevelRequest{},
+			Response: plainText(""),
 			// Use
 			// curl -X PUT "http://127.0.0.163:8000/dragonball/character/Goku" -d '{"Powerlevel":  500}'
 		},
+		{
+			Name:     "Delete Dragon Ball Character",
+			Method:   http.MethodDelete,
+			Pattern:  "/character/:name",
+			APIFunc:  s.HTTPDeleteDragonBallCharacter,
+			Response: plainText(""),
+			// Use
+			// curl -X DELETE http://127.0.0.163:8000/dragonball/character/Yamcha
+		},
+		{
+			Name:     "List Dragon Ball Characters",
+			Method:   http.MethodGet,
+			Pattern:  "/characters",
+			APIFunc:  s.HTTPListDragonBallCharacters,
+			Response: []processor.DragonBallCharacter{},
+			Query:    []string{"sort", "offset", "limit"},
+			// Use
+			// curl -X GET "http://127.0.0.163:8000/dragonball/characters?sort=name&offset=0&limit=5"
+		},
+		{
+			Name:     "Dragon Ball Power Ranking",
+			Method:   http.MethodGet,
+			Pattern:  "/ranking",
+			APIFunc:  s.HTTPGetDragonBallRanking,
+			Response: []processor.DragonBallRank{},
+			Query:    []string{"offset", "limit"},
+			// Use
+			// curl -X GET "http://127.0.0.163:8000/dragonball/ranking?limit=3"
+		},
+		{
+			Name:     "Run Dragon Ball Tournament",
+			Method:   http.MethodPost,
+			Pattern:  "/tournament",
+			APIFunc:  s.HTTPRunDragonBallTournament,
+			Request:  DragonBallTournamentRequest{},
+			Response: nf_context.Tournament{},
+			Status:   http.StatusCreated,
+			// Use
+			// curl -X POST "http://127.0.0.163:8000/dragonball/tournament" -d '{"characters": ["Goku", "Vegeta", "Gohan"]}'
+			// curl -X POST "http://127.0.0.163:8000/dragonball/tournament" -d '{"characters": "all"}'
+		},
+		{
+			Name:     "Get Dragon Ball Tournament",
+			Method:   http.MethodGet,
+			Pattern:  "/tournament/:id",
+			APIFunc:  s.HTTPGetDragonBallTournament,
+			Response: nf_context.Tournament{},
+			// Use
+			// curl -X GET http://127.0.0.163:8000/dragonball/tournament/1
+		},
 	}
 }
 
+type DragonBallFightRequest struct {
+	TargetName1 string `json:"name1"`
+	TargetName2 string `json:"name2"`
+}
+
+type DragonBallCharacterRequest struct {
+	Name       string `json:"name"`
+	PowerLevel *int32 `json:"powerLevel"`
+}
+
+type DragonBallPowerLevelRequest struct {
+	PowerLevel *int32 `json:"powerLevel"`
+}
+
+type DragonBallTournamentRequest struct {
+	Characters TournamentCharacters `json:"characters"`
+}
+
+// TournamentCharacters is a list of character names, or the string "all" for every character.
+type TournamentCharacters struct {
+	Names []string
+	All   bool
+}
+
+func (t *TournamentCharacters) UnmarshalJSON(data []byte) error {
+	var all string
+	if err := json.Unmarshal(data, &all); err == nil {
+		if all != "all" {
+			return fmt.Errorf(`characters must be a list of names or "all", got %q`, all)
+		}
+		t.All = true
+		return nil
+	}
+	return json.Unmarshal(data, &t.Names)
+}
+
+func (TournamentCharacters) OpenAPISchema() map[string]any {
+	return map[string]any{"oneOf": []any{
+		map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		map[string]any{"type": "string", "enum": []string{"all"}},
+	}}
+}
+
 func (s *Server) HTTPSearchDragonBallCharacter(c *gin.Context) {
 	logger.SBILog.Infof("In HTTPSearchDragonBallCharacter")
 	targetName := c.Param("name")
 
 	if targetName == "" {
-		c.String(http.StatusBadRequest, "No name provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No name provided")
 		return
 	}
 
@@ -69,21 +176,17 @@ func (s *Server) HTTPSearchDragonBallCharacter(c *gin.Context) {
 func (s *Server) HTTPDragonBallFight(c *gin.Context) {
 	logger.SBILog.Infof("In HTTPDragonBallFight")
 
-	type RequestBody struct {
-		TargetName1 string `json:"name1"`
-		TargetName2 string `json:"name2"`
-	}
-	var requestbody RequestBody
+	var requestbody DragonBallFightRequest
 	if err := c.ShouldBindBodyWithJSON(&requestbody); err != nil {
-		c.String(http.StatusBadRequest, "error")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, err.Error())
 		return
 	}
 	if requestbody.TargetName1 == "" {
-		c.String(http.StatusBadRequest, "No name1 provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No name1 provided")
 		return
 	}
 	if requestbody.TargetName2 == "" {
-		c.String(http.StatusBadRequest, "No name2 provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No name2 provided")
 		return
 	}
 
@@ -93,21 +196,17 @@ func (s *Server) HTTPDragonBallFight(c *gin.Context) {
 func (s *Server) HTTPAddDragonBallCharacter(c *gin.Context) {
 	logger.SBILog.Infof("In HTTPAddDragonBallCharacter")
 
-	type RequestBody struct {
-		Name       string `json:"name"`
-		PowerLevel *int32 `json:"powerLevel"`
-	}
-	var requestbody RequestBody
+	var requestbody DragonBallCharacterRequest
 	if err := c.ShouldBindBodyWithJSON(&requestbody); err != nil {
-		c.String(http.StatusBadRequest, "error")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, err.Error())
 		return
 	}
 	if requestbody.Name == "" {
-		c.String(http.StatusBadRequest, "No name provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No name provided")
 		return
 	}
 	if requestbody.PowerLevel == nil {
-		c.String(http.StatusBadRequest, "No Powerlevel provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No Powerlevel provided")
 		return
 	}
 
@@ -119,23 +218,70 @@ func (s *Server) HTTPUpdateDragonBallCharacter(c *gin.Context) {
 	targetName := c.Param("name")
 
 	if targetName == "" {
-		c.String(http.StatusBadRequest, "No name provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No name provided")
 		return
 	}
 
-	type RequestBody struct {
-		PowerLevel *int32 `json:"powerLevel"`
-	}
-	var requestbody RequestBody
+	var requestbody DragonBallPowerLevelRequest
 	if err := c.ShouldBindBodyWithJSON(&requestbody); err != nil {
-		c.String(http.StatusBadRequest, "error")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, err.Error())
 		return
 	}
 
 	if requestbody.PowerLevel == nil {
-		c.String(http.StatusBadRequest, "No Powerlevel provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No Powerlevel provided")
 		return
 	}
 
 	s.Processor().UpdateDragonBallCharacter(c, targetName, *requestbody.PowerLevel)
 }
+
+func (s *Server) HTTPDeleteDragonBallCharacter(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPDeleteDragonBallCharacter")
+	targetName := c.Param("name")
+
+	if targetName == "" {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No name provided")
+		return
+	}
+
+	s.Processor().DeleteDragonBallCharacter(c, targetName)
+}
+
+func (s *Server) HTTPListDragonBallCharacters(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPListDragonBallCharacters")
+
+	s.Processor().ListDragonBallCharacters(c)
+}
+
+func (s *Server) HTTPGetDragonBallRanking(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPGetDragonBallRanking")
+
+	s.Processor().GetDragonBallRanking(c)
+}
+
+func (s *Server) HTTPRunDragonBallTournament(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPRunDragonBallTournament")
+
+	var requestbody DragonBallTournamentRequest
+	if err := c.ShouldBindBodyWithJSON(&requestbody); err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, err.Error())
+		return
+	}
+	if requestbody.Characters.All {
+		s.Processor().RunDragonBallTournament(c, nil)
+		return
+	}
+	if len(requestbody.Characters.Names) == 0 {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No characters provided")
+		return
+	}
+
+	s.Processor().RunDragonBallTournament(c, requestbody.Characters.Names)
+}
+
+func (s *Server) HTTPGetDragonBallTournament(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPGetDragonBallTournament")
+
+	s.Processor().GetDragonBallTournament(c, c.Param("id"))
+}
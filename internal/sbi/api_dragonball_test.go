@@ -32,7 +32,7 @@ func Test_HTTPSearchDragonBallCharacter(t *testing.T) {
 
 	t.Run("No name provided", func(t *testing.T) {
 		const EXPECTED_STATUS = http.StatusBadRequest
-		const EXPECTED_BODY = "No name provided"
+		const EXPECTED_CAUSE = "MANDATORY_IE_MISSING"
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -50,8 +50,8 @@ func Test_HTTPSearchDragonBallCharacter(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 	})
 }
@@ -64,25 +64,25 @@ func Test_HTTPDragonBallFight(t *testing.T) {
 		name           string
 		jsonBody       string
 		expectedStatus int
-		expectedBody   string
+		expectedCause  string
 	}{
 		{
 			name:           "error",
 			jsonBody:       `{`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "error",
+			expectedCause:  "INVALID_MSG_FORMAT",
 		},
 		{
 			name:           "No name1 provided",
 			jsonBody:       `{"name2": "Vegeta"}`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "No name1 provided",
+			expectedCause:  "MANDATORY_IE_MISSING",
 		},
 		{
 			name:           "No name2 provided",
 			jsonBody:       `{"name1":"Goku"}`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "No name2 provided",
+			expectedCause:  "MANDATORY_IE_MISSING",
 		},
 	}
 
@@ -105,9 +105,8 @@ func Test_HTTPDragonBallFight(t *testing.T) {
 					tc.expectedStatus, httpRecorder.Code, httpRecorder.Body.String())
 			}
 
-			if httpRecorder.Body.String() != tc.expectedBody {
-				t.Errorf("expected body %q, got %q",
-					tc.expectedBody, httpRecorder.Body.String())
+			if problem := decodeProblem(t, httpRecorder); problem.Cause != tc.expectedCause {
+				t.Errorf("expected cause %q, got %q", tc.expectedCause, problem.Cause)
 			}
 		})
 	}
@@ -130,25 +129,25 @@ func Test_HTTPAddDragonBallCharacter(t *testing.T) {
 		name           string
 		jsonBody       string
 		expectedStatus int
-		expectedBody   string
+		expectedCause  string
 	}{
 		{
 			name:           "error",
 			jsonBody:       `{`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "error",
+			expectedCause:  "INVALID_MSG_FORMAT",
 		},
 		{
 			name:           "No name provided",
 			jsonBody:       `{"powerLevel":100}`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "No name provided",
+			expectedCause:  "MANDATORY_IE_MISSING",
 		},
 		{
 			name:           "No Powerlevel provided",
 			jsonBody:       `{"name":"Goku"}`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "No Powerlevel provided",
+			expectedCause:  "MANDATORY_IE_MISSING",
 		},
 	}
 
@@ -171,9 +170,8 @@ func Test_HTTPAddDragonBallCharacter(t *testing.T) {
 					tc.expectedStatus, httpRecorder.Code, httpRecorder.Body.String())
 			}
 
-			if httpRecorder.Body.String() != tc.expectedBody {
-				t.Errorf("expected body %q, got %q",
-					tc.expectedBody, httpRecorder.Body.String())
+			if problem := decodeProblem(t, httpRecorder); problem.Cause != tc.expectedCause {
+				t.Errorf("expected cause %q, got %q", tc.expectedCause, problem.Cause)
 			}
 		})
 	}
@@ -187,27 +185,27 @@ func Test_HTTPUpdateDragonBallCharacter(t *testing.T) {
 		name           string
 		jsonBody       string
 		expectedStatus int
-		expectedBody   string
+		expectedCause  string
 		url_param      string
 	}{
 		{
 			name:           "No name provided",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "No name provided",
+			expectedCause:  "MANDATORY_IE_MISSING",
 			url_param:      "",
 		},
 		{
 			name:           "error",
 			jsonBody:       `{`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "error",
+			expectedCause:  "INVALID_MSG_FORMAT",
 			url_param:      "/Character",
 		},
 		{
 			name:           "No Powerlevel provided",
 			jsonBody:       `{}`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "No Powerlevel provided",
+			expectedCause:  "MANDATORY_IE_MISSING",
 			url_param:      "/Character",
 		},
 	}
@@ -233,10 +231,88 @@ func Test_HTTPUpdateDragonBallCharacter(t *testing.T) {
 					tc.expectedStatus, httpRecorder.Code, httpRecorder.Body.String())
 			}
 
-			if httpRecorder.Body.String() != tc.expectedBody {
-				t.Errorf("expected body %q, got %q",
-					tc.expectedBody, httpRecorder.Body.String())
+			if problem := decodeProblem(t, httpRecorder); problem.Cause != tc.expectedCause {
+				t.Errorf("expected cause %q, got %q", tc.expectedCause, problem.Cause)
 			}
 		})
 	}
 }
+
+func Test_HTTPRunDragonBallTournament(t *testing.T) {
+	server := setupTestServer()
+
+	tests := []struct {
+		name           string
+		jsonBody       string
+		expectedStatus int
+		expectedCause  string
+	}{
+		{
+			name:           "Malformed body",
+			jsonBody:       `{`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCause:  "INVALID_MSG_FORMAT",
+		},
+		{
+			name:           "No characters provided",
+			jsonBody:       `{"characters": []}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCause:  "MANDATORY_IE_MISSING",
+		},
+		{
+			name:           "Unknown keyword",
+			jsonBody:       `{"characters": "everyone"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCause:  "INVALID_MSG_FORMAT",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			httpRecorder := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(httpRecorder)
+
+			req, err := http.NewRequest("POST", "/dragonball/tournament", bytes.NewBufferString(tc.jsonBody))
+			if err != nil {
+				t.Errorf("Failed to create request: %s", err)
+				return
+			}
+			ginCtx.Request = req
+
+			server.HTTPRunDragonBallTournament(ginCtx)
+
+			if httpRecorder.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d body=%s",
+					tc.expectedStatus, httpRecorder.Code, httpRecorder.Body.String())
+			}
+
+			if problem := decodeProblem(t, httpRecorder); problem.Cause != tc.expectedCause {
+				t.Errorf("expected cause %q, got %q", tc.expectedCause, problem.Cause)
+			}
+		})
+	}
+}
+
+func Test_HTTPDeleteDragonBallCharacter(t *testing.T) {
+	server := setupTestServer()
+
+	httpRecorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(httpRecorder)
+	ginCtx.Params = gin.Params{gin.Param{Key: "name", Value: ""}}
+
+	var err error
+	ginCtx.Request, err = http.NewRequest("DELETE", "/dragonball/character/", nil)
+	if err != nil {
+		t.Errorf("Failed to create request: %s", err)
+		return
+	}
+
+	server.HTTPDeleteDragonBallCharacter(ginCtx)
+
+	if httpRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, httpRecorder.Code)
+	}
+	if problem := decodeProblem(t, httpRecorder); problem.Cause != "MANDATORY_IE_MISSING" {
+		t.Errorf("Expected cause MANDATORY_IE_MISSING, got %s", problem.Cause)
+	}
+}
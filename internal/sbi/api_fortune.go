@@ -3,30 +3,88 @@ package sbi
 import (
 	"net/http"
 
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/logger"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
 )
 
 func (s *Server) getFortuneRoute() []Route {
 	return []Route{
 		{
-			Name:    "Get Today's Fortune",
-			Method:  http.MethodGet,
-			Pattern: "/",
-			APIFunc: s.HTTPGetFortune,
+			Name:     "Get Today's Fortune",
+			Method:   http.MethodGet,
+			Pattern:  "/",
+			APIFunc:  s.HTTPGetFortune,
+			Response: processor.FortuneResponse{},
 			// Use
 			// curl -X GET http://127.0.0.163:8000/fortune/ -w "\n"
 		},
 		{
-			Name:    "Add a new Fortune",
-			Method:  http.MethodPost,
-			Pattern: "/",
-			APIFunc: s.HTTPPostFortune,
+			Name:     "Add a new Fortune",
+			Method:   http.MethodPost,
+			Pattern:  "/",
+			APIFunc:  s.HTTPPostFortune,
+			Request:  processor.PostFortuneRequest{},
+			Response: processor.FortuneResponse{},
+			Status:   http.StatusCreated,
 			// Use
 			// curl -X POST http://127.0.0.163:8000/fortune/ \
 			//   -H "Content-Type: application/json" \
-			//   -d '{"fortune":"New fortune text"}' -w "\n"
+			//   -d '{"fortune":"New fortune text", "rank":"小吉", "weight":2}' -w "\n"
+		},
+		{
+			Name:     "Get Daily Fortune",
+			Method:   http.MethodGet,
+			Pattern:  "/daily",
+			APIFunc:  s.HTTPGetDailyFortune,
+			Response: processor.DailyFortuneResponse{},
+			Query:    []string{"user"},
+			// Use
+			// curl -X GET "http://127.0.0.163:8000/fortune/daily?user=Anya" -w "\n"
+		},
+		{
+			Name:     "List Fortunes",
+			Method:   http.MethodGet,
+			Pattern:  "/list",
+			APIFunc:  s.HTTPListFortunes,
+			Response: []nf_context.Fortune{},
+			Query:    []string{"rank", "offset", "limit"},
+			// Use
+			// curl -X GET "http://127.0.0.163:8000/fortune/list?rank=大吉" -w "\n"
+		},
+		{
+			Name:     "Get Fortune",
+			Method:   http.MethodGet,
+			Pattern:  "/:id",
+			APIFunc:  s.HTTPGetFortuneByID,
+			Response: nf_context.Fortune{},
+			// Use
+			// curl -X GET http://127.0.0.163:8000/fortune/1 -w "\n"
+		},
+		{
+			Name:     "Update Fortune",
+			Method:   http.MethodPut,
+			Pattern:  "/:id",
+			APIFunc:  s.HTTPUpdateFortune,
+			Request:  processor.PostFortuneRequest{},
+			Response: processor.FortuneResponse{},
+			// Use
+			// curl -X PUT http://127.0.0.163:8000/fortune/1 \
+			//   -H "Content-Type: application/json" \
+			//   -d '{"fortune":"大吉: Everything goes your way.", "weight":1}' -w "\n"
+		},
+		{
+			Name:    "Delete Fortune",
+			Method:  http.MethodDelete,
+			Pattern: "/:id",
+			APIFunc: s.HTTPDeleteFortune,
+			Status:  http.StatusNoContent,
+			// Use
+			// curl -X DELETE http://127.0.0.163:8000/fortune/1
 		},
 	}
 }
@@ -43,16 +101,57 @@ func (s *Server) HTTPPostFortune(c *gin.Context) {
 	var req processor.PostFortuneRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.SBILog.Errorf("Invalid request body: %+v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid request body",
-			"error":   err.Error(),
-		})
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, "Invalid request body: "+err.Error())
 		return
 	}
 
 	s.Processor().PostFortune(c, req)
 }
 
+func (s *Server) HTTPGetDailyFortune(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPGetDailyFortune")
+
+	user := c.Query("user")
+	if user == "" {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No user provided",
+			models.InvalidParam{Param: "user"})
+		return
+	}
+
+	s.Processor().GetDailyFortune(c, user)
+}
+
+func (s *Server) HTTPListFortunes(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPListFortunes")
+
+	s.Processor().ListFortunes(c)
+}
+
+func (s *Server) HTTPGetFortuneByID(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPGetFortuneByID")
+
+	s.Processor().GetFortuneByID(c, c.Param("id"))
+}
+
+func (s *Server) HTTPUpdateFortune(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPUpdateFortune")
+
+	var req processor.PostFortuneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.SBILog.Errorf("Invalid request body: %+v", err)
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, "Invalid request body: "+err.Error())
+		return
+	}
+
+	s.Processor().UpdateFortune(c, c.Param("id"), req)
+}
+
+func (s *Server) HTTPDeleteFortune(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPDeleteFortune")
+
+	s.Processor().DeleteFortune(c, c.Param("id"))
+}
+
 func (s *Server) GetFortuneRoute() []Route {
 	return s.getFortuneRoute()
 }
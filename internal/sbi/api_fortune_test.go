@@ -29,8 +29,8 @@ func Test_GetFortuneRoute(t *testing.T) {
 		t.Fatalf("Expected routes slice, got nil")
 	}
 
-	if len(routes) != 2 {
-		t.Fatalf("Expected 2 routes, got %d", len(routes))
+	if len(routes) != 7 {
+		t.Fatalf("Expected 7 routes, got %d", len(routes))
 	}
 
 	// Validate first route (GET)
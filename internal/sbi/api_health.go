@@ -0,0 +1,27 @@
+package sbi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// HTTPGetHealth answers as long as the server serves requests, a failing liveness probe
+// means the process has to be restarted.
+func (s *Server) HTTPGetHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// HTTPGetReadiness answers 503 while any readiness check fails, the body lists the checks.
+func (s *Server) HTTPGetReadiness(c *gin.Context) {
+	readiness := s.Readiness()
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, readiness)
+}
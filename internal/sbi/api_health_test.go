@@ -0,0 +1,79 @@
+package sbi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alonza0314/nf-example/internal/sbi"
+	"github.com/Alonza0314/nf-example/pkg/app"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_HTTPHealthProbes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	nfApp := sbi.NewMocknfApp(mockCtrl)
+	nfApp.EXPECT().Config().Return(&factory.Config{
+		Configuration: &factory.Configuration{
+			Sbi: &factory.Sbi{Port: 8000},
+		},
+	}).AnyTimes()
+	server := sbi.NewServer(nfApp, "")
+
+	t.Run("Liveness", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(recorder)
+		ginCtx.Request = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+
+		server.HTTPGetHealth(ginCtx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+	})
+
+	tests := []struct {
+		name           string
+		readiness      app.Readiness
+		expectedStatus int
+	}{
+		{
+			name: "Ready",
+			readiness: app.NewReadiness(
+				app.HealthCheck{Name: "lifecycle", Ready: true},
+				app.HealthCheck{Name: "storage", Ready: true},
+			),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Terminating",
+			readiness: app.NewReadiness(
+				app.HealthCheck{Name: "lifecycle", Detail: "terminating"},
+				app.HealthCheck{Name: "storage", Ready: true},
+			),
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nfApp.EXPECT().Readiness().Return(tt.readiness)
+
+			recorder := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(recorder)
+			ginCtx.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+
+			server.HTTPGetReadiness(ginCtx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			var readiness app.Readiness
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &readiness))
+			assert.Equal(t, tt.readiness, readiness)
+		})
+	}
+}
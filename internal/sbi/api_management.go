@@ -0,0 +1,43 @@
+package sbi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/util"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) getManagementRoute() []Route {
+	return []Route{
+		{
+			Name:     "Reload Config",
+			Method:   http.MethodPut,
+			Pattern:  "/config",
+			APIFunc:  s.HTTPReloadConfig,
+			Response: factory.ReloadResult{},
+			// Use
+			// curl -X PUT http://127.0.0.163:8000/nf-management/config -w "\n"
+			// re-read the config file, same as sending SIGHUP
+		},
+	}
+}
+
+func (s *Server) HTTPReloadConfig(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPReloadConfig")
+
+	result, err := s.ReloadConfig()
+	if err != nil {
+		logger.SBILog.Errorf("Reload config failed: %+v", err)
+		var invalid *factory.InvalidConfigError
+		if errors.As(err, &invalid) {
+			util.SendProblem(c, http.StatusUnprocessableEntity, util.CauseInvalidConfiguration, err.Error())
+			return
+		}
+		util.SendSystemFailure(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,80 @@
+package sbi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Alonza0314/nf-example/internal/sbi"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_HTTPReloadConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		result         *factory.ReloadResult
+		err            error
+		expectedStatus int
+		expectedCause  string
+	}{
+		{
+			name: "Reload applied",
+			result: &factory.ReloadResult{
+				Applied:         []string{"logger.level"},
+				RestartRequired: []string{"configuration.sbi"},
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid config",
+			err:            &factory.InvalidConfigError{Err: errors.New("Config validate Error: invalid serviceNameList: nanya-unknown")},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCause:  "INVALID_CONFIGURATION",
+		},
+		{
+			name:           "Seeding failed",
+			err:            errors.New("storage is closed"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCause:  "SYSTEM_FAILURE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			nfApp := sbi.NewMocknfApp(mockCtrl)
+			nfApp.EXPECT().Config().Return(&factory.Config{
+				Configuration: &factory.Configuration{
+					Sbi: &factory.Sbi{Port: 8000},
+				},
+			}).AnyTimes()
+			nfApp.EXPECT().ReloadConfig().Return(tt.result, tt.err).Times(1)
+			server := sbi.NewServer(nfApp, "")
+
+			recorder := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(recorder)
+			ginCtx.Request, _ = http.NewRequest(http.MethodPut, "/nf-management/config", nil)
+
+			server.HTTPReloadConfig(ginCtx)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedCause != "" {
+				problem := decodeProblem(t, recorder)
+				assert.Equal(t, tt.expectedCause, problem.Cause)
+				assert.Equal(t, tt.err.Error(), problem.Detail)
+				return
+			}
+			var result factory.ReloadResult
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+			assert.Equal(t, *tt.result, result)
+		})
+	}
+}
@@ -3,25 +3,28 @@ package sbi
 import (
 	"net/http"
 
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
 )
 
 func (s *Server) myPutGetMessageRoute() []Route {
 	return []Route{
 		{
-			Name:    "get messages",
-			Method:  http.MethodGet,
-			Pattern: "/",
-			APIFunc: s.HTTPGetMessageRecord,
+			Name:     "get messages",
+			Method:   http.MethodGet,
+			Pattern:  "/",
+			APIFunc:  s.HTTPGetMessageRecord,
+			Response: plainText(""),
 			// Use
 			// curl -X GET http://127.0.0.163:8000/message/ -w "\n"
 			// return all added message
 		},
 		{
-			Name:    "add message",
-			Method:  http.MethodPut,
-			Pattern: "/:Message",
-			APIFunc: s.HTTPAddNewMessage,
+			Name:     "add message",
+			Method:   http.MethodPut,
+			Pattern:  "/:Message",
+			APIFunc:  s.HTTPAddNewMessage,
+			Response: plainText(""),
 			// Use
 			// curl -X PUT http://127.0.0.163:8000/message/yourmessage -w "\n"
 			// add "yourmessage" to message record
@@ -46,7 +49,7 @@ func (s *Server) HTTPAddNewMessage(c *gin.Context) {
 }
 
 func (s *Server) noMessageHandler(c *gin.Context) {
-	c.String(http.StatusBadRequest, "No message provided")
+	util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No message provided")
 }
 
 func (s *Server) HTTPGetMessageRecord(c *gin.Context) {
@@ -27,7 +27,7 @@ func Test_PUTWithEmptyInput(t *testing.T) {
 
 	t.Run("Add Message That Empty", func(t *testing.T) {
 		const EXPECTED_STATUS = 400
-		const EXPECTED_BODY = "No message provided"
+		const EXPECTED_CAUSE = "MANDATORY_IE_MISSING"
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -45,8 +45,8 @@ func Test_PUTWithEmptyInput(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 	})
 }
@@ -1,41 +1,95 @@
 package sbi
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/Alonza0314/nf-example/internal/logger"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
 )
 
 func (s *Server) getMessageRoute() []Route {
 	return []Route{
 		{
-			Name:    "Post Message",
-			Method:  http.MethodPost,
-			Pattern: "/",
-			APIFunc: s.HTTPPostMessage,
+			Name:     "Post Message",
+			Method:   http.MethodPost,
+			Pattern:  "/",
+			APIFunc:  s.HTTPPostMessage,
+			Request:  processor.PostMessageRequest{},
+			Response: processor.PostMessageResponse{},
+			Status:   http.StatusCreated,
 			// Use
 			// curl -X POST http://127.0.0.163:8000/msg/ \
 			//   -H "Content-Type: application/json" \
 			//   -d '{"content":"Hello World","author":"Anya"}' -w "\n"
+			// add "parentId":"{message-id}" to reply to a message
 		},
 		{
-			Name:    "Get All Messages",
-			Method:  http.MethodGet,
-			Pattern: "/",
-			APIFunc: s.HTTPGetMessages,
+			Name:     "Get All Messages",
+			Method:   http.MethodGet,
+			Pattern:  "/",
+			APIFunc:  s.HTTPGetMessages,
+			Response: processor.GetMessagesResponse{},
+			Query:    []string{"author", "since", "until", "includeDeleted", "cursor", "limit"},
 			// Use
-			// curl -X GET http://127.0.0.163:8000/msg/ -w "\n"
+			// curl -X GET "http://127.0.0.163:8000/msg/?author=Anya&since=2025-01-01T00:00:00Z&limit=10" -w "\n"
+			// pass the nextCursor of the response as cursor to get the next page
 		},
 		{
-			Name:    "Get Message by ID",
-			Method:  http.MethodGet,
-			Pattern: "/:id", // ":" is used for dynamic parameter
-			APIFunc: s.HTTPGetMessageByID,
+			Name:     "Stream Messages",
+			Method:   http.MethodGet,
+			Pattern:  "/stream",
+			APIFunc:  s.HTTPStreamMessages,
+			Response: eventStream(""),
+			Query:    []string{"author"},
+			// Use
+			// curl -N http://127.0.0.163:8000/msg/stream?author=Anya,Loid
+			// add -H "Last-Event-ID: 42" to resume after the event 42
+		},
+		{
+			Name:     "Get Message by ID",
+			Method:   http.MethodGet,
+			Pattern:  "/:id", // ":" is used for dynamic parameter
+			APIFunc:  s.HTTPGetMessageByID,
+			Response: processor.PostMessageResponse{},
 			// Use
 			// curl -X GET http://127.0.0.163:8000/msg/{message-id} -w "\n"
 		},
+		{
+			Name:     "Get Message Thread",
+			Method:   http.MethodGet,
+			Pattern:  "/:id/thread",
+			APIFunc:  s.HTTPGetMessageThread,
+			Response: processor.GetMessagesResponse{},
+			// Use
+			// curl -X GET http://127.0.0.163:8000/msg/{message-id}/thread -w "\n"
+		},
+		{
+			Name:     "Edit Message",
+			Method:   http.MethodPatch,
+			Pattern:  "/:id",
+			APIFunc:  s.HTTPEditMessage,
+			Request:  processor.EditMessageRequest{},
+			Response: processor.PostMessageResponse{},
+			// Use
+			// curl -X PATCH http://127.0.0.163:8000/msg/{message-id} \
+			//   -H "Content-Type: application/json" \
+			//   -d '{"content":"Hello again"}' -w "\n"
+		},
+		{
+			Name:    "Delete Message",
+			Method:  http.MethodDelete,
+			Pattern: "/:id",
+			APIFunc: s.HTTPDeleteMessage,
+			Status:  http.StatusNoContent,
+			// Use
+			// curl -X DELETE http://127.0.0.163:8000/msg/{message-id} -w "\n"
+		},
 	}
 }
 
@@ -45,10 +99,7 @@ func (s *Server) HTTPPostMessage(c *gin.Context) {
 	var req processor.PostMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		logger.SBILog.Errorf("Invalid request body: %+v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"message": "Invalid request body",
-			"error":   err.Error(),
-		})
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, "Invalid request body: "+err.Error())
 		return
 	}
 	// if req has redundant fields, it will be ignored
@@ -58,7 +109,24 @@ func (s *Server) HTTPPostMessage(c *gin.Context) {
 func (s *Server) HTTPGetMessages(c *gin.Context) {
 	logger.SBILog.Infof("In HTTPGetMessages")
 
-	s.Processor().GetMessages(c)
+	var query processor.MessageQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, "Invalid query: "+err.Error())
+		return
+	}
+	if query.Since != nil && query.Until != nil && query.Until.Before(*query.Since) {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, "until must not be before since",
+			models.InvalidParam{Param: "until"})
+		return
+	}
+	if query.Limit > processor.MessageMaxLimit {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam,
+			fmt.Sprintf("limit must not be above %d", processor.MessageMaxLimit),
+			models.InvalidParam{Param: "limit"})
+		return
+	}
+
+	s.Processor().GetMessages(c, query)
 }
 
 func (s *Server) HTTPGetMessageByID(c *gin.Context) {
@@ -68,3 +136,41 @@ func (s *Server) HTTPGetMessageByID(c *gin.Context) {
 
 	s.Processor().GetMessageByID(c, messageID)
 }
+
+func (s *Server) HTTPGetMessageThread(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPGetMessageThread")
+
+	s.Processor().GetMessageThread(c, c.Param("id"))
+}
+
+func (s *Server) HTTPEditMessage(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPEditMessage")
+
+	var req processor.EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.SBILog.Errorf("Invalid request body: %+v", err)
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, "Invalid request body: "+err.Error())
+		return
+	}
+
+	s.Processor().EditMessage(c, c.Param("id"), req)
+}
+
+func (s *Server) HTTPDeleteMessage(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPDeleteMessage")
+
+	s.Processor().DeleteMessage(c, c.Param("id"))
+}
+
+func (s *Server) HTTPStreamMessages(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPStreamMessages")
+
+	var authors []string
+	for _, author := range strings.Split(c.Query("author"), ",") {
+		if author = strings.TrimSpace(author); author != "" {
+			authors = append(authors, author)
+		}
+	}
+
+	s.Processor().StreamMessages(c, authors, c.GetHeader("Last-Event-ID"))
+}
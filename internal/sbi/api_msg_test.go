@@ -5,6 +5,7 @@ package sbi_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	nf_context "github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/sbi"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/storage"
 	"github.com/Alonza0314/nf-example/pkg/factory"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/mock/gomock"
@@ -78,9 +80,8 @@ func Test_HTTPPostMessage(t *testing.T) {
 
 		// Set up mock context with an empty messages slice
 		// This represents the initial state before any messages are created
-		mockContext := &nf_context.NFContext{
-			Messages: []nf_context.Message{}, // Start with no existing messages
-		}
+		mockContext := &nf_context.NFContext{}
+		mockContext.UseStorage(storage.NewMemoryStorage())
 		// Expect the Context() method to be called once during message processing
 		mockProcessor.EXPECT().Context().Return(mockContext).Times(1)
 
@@ -151,7 +152,7 @@ func Test_HTTPPostMessage(t *testing.T) {
 	t.Run("Post Message with Invalid JSON", func(t *testing.T) {
 		// Define expected response for invalid JSON
 		const EXPECTED_STATUS = http.StatusBadRequest
-		const EXPECTED_MESSAGE = "Invalid request body"
+		const EXPECTED_CAUSE = "INVALID_MSG_FORMAT"
 
 		// Create intentionally malformed JSON (missing value after "author":)
 		// This simulates a client sending corrupted or incomplete JSON data
@@ -178,22 +179,16 @@ func Test_HTTPPostMessage(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		// Parse and verify the error response
-		var response map[string]interface{}
-		err = json.Unmarshal(httpRecorder.Body.Bytes(), &response)
-		if err != nil {
-			t.Errorf("Failed to unmarshal response: %s", err)
-		}
-
-		// Verify the error message is descriptive
-		if response["message"] != EXPECTED_MESSAGE {
-			t.Errorf("Expected message %s, got %s", EXPECTED_MESSAGE, response["message"])
+		// Parse and verify the problem details
+		problem := decodeProblem(t, httpRecorder)
+		if problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 
-		// Verify that an error field is present in the response
+		// Verify that a detail is present in the response
 		// This helps clients understand what went wrong
-		if response["error"] == nil {
-			t.Errorf("Expected error field to be present")
+		if problem.Detail == "" {
+			t.Errorf("Expected detail to be present")
 		}
 	})
 
@@ -202,7 +197,7 @@ func Test_HTTPPostMessage(t *testing.T) {
 	t.Run("Post Message with Missing Required Fields", func(t *testing.T) {
 		// Define expected response for incomplete request
 		const EXPECTED_STATUS = http.StatusBadRequest
-		const EXPECTED_MESSAGE = "Invalid request body"
+		const EXPECTED_CAUSE = "INVALID_MSG_FORMAT"
 
 		// Create request body missing the "author" field
 		// This tests the API's input validation capabilities
@@ -238,16 +233,9 @@ func Test_HTTPPostMessage(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		// Parse and verify the validation error response
-		var response map[string]interface{}
-		err = json.Unmarshal(httpRecorder.Body.Bytes(), &response)
-		if err != nil {
-			t.Errorf("Failed to unmarshal response: %s", err)
-		}
-
-		// Verify appropriate error message
-		if response["message"] != EXPECTED_MESSAGE {
-			t.Errorf("Expected message %s, got %s", EXPECTED_MESSAGE, response["message"])
+		// Verify appropriate error cause
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 	})
 }
@@ -291,9 +279,8 @@ func Test_HTTPGetMessages(t *testing.T) {
 
 		// Set up mock context with empty messages array
 		// This simulates the state when no messages have been created yet
-		mockContext := &nf_context.NFContext{
-			Messages: []nf_context.Message{}, // Empty slice represents no existing messages
-		}
+		mockContext := &nf_context.NFContext{}
+		mockContext.UseStorage(storage.NewMemoryStorage())
 		// Expect the Context() method to be called once during message retrieval
 		mockProcessor.EXPECT().Context().Return(mockContext).Times(1)
 
@@ -336,6 +323,47 @@ func Test_HTTPGetMessages(t *testing.T) {
 			t.Errorf("Expected data field to be an array")
 		}
 	})
+
+	// Test case: Invalid filters are rejected before the processor is reached
+	// No Context() call is expected, the query never gets to the storage
+	invalidQueries := map[string]string{
+		"Malformed since":     "/msg/?since=yesterday",
+		"Until before since":  "/msg/?since=2023-01-02T00:00:00Z&until=2023-01-01T00:00:00Z",
+		"Limit above maximum": "/msg/?limit=1000",
+	}
+	for name, target := range invalidQueries {
+		t.Run(name, func(t *testing.T) {
+			httpRecorder := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(httpRecorder)
+			ginCtx.Request = httptest.NewRequest("GET", target, nil)
+
+			server.HTTPGetMessages(ginCtx)
+
+			if httpRecorder.Code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, httpRecorder.Code)
+			}
+			if problem := decodeProblem(t, httpRecorder); problem.Cause != "INVALID_QUERY_PARAM" {
+				t.Errorf("Expected cause INVALID_QUERY_PARAM, got %s", problem.Cause)
+			}
+		})
+	}
+
+	t.Run("Limit above MessageMaxLimit", func(t *testing.T) {
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		ginCtx.Request = httptest.NewRequest("GET", fmt.Sprintf("/msg/?limit=%d", processor.MessageMaxLimit+1), nil)
+
+		server.HTTPGetMessages(ginCtx)
+
+		problem := decodeProblem(t, httpRecorder)
+		expectedDetail := fmt.Sprintf("limit must not be above %d", processor.MessageMaxLimit)
+		if problem.Detail != expectedDetail {
+			t.Errorf("Expected detail %q, got %q", expectedDetail, problem.Detail)
+		}
+		if len(problem.InvalidParams) != 1 || problem.InvalidParams[0].Param != "limit" {
+			t.Errorf("Expected invalid param limit, got %+v", problem.InvalidParams)
+		}
+	})
 }
 
 // Test_HTTPGetMessageByID tests the HTTP GET endpoint for retrieving a specific message by ID
@@ -378,9 +406,8 @@ func Test_HTTPGetMessageByID(t *testing.T) {
 
 		// Set up mock context with no messages
 		// This simulates a scenario where the requested message doesn't exist
-		mockContext := &nf_context.NFContext{
-			Messages: []nf_context.Message{}, // Empty slice means no messages exist
-		}
+		mockContext := &nf_context.NFContext{}
+		mockContext.UseStorage(storage.NewMemoryStorage())
 		// Expect the Context() method to be called once during message lookup
 		mockProcessor.EXPECT().Context().Return(mockContext).Times(1)
 
@@ -411,16 +438,9 @@ func Test_HTTPGetMessageByID(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		// Parse and verify the error response
-		var response map[string]interface{}
-		err = json.Unmarshal(httpRecorder.Body.Bytes(), &response)
-		if err != nil {
-			t.Errorf("Failed to unmarshal response: %s", err)
-		}
-
-		// Verify that the response contains an appropriate error message
-		if response["message"] != "Message not found" {
-			t.Errorf("Expected message 'Message not found', got %s", response["message"])
+		// Verify that the response carries the not found cause
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != "DATA_NOT_FOUND" {
+			t.Errorf("Expected cause DATA_NOT_FOUND, got %s", problem.Cause)
 		}
 	})
 }
@@ -1,43 +1,120 @@
 package sbi
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
+	"strings"
 
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
 func (s *Server) getOnePieceRoute() []Route {
 	return []Route{
 		{
-			Name:    "Hello Straw Hats",
-			Method:  http.MethodGet,
-			Pattern: "/",
-			APIFunc: s.HTTPOnePieceGreeting,
+			Name:     "Hello Straw Hats",
+			Method:   http.MethodGet,
+			Pattern:  "/",
+			APIFunc:  s.HTTPOnePieceGreeting,
+			Response: "",
 		},
 		{
-			Name:    "Recruit Straw Hat",
-			Method:  http.MethodPost,
-			Pattern: "/crew",
-			APIFunc: s.HTTPOnePieceRecruit,
+			Name:     "Recruit Straw Hat",
+			Method:   http.MethodPost,
+			Pattern:  "/crew",
+			APIFunc:  s.HTTPOnePieceRecruit,
+			Request:  OnePieceRecruitRequest{},
+			Response: "",
+			Status:   http.StatusCreated,
+			// Use
+			// curl -X POST http://127.0.0.163:8000/onepiece/crew -d '{"name": "Zoro", "role": "Swordsman", "bounty": 1111000000}'
+		},
+		{
+			Name:     "List Straw Hat Crew",
+			Method:   http.MethodGet,
+			Pattern:  "/crew",
+			APIFunc:  s.HTTPOnePieceListCrew,
+			Response: processor.OnePieceCrewResponse{},
+			// Use
+			// curl -X GET http://127.0.0.163:8000/onepiece/crew
+		},
+		{
+			Name:     "Get Straw Hat",
+			Method:   http.MethodGet,
+			Pattern:  "/crew/:name",
+			APIFunc:  s.HTTPOnePieceGetCrewMember,
+			Response: nf_context.CrewMember{},
+			// Use
+			// curl -X GET http://127.0.0.163:8000/onepiece/crew/Zoro
+		},
+		{
+			Name:     "Remove Straw Hat",
+			Method:   http.MethodDelete,
+			Pattern:  "/crew/:name",
+			APIFunc:  s.HTTPOnePieceDeleteCrewMember,
+			Response: "",
+			// Use
+			// curl -X DELETE http://127.0.0.163:8000/onepiece/crew/Zoro
 		},
 	}
 }
 
+type OnePieceRecruitRequest struct {
+	Name string `json:"name" binding:"required"`
+	Role string `json:"role"`
+	// Bounty is in berries
+	Bounty int64 `json:"bounty"`
+}
+
 func (s *Server) HTTPOnePieceGreeting(c *gin.Context) {
 	c.JSON(http.StatusOK, "Hello Straw Hat Pirates!")
 }
 
 func (s *Server) HTTPOnePieceRecruit(c *gin.Context) {
-	var request struct {
-		Name string `json:"name" binding:"required"`
-	}
+	logger.SBILog.Infof("In HTTPOnePieceRecruit")
 
+	var request OnePieceRecruitRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		// a body without name fails the binding validation, the others are malformed
+		var invalid validator.ValidationErrors
+		if errors.As(err, &invalid) {
+			util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "name is required")
+			return
+		}
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, err.Error())
+		return
+	}
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "name is required")
 		return
 	}
+	if request.Bounty < 0 {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, "bounty must not be negative")
+		return
+	}
+
+	s.Processor().RecruitOnePieceCrewMember(c, name, strings.TrimSpace(request.Role), request.Bounty)
+}
+
+func (s *Server) HTTPOnePieceListCrew(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPOnePieceListCrew")
+
+	s.Processor().ListOnePieceCrew(c)
+}
+
+func (s *Server) HTTPOnePieceGetCrewMember(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPOnePieceGetCrewMember")
+
+	s.Processor().GetOnePieceCrewMember(c, c.Param("name"))
+}
+
+func (s *Server) HTTPOnePieceDeleteCrewMember(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPOnePieceDeleteCrewMember")
 
-	message := fmt.Sprintf("%s has joined the Straw Hat crew!", request.Name)
-	c.JSON(http.StatusCreated, message)
+	s.Processor().DeleteOnePieceCrewMember(c, c.Param("name"))
 }
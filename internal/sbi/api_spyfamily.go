@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,17 +18,59 @@ func (s *Server) getSpyFamilyRoute() []Route {
 			APIFunc: func(c *gin.Context) {
 				c.JSON(http.StatusOK, "Hello SPYxFAMILY!")
 			},
+			Response: "",
 			// Use
 			// curl -X GET http://127.0.0.163:8000/spyfamily/ -w "\n"
 		},
 		{
-			Name:    "SPYxFAMILY Character",
-			Method:  http.MethodGet,
+			Name:     "SPYxFAMILY Character",
+			Method:   http.MethodGet,
+			Pattern:  "/character/:Name",
+			APIFunc:  s.HTTPSerchSpyFamilyCharacter,
+			Response: processor.SpyFamilyCharacterResponse{},
+			// Use
+			// curl -X GET http://127.0.0.163:8000/spyfamily/character/Anya -w "\n"
+			// {"firstName":"Anya","lastName":"Forger","fullName":"Anya Forger","family":"Forger",...}
+		},
+		{
+			Name:     "List SPYxFAMILY Characters",
+			Method:   http.MethodGet,
+			Pattern:  "/characters",
+			APIFunc:  s.HTTPListSpyFamilyCharacters,
+			Response: []processor.SpyFamilyCharacterResponse{},
+			Query:    []string{"family", "affiliation", "q", "offset", "limit"},
+			// Use
+			// curl -X GET "http://127.0.0.163:8000/spyfamily/characters?affiliation=WISE&q=night"
+		},
+		{
+			Name:     "Add SPYxFAMILY Character",
+			Method:   http.MethodPost,
+			Pattern:  "/character",
+			APIFunc:  s.HTTPAddSpyFamilyCharacter,
+			Request:  processor.SpyFamilyCharacterRequest{},
+			Response: processor.SpyFamilyCharacterResponse{},
+			Status:   http.StatusCreated,
+			// Use
+			// curl -X POST http://127.0.0.163:8000/spyfamily/character -d '{"firstName": "Donovan", "lastName": "Desmond", "affiliation": "National Unity Party"}'
+		},
+		{
+			Name:     "Update SPYxFAMILY Character",
+			Method:   http.MethodPut,
+			Pattern:  "/character/:Name",
+			APIFunc:  s.HTTPUpdateSpyFamilyCharacter,
+			Request:  processor.SpyFamilyCharacterRequest{},
+			Response: processor.SpyFamilyCharacterResponse{},
+			// Use
+			// curl -X PUT http://127.0.0.163:8000/spyfamily/character/Donovan -d '{"lastName": "Desmond", "affiliation": "Ostania"}'
+		},
+		{
+			Name:    "Delete SPYxFAMILY Character",
+			Method:  http.MethodDelete,
 			Pattern: "/character/:Name",
-			APIFunc: s.HTTPSerchSpyFamilyCharacter,
+			APIFunc: s.HTTPDeleteSpyFamilyCharacter,
+			Status:  http.StatusNoContent,
 			// Use
-			// curl -X GET http://127.0.0.163:8000/spyfamily/Anya -w "\n"
-			// "Character: Anya Forger"
+			// curl -X DELETE http://127.0.0.163:8000/spyfamily/character/Donovan
 		},
 	}
 }
@@ -36,9 +80,56 @@ func (s *Server) HTTPSerchSpyFamilyCharacter(c *gin.Context) {
 
 	targetName := c.Param("Name")
 	if targetName == "" {
-		c.String(http.StatusBadRequest, "No name provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No name provided")
 		return
 	}
 
 	s.Processor().FindSpyFamilyCharacterName(c, targetName)
 }
+
+func (s *Server) HTTPListSpyFamilyCharacters(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPListSpyFamilyCharacters")
+
+	s.Processor().ListSpyFamilyCharacters(c)
+}
+
+func (s *Server) HTTPAddSpyFamilyCharacter(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPAddSpyFamilyCharacter")
+
+	var requestbody processor.SpyFamilyCharacterRequest
+	if err := c.ShouldBindBodyWithJSON(&requestbody); err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, err.Error())
+		return
+	}
+
+	s.Processor().AddSpyFamilyCharacter(c, requestbody)
+}
+
+func (s *Server) HTTPUpdateSpyFamilyCharacter(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPUpdateSpyFamilyCharacter")
+
+	targetName := c.Param("Name")
+	if targetName == "" {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No name provided")
+		return
+	}
+	var requestbody processor.SpyFamilyCharacterRequest
+	if err := c.ShouldBindBodyWithJSON(&requestbody); err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, err.Error())
+		return
+	}
+
+	s.Processor().UpdateSpyFamilyCharacter(c, targetName, requestbody)
+}
+
+func (s *Server) HTTPDeleteSpyFamilyCharacter(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPDeleteSpyFamilyCharacter")
+
+	targetName := c.Param("Name")
+	if targetName == "" {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No name provided")
+		return
+	}
+
+	s.Processor().DeleteSpyFamilyCharacter(c, targetName)
+}
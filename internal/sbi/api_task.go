@@ -3,6 +3,8 @@ package sbi
 import (
 	"net/http"
 
+	"github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/sbi/processor"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,19 +16,82 @@ func (s *Server) HTTPGetAllTasks(c *gin.Context) {
 	s.Processor().GetAllTasks(c)
 }
 
+func (s *Server) HTTPGetTask(c *gin.Context) {
+	s.Processor().GetTask(c)
+}
+
+func (s *Server) HTTPUpdateTask(c *gin.Context) {
+	s.Processor().UpdateTask(c)
+}
+
+func (s *Server) HTTPPatchTask(c *gin.Context) {
+	s.Processor().PatchTask(c)
+}
+
+func (s *Server) HTTPDeleteTask(c *gin.Context) {
+	s.Processor().DeleteTask(c)
+}
+
 func (s *Server) getTaskRoute() []Route {
 	return []Route{
 		{
-			Name:    "Get All Tasks",
-			Method:  http.MethodGet,
-			Pattern: "/tasks",
-			APIFunc: s.HTTPGetAllTasks,
+			Name:     "Get All Tasks",
+			Method:   http.MethodGet,
+			Pattern:  "/tasks",
+			APIFunc:  s.HTTPGetAllTasks,
+			Response: []context.Task{},
+			Query:    []string{"status", "sort", "offset", "limit"},
+			// Use
+			// curl -X GET "http://127.0.0.163:8000/task/tasks?status=todo,doing&sort=-priority&offset=0&limit=10"
+		},
+		{
+			Name:     "Create New Task",
+			Method:   http.MethodPost,
+			Pattern:  "/tasks",
+			APIFunc:  s.HTTPCreateNewTask,
+			Request:  processor.TaskRequest{},
+			Response: context.Task{},
+			Status:   http.StatusCreated,
+			// Use
+			// curl -X POST http://127.0.0.163:8000/task/tasks -d '{"name": "Buy peanuts", "priority": 3, "dueDate": "2025-01-01T00:00:00Z"}'
+		},
+		{
+			Name:     "Get Task",
+			Method:   http.MethodGet,
+			Pattern:  "/tasks/:id",
+			APIFunc:  s.HTTPGetTask,
+			Response: context.Task{},
+			// Use
+			// curl -X GET http://127.0.0.163:8000/task/tasks/1
+		},
+		{
+			Name:     "Update Task",
+			Method:   http.MethodPut,
+			Pattern:  "/tasks/:id",
+			APIFunc:  s.HTTPUpdateTask,
+			Request:  processor.TaskRequest{},
+			Response: context.Task{},
+			// Use
+			// curl -X PUT http://127.0.0.163:8000/task/tasks/1 -d '{"name": "Buy peanuts", "status": "doing"}'
+		},
+		{
+			Name:     "Patch Task",
+			Method:   http.MethodPatch,
+			Pattern:  "/tasks/:id",
+			APIFunc:  s.HTTPPatchTask,
+			Request:  processor.TaskPatchRequest{},
+			Response: context.Task{},
+			// Use
+			// curl -X PATCH http://127.0.0.163:8000/task/tasks/1 -d '{"status": "done"}'
 		},
 		{
-			Name:    "Create New Task",
-			Method:  http.MethodPost,
-			Pattern: "/tasks",
-			APIFunc: s.HTTPCreateNewTask,
+			Name:    "Delete Task",
+			Method:  http.MethodDelete,
+			Pattern: "/tasks/:id",
+			APIFunc: s.HTTPDeleteTask,
+			Status:  http.StatusNoContent,
+			// Use
+			// curl -X DELETE http://127.0.0.163:8000/task/tasks/1
 		},
 	}
 }
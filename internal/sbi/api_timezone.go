@@ -5,6 +5,7 @@ import (
 
 	"github.com/Alonza0314/nf-example/internal/logger"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,50 +18,93 @@ func (s *Server) getTimeZoneRoute() []Route {
 			APIFunc: func(c *gin.Context) {
 				c.String(http.StatusOK, "Welcome to time zone query service")
 			},
+			Response: plainText(""),
 			// Use
 			// curl -X GET http://127.0.0.163:8000/timezone/ -w "\n"
 		},
 		{
-			Name:    "Query city time zone",
-			Method:  http.MethodGet,
-			Pattern: "/city/:City",
-			APIFunc: s.HTTPGetTimeZoneByCity,
+			Name:     "Query city time zone",
+			Method:   http.MethodGet,
+			Pattern:  "/city/:City",
+			APIFunc:  s.HTTPGetTimeZoneByCity,
+			Response: plainText(""),
 			// Use
 			// curl -X GET http://127.0.0.163:8000/timezone/city/Taipei -w "\n"
 		},
 		{
-			Name:    "Add new city time zone",
-			Method:  http.MethodPost,
-			Pattern: "/city",
-			APIFunc: s.HTTPAddNewCityTimeZone,
+			Name:     "Query city local time",
+			Method:   http.MethodGet,
+			Pattern:  "/city/:City/time",
+			APIFunc:  s.HTTPGetCityTime,
+			Response: processor.CityTime{},
+			Query:    []string{"at"},
+			// Use
+			// curl -X GET "http://127.0.0.163:8000/timezone/city/Paris/time?at=2025-07-01T12:00:00Z" -w "\n"
+			// at is optional and defaults to now
+		},
+		{
+			Name:     "Convert time between cities",
+			Method:   http.MethodGet,
+			Pattern:  "/convert",
+			APIFunc:  s.HTTPConvertTime,
+			Response: processor.TimeConversion{},
+			Query:    []string{"from", "to", "time"},
+			// Use
+			// curl -X GET "http://127.0.0.163:8000/timezone/convert?from=Taipei&to=NewYork&time=2025-03-09T12:00:00" -w "\n"
+			// a time without offset is local to the from city, it defaults to now
+		},
+		{
+			Name:     "List cities by UTC offset",
+			Method:   http.MethodGet,
+			Pattern:  "/offset/:Offset",
+			APIFunc:  s.HTTPGetCitiesByOffset,
+			Response: processor.OffsetCities{},
+			Query:    []string{"at"},
 			// Use
-			// curl -X POST http://127.0.0.163:8000/timezone/city -d '{"City": "Chicago", "TimeZone": "UTC-5"}' -w "\n"
+			// curl -X GET "http://127.0.0.163:8000/timezone/offset/+09:00" -w "\n"
 		},
 		{
-			Name:    "Reset city time zone",
-			Method:  http.MethodPost,
-			Pattern: "/city/:City",
-			APIFunc: s.HTTPResetCityTimeZone,
+			Name:     "Add new city time zone",
+			Method:   http.MethodPost,
+			Pattern:  "/city",
+			APIFunc:  s.HTTPAddNewCityTimeZone,
+			Request:  processor.TimeZoneRequest{},
+			Response: plainText(""),
 			// Use
-			// curl -X POST http://127.0.0.163:8000/timezone/city/Chicago -d '{"TimeZone": "UTC-6"}' -w "\n"
+			// curl -X POST http://127.0.0.163:8000/timezone/city -d '{"City": "Chicago", "TimeZone": "America/Chicago"}' -w "\n"
 		},
 		{
-			Name:    "Delete city time zone",
-			Method:  http.MethodDelete,
-			Pattern: "/city/:City",
-			APIFunc: s.HTTPDeleteCityTimeZone,
+			Name:     "Reset city time zone",
+			Method:   http.MethodPost,
+			Pattern:  "/city/:City",
+			APIFunc:  s.HTTPResetCityTimeZone,
+			Request:  TimeZoneResetRequest{},
+			Response: plainText(""),
+			// Use
+			// curl -X POST http://127.0.0.163:8000/timezone/city/Chicago -d '{"TimeZone": "America/Winnipeg"}' -w "\n"
+		},
+		{
+			Name:     "Delete city time zone",
+			Method:   http.MethodDelete,
+			Pattern:  "/city/:City",
+			APIFunc:  s.HTTPDeleteCityTimeZone,
+			Response: plainText(""),
 			// Usage:
 			// curl -X DELETE http://127.0.0.163:8000/timezone/city/Chicago -w "\n"
 		},
 	}
 }
 
+type TimeZoneResetRequest struct {
+	TZ string `json:"TimeZone"`
+}
+
 func (s *Server) HTTPGetTimeZoneByCity(c *gin.Context) {
 	logger.SBILog.Infof("In HTTPGetTimeZoneByCity")
 
 	city := c.Param("City")
 	if city == "" {
-		c.String(http.StatusBadRequest, "No city provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No city provided")
 		return
 	}
 	s.Processor().HandleGetTimeZone(c, city)
@@ -71,12 +115,12 @@ func (s *Server) HTTPAddNewCityTimeZone(c *gin.Context) {
 
 	var req processor.TimeZoneRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.String(http.StatusBadRequest, "Invalid JSON")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, "Invalid JSON")
 		return
 	}
 
 	if req.City == "" || req.TimeZone == "" {
-		c.String(http.StatusBadRequest, "City and TimeZone fields are required")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "City and TimeZone fields are required")
 		return
 	}
 	s.Processor().HandleAddNewCityTimeZone(c, req)
@@ -87,20 +131,18 @@ func (s *Server) HTTPResetCityTimeZone(c *gin.Context) {
 
 	city := c.Param("City")
 	if city == "" {
-		c.String(http.StatusBadRequest, "No city provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No city provided")
 		return
 	}
 
-	var req struct {
-		TZ string `json:"TimeZone"`
-	}
+	var req TimeZoneResetRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.String(http.StatusBadRequest, "Invalid JSON format, expected object with TimeZone field")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, "Invalid JSON format, expected object with TimeZone field")
 		return
 	}
 
 	if req.TZ == "" {
-		c.String(http.StatusBadRequest, "TimeZone field is required")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "TimeZone field is required")
 		return
 	}
 
@@ -112,8 +154,36 @@ func (s *Server) HTTPDeleteCityTimeZone(c *gin.Context) {
 
 	city := c.Param("City")
 	if city == "" {
-		c.String(http.StatusBadRequest, "No city provided")
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No city provided")
 		return
 	}
 	s.Processor().HandleDeleteCityTimeZone(c, city)
 }
+
+func (s *Server) HTTPGetCityTime(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPGetCityTime")
+
+	city := c.Param("City")
+	if city == "" {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No city provided")
+		return
+	}
+	s.Processor().HandleGetCityTime(c, city, c.Query("at"))
+}
+
+func (s *Server) HTTPConvertTime(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPConvertTime")
+
+	from, to := c.Query("from"), c.Query("to")
+	if from == "" || to == "" {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "from and to cities are required")
+		return
+	}
+	s.Processor().HandleConvertTime(c, from, to, c.Query("time"))
+}
+
+func (s *Server) HTTPGetCitiesByOffset(c *gin.Context) {
+	logger.SBILog.Infof("In HTTPGetCitiesByOffset")
+
+	s.Processor().HandleGetCitiesByOffset(c, c.Param("Offset"), c.Query("at"))
+}
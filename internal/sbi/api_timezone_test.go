@@ -63,7 +63,7 @@ func Test_HTTPGetTimeZoneByCity(t *testing.T) {
 
 	t.Run("No city provided", func(t *testing.T) {
 		const EXPECTED_STATUS = http.StatusBadRequest
-		const EXPECTED_BODY = "No city provided"
+		const EXPECTED_CAUSE = "MANDATORY_IE_MISSING"
 
 		httpRecorder, ginCtx := createJSONRequest(t, "GET", "/timezone/city/", "", nil)
 		if ginCtx == nil {
@@ -76,8 +76,8 @@ func Test_HTTPGetTimeZoneByCity(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 	})
 }
@@ -87,7 +87,7 @@ func Test_HTTPAddNewCityTimeZone(t *testing.T) {
 
 	t.Run("Invalid JSON", func(t *testing.T) {
 		const EXPECTED_STATUS = http.StatusBadRequest
-		const EXPECTED_BODY = "Invalid JSON"
+		const EXPECTED_CAUSE = "INVALID_MSG_FORMAT"
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -107,19 +107,19 @@ func Test_HTTPAddNewCityTimeZone(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 	})
 
 	t.Run("Missing required fields", func(t *testing.T) {
 		const EXPECTED_STATUS = http.StatusBadRequest
-		const EXPECTED_BODY = "City and TimeZone fields are required"
+		const EXPECTED_CAUSE = "MANDATORY_IE_MISSING"
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
 
-		incompleteJSON := bytes.NewBufferString(`{"City": "", "TimeZone": "UTC+8"}`)
+		incompleteJSON := bytes.NewBufferString(`{"City": "", "TimeZone": "Asia/Taipei"}`)
 		var err error
 		ginCtx.Request, err = http.NewRequest("POST", "/timezone/city", incompleteJSON)
 		if err != nil {
@@ -134,8 +134,8 @@ func Test_HTTPAddNewCityTimeZone(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 	})
 }
@@ -145,12 +145,12 @@ func Test_HTTPResetCityTimeZone(t *testing.T) {
 
 	t.Run("No city provided", func(t *testing.T) {
 		const EXPECTED_STATUS = http.StatusBadRequest
-		const EXPECTED_BODY = "No city provided"
+		const EXPECTED_CAUSE = "MANDATORY_IE_MISSING"
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
 
-		validJSON := bytes.NewBufferString(`{"TimeZone": "UTC+8"}`)
+		validJSON := bytes.NewBufferString(`{"TimeZone": "Asia/Taipei"}`)
 		var err error
 		ginCtx.Request, err = http.NewRequest("POST", "/timezone/city/", validJSON)
 		if err != nil {
@@ -165,14 +165,14 @@ func Test_HTTPResetCityTimeZone(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 	})
 
 	t.Run("Invalid JSON format", func(t *testing.T) {
 		const EXPECTED_STATUS = http.StatusBadRequest
-		const EXPECTED_BODY = "Invalid JSON format, expected object with TimeZone field"
+		const EXPECTED_CAUSE = "INVALID_MSG_FORMAT"
 
 		httpRecorder, ginCtx := createJSONRequest(t, "POST", "/timezone/city/Taipei",
 			"{invalid json}", gin.Params{gin.Param{Key: "City", Value: "Taipei"}})
@@ -186,14 +186,14 @@ func Test_HTTPResetCityTimeZone(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 	})
 
 	t.Run("Missing TimeZone field", func(t *testing.T) {
 		const EXPECTED_STATUS = http.StatusBadRequest
-		const EXPECTED_BODY = "TimeZone field is required"
+		const EXPECTED_CAUSE = "MANDATORY_IE_MISSING"
 
 		httpRecorder, ginCtx := createJSONRequest(t, "POST", "/timezone/city/Taipei",
 			`{"TimeZone": ""}`, gin.Params{gin.Param{Key: "City", Value: "Taipei"}})
@@ -207,8 +207,8 @@ func Test_HTTPResetCityTimeZone(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 	})
 }
@@ -218,7 +218,7 @@ func Test_HTTPDeleteCityTimeZone(t *testing.T) {
 
 	t.Run("No city provided", func(t *testing.T) {
 		const EXPECTED_STATUS = http.StatusBadRequest
-		const EXPECTED_BODY = "No city provided"
+		const EXPECTED_CAUSE = "MANDATORY_IE_MISSING"
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -236,8 +236,8 @@ func Test_HTTPDeleteCityTimeZone(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		if problem := decodeProblem(t, httpRecorder); problem.Cause != EXPECTED_CAUSE {
+			t.Errorf("Expected cause %s, got %s", EXPECTED_CAUSE, problem.Cause)
 		}
 	})
 }
@@ -0,0 +1,135 @@
+package sbi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/Alonza0314/nf-example/internal/logger"
+)
+
+const (
+	certWatchInterval = 10 * time.Second
+	// certExpiryWarning is how long before expiry the certificate is reported as expiring
+	certExpiryWarning = 30 * 24 * time.Hour
+	// certExpiryReminder spaces the warnings about an expiring certificate
+	certExpiryReminder = 24 * time.Hour
+)
+
+// certReloader serves the SBI certificate through tls.Config.GetCertificate and swaps in
+// the pem and key files again when they change, the open connections keep their certificate.
+type certReloader struct {
+	pemPath string
+	keyPath string
+	cert    atomic.Pointer[tls.Certificate]
+
+	// stamp and warnedAt are only used by the watch loop after newCertReloader
+	stamp    string
+	warnedAt time.Time
+}
+
+func newCertReloader(pemPath string, keyPath string) (*certReloader, error) {
+	r := &certReloader{
+		pemPath: pemPath,
+		keyPath: keyPath,
+	}
+	stamp, err := r.fileStamp()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(stamp, time.Now()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// fileStamp identifies the current content of the pem and key files by modification time and size.
+func (r *certReloader) fileStamp() (string, error) {
+	var stamp string
+	for _, path := range []string{r.pemPath, r.keyPath} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		stamp += fmt.Sprintf("%d/%d;", info.ModTime().UnixNano(), info.Size())
+	}
+	return stamp, nil
+}
+
+func (r *certReloader) load(stamp string, now time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.pemPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("load certificate [%s]: %+v", r.pemPath, err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return fmt.Errorf("parse certificate [%s]: %+v", r.pemPath, err)
+		}
+	}
+
+	r.cert.Store(&cert)
+	r.stamp = stamp
+	r.warnedAt = time.Time{}
+	logger.SBILog.Infof("Loaded certificate [%s] of [%s], valid until %s",
+		r.pemPath, cert.Leaf.Subject, cert.Leaf.NotAfter.Format(time.RFC3339))
+	r.checkExpiry(now)
+	return nil
+}
+
+// reloadIfChanged loads the files again when their stamp changed. A certificate that fails
+// to load, usually because only one of the files is written yet, is retried on the next check.
+func (r *certReloader) reloadIfChanged(now time.Time) {
+	stamp, err := r.fileStamp()
+	if err != nil {
+		logger.SBILog.Errorf("Check certificate [%s] failed: %+v", r.pemPath, err)
+		return
+	}
+	if stamp == r.stamp {
+		return
+	}
+	if err := r.load(stamp, now); err != nil {
+		logger.SBILog.Errorf("Reload certificate failed, keep the current one: %+v", err)
+	}
+}
+
+// checkExpiry warns about a certificate expiring within certExpiryWarning, once per certExpiryReminder.
+func (r *certReloader) checkExpiry(now time.Time) {
+	leaf := r.cert.Load().Leaf
+	remaining := leaf.NotAfter.Sub(now)
+	if remaining > certExpiryWarning {
+		return
+	}
+	if !r.warnedAt.IsZero() && now.Sub(r.warnedAt) < certExpiryReminder {
+		return
+	}
+	r.warnedAt = now
+	if remaining <= 0 {
+		logger.SBILog.Errorf("Certificate [%s] expired at %s", r.pemPath, leaf.NotAfter.Format(time.RFC3339))
+		return
+	}
+	logger.SBILog.Warnf("Certificate [%s] expires at %s, in %d days", r.pemPath,
+		leaf.NotAfter.Format(time.RFC3339), int(remaining.Hours()/24))
+}
+
+// watch checks the files every certWatchInterval until done is closed.
+func (r *certReloader) watch(done <-chan struct{}) {
+	ticker := time.NewTicker(certWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			r.reloadIfChanged(now)
+			r.checkExpiry(now)
+		}
+	}
+}
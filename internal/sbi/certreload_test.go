@@ -0,0 +1,89 @@
+package sbi
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertPair writes a certificate of commonName valid for a year from issuedAt, the files
+// get modTime so consecutive writes are told apart whatever the file system resolution.
+func writeCertPair(t *testing.T, pemPath, keyPath, commonName string, issuedAt, modTime time.Time) {
+	t.Helper()
+	certPEM, keyPEM, _, err := newSelfSignedCert(commonName, []string{"127.0.0.1"}, issuedAt)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(pemPath, certPEM, 0o600))
+	require.NoError(t, os.WriteFile(keyPath, keyPEM, 0o600))
+	require.NoError(t, os.Chtimes(pemPath, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyPath, modTime, modTime))
+}
+
+func servedCommonName(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+	conn, err := tls.Dial("tcp", ts.Listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func Test_CertReloader(t *testing.T) {
+	dir := t.TempDir()
+	pemPath, keyPath := filepath.Join(dir, "nf.pem"), filepath.Join(dir, "nf.key")
+	now := time.Now()
+	writeCertPair(t, pemPath, keyPath, "first", now, now.Add(-time.Hour))
+
+	reloader, err := newCertReloader(pemPath, keyPath)
+	require.NoError(t, err)
+	assert.True(t, reloader.warnedAt.IsZero())
+
+	// not StartTLS, its own certificate would take precedence over GetCertificate
+	ts := httptest.NewUnstartedServer(http.NotFoundHandler())
+	ts.Listener = tls.NewListener(ts.Listener, &tls.Config{GetCertificate: reloader.GetCertificate})
+	ts.Start()
+	defer ts.Close()
+	assert.Equal(t, "first", servedCommonName(t, ts))
+
+	t.Run("Unchanged Files", func(t *testing.T) {
+		current := reloader.cert.Load()
+		reloader.reloadIfChanged(now)
+		assert.Same(t, current, reloader.cert.Load())
+	})
+
+	t.Run("Rotated Files", func(t *testing.T) {
+		writeCertPair(t, pemPath, keyPath, "second", now, now)
+		reloader.reloadIfChanged(now)
+		assert.Equal(t, "second", servedCommonName(t, ts))
+	})
+
+	t.Run("Broken Files Keep The Certificate", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(pemPath, []byte("not a certificate"), 0o600))
+		reloader.reloadIfChanged(now)
+		assert.Equal(t, "second", servedCommonName(t, ts))
+	})
+
+	t.Run("Expiring Certificate", func(t *testing.T) {
+		// issued 350 days ago, so it expires in about 15 days
+		writeCertPair(t, pemPath, keyPath, "expiring", now.Add(-350*24*time.Hour), now.Add(time.Hour))
+		reloader.reloadIfChanged(now)
+		assert.Equal(t, "expiring", servedCommonName(t, ts))
+		assert.Equal(t, now, reloader.warnedAt)
+
+		reloader.checkExpiry(now.Add(time.Hour))
+		assert.Equal(t, now, reloader.warnedAt, "warned again before certExpiryReminder")
+		reloader.checkExpiry(now.Add(certExpiryReminder))
+		assert.Equal(t, now.Add(certExpiryReminder), reloader.warnedAt)
+	})
+}
+
+func Test_NewCertReloaderMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, err := newCertReloader(filepath.Join(dir, "nf.pem"), filepath.Join(dir, "nf.key"))
+	assert.Error(t, err)
+}
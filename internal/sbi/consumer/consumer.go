@@ -0,0 +1,30 @@
+package consumer
+
+import (
+	"github.com/Alonza0314/nf-example/pkg/app"
+
+	"github.com/free5gc/openapi/nrf/NFManagement"
+)
+
+type ConsumerNf interface {
+	app.App
+}
+
+type Consumer struct {
+	ConsumerNf
+
+	*nnrfService
+}
+
+func NewConsumer(nf ConsumerNf) (*Consumer, error) {
+	c := &Consumer{
+		ConsumerNf: nf,
+	}
+
+	c.nnrfService = &nnrfService{
+		consumer:        c,
+		nfMngmntClients: make(map[string]*NFManagement.APIClient),
+	}
+
+	return c, nil
+}
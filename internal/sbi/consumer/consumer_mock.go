@@ -0,0 +1,129 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/sbi/consumer/consumer.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/sbi/consumer/consumer.go -package=consumer
+//
+
+// Package consumer is a generated GoMock package.
+package consumer
+
+import (
+	reflect "reflect"
+
+	context "github.com/Alonza0314/nf-example/internal/context"
+	factory "github.com/Alonza0314/nf-example/pkg/factory"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockConsumerNf is a mock of ConsumerNf interface.
+type MockConsumerNf struct {
+	ctrl     *gomock.Controller
+	recorder *MockConsumerNfMockRecorder
+}
+
+// MockConsumerNfMockRecorder is the mock recorder for MockConsumerNf.
+type MockConsumerNfMockRecorder struct {
+	mock *MockConsumerNf
+}
+
+// NewMockConsumerNf creates a new mock instance.
+func NewMockConsumerNf(ctrl *gomock.Controller) *MockConsumerNf {
+	mock := &MockConsumerNf{ctrl: ctrl}
+	mock.recorder = &MockConsumerNfMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConsumerNf) EXPECT() *MockConsumerNfMockRecorder {
+	return m.recorder
+}
+
+// Config mocks base method.
+func (m *MockConsumerNf) Config() *factory.Config {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Config")
+	ret0, _ := ret[0].(*factory.Config)
+	return ret0
+}
+
+// Config indicates an expected call of Config.
+func (mr *MockConsumerNfMockRecorder) Config() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Config", reflect.TypeOf((*MockConsumerNf)(nil).Config))
+}
+
+// Context mocks base method.
+func (m *MockConsumerNf) Context() *context.NFContext {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Context")
+	ret0, _ := ret[0].(*context.NFContext)
+	return ret0
+}
+
+// Context indicates an expected call of Context.
+func (mr *MockConsumerNfMockRecorder) Context() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockConsumerNf)(nil).Context))
+}
+
+// SetLogEnable mocks base method.
+func (m *MockConsumerNf) SetLogEnable(enable bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetLogEnable", enable)
+}
+
+// SetLogEnable indicates an expected call of SetLogEnable.
+func (mr *MockConsumerNfMockRecorder) SetLogEnable(enable any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLogEnable", reflect.TypeOf((*MockConsumerNf)(nil).SetLogEnable), enable)
+}
+
+// SetLogLevel mocks base method.
+func (m *MockConsumerNf) SetLogLevel(level string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetLogLevel", level)
+}
+
+// SetLogLevel indicates an expected call of SetLogLevel.
+func (mr *MockConsumerNfMockRecorder) SetLogLevel(level any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLogLevel", reflect.TypeOf((*MockConsumerNf)(nil).SetLogLevel), level)
+}
+
+// SetReportCaller mocks base method.
+func (m *MockConsumerNf) SetReportCaller(reportCaller bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReportCaller", reportCaller)
+}
+
+// SetReportCaller indicates an expected call of SetReportCaller.
+func (mr *MockConsumerNfMockRecorder) SetReportCaller(reportCaller any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReportCaller", reflect.TypeOf((*MockConsumerNf)(nil).SetReportCaller), reportCaller)
+}
+
+// Start mocks base method.
+func (m *MockConsumerNf) Start() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Start")
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockConsumerNfMockRecorder) Start() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockConsumerNf)(nil).Start))
+}
+
+// Terminate mocks base method.
+func (m *MockConsumerNf) Terminate() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Terminate")
+}
+
+// Terminate indicates an expected call of Terminate.
+func (mr *MockConsumerNfMockRecorder) Terminate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Terminate", reflect.TypeOf((*MockConsumerNf)(nil).Terminate))
+}
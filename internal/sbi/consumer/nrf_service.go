@@ -0,0 +1,251 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/logger"
+
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/openapi/nrf/NFManagement"
+)
+
+const registerRetryInterval = 2 * time.Second
+
+type nnrfService struct {
+	consumer *Consumer
+
+	nfMngmntMu      sync.RWMutex
+	nfMngmntClients map[string]*NFManagement.APIClient
+
+	registeredMu   sync.RWMutex
+	registered     bool
+	heartbeatTimer int32
+}
+
+func (s *nnrfService) getNFManagementClient(uri string) *NFManagement.APIClient {
+	if uri == "" {
+		return nil
+	}
+	s.nfMngmntMu.RLock()
+	client, ok := s.nfMngmntClients[uri]
+	if ok {
+		s.nfMngmntMu.RUnlock()
+		return client
+	}
+
+	configuration := NFManagement.NewConfiguration()
+	configuration.SetBasePath(uri)
+	client = NFManagement.NewAPIClient(configuration)
+
+	s.nfMngmntMu.RUnlock()
+	s.nfMngmntMu.Lock()
+	defer s.nfMngmntMu.Unlock()
+	s.nfMngmntClients[uri] = client
+	return client
+}
+
+// IsRegistered reports whether the NF currently holds a registration at the NRF.
+func (s *nnrfService) IsRegistered() bool {
+	s.registeredMu.RLock()
+	defer s.registeredMu.RUnlock()
+	return s.registered
+}
+
+func (s *nnrfService) setRegistered(registered bool, heartbeatTimer int32) {
+	s.registeredMu.Lock()
+	defer s.registeredMu.Unlock()
+	s.registered = registered
+	if heartbeatTimer > 0 {
+		s.heartbeatTimer = heartbeatTimer
+	}
+}
+
+func (s *nnrfService) getHeartbeatTimer() int32 {
+	s.registeredMu.RLock()
+	defer s.registeredMu.RUnlock()
+	return s.heartbeatTimer
+}
+
+// BuildNfProfile describes this NF and its enabled route groups for the NRF.
+func BuildNfProfile(nfCtx *nf_context.NFContext) models.NrfNfManagementNfProfile {
+	bindingIPs := nfCtx.BindingIPs
+	if len(bindingIPs) == 0 {
+		bindingIPs = []string{nfCtx.BindingIPv4}
+	}
+	apiPrefix := fmt.Sprintf("%s://%s", nfCtx.UriScheme, net.JoinHostPort(bindingIPs[0], strconv.Itoa(nfCtx.SBIPort)))
+
+	// every SBI address is an endpoint of every service, an address that is not
+	// an IPv6 one is announced as IPv4 like bindingIPv4 always was
+	var ipv4Addresses, ipv6Addresses []string
+	ipEndPoints := make([]models.IpEndPoint, 0, len(bindingIPs))
+	for _, ip := range bindingIPs {
+		endPoint := models.IpEndPoint{
+			Transport: models.NrfNfManagementTransportProtocol_TCP,
+			Port:      int32(nfCtx.SBIPort),
+		}
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+			endPoint.Ipv6Address = ip
+			ipv6Addresses = append(ipv6Addresses, ip)
+		} else {
+			endPoint.Ipv4Address = ip
+			ipv4Addresses = append(ipv4Addresses, ip)
+		}
+		ipEndPoints = append(ipEndPoints, endPoint)
+	}
+
+	nfServices := make([]models.NrfNfManagementNfService, 0, len(nfCtx.ServiceNameList))
+	for index, serviceName := range nfCtx.ServiceNameList {
+		nfServices = append(nfServices, models.NrfNfManagementNfService{
+			ServiceInstanceId: strconv.Itoa(index),
+			ServiceName:       models.ServiceName(serviceName),
+			Versions: []models.NfServiceVersion{
+				{
+					ApiVersionInUri: "v1",
+					ApiFullVersion:  "1.0.0",
+				},
+			},
+			Scheme:          nfCtx.UriScheme,
+			NfServiceStatus: models.NfServiceStatus_REGISTERED,
+			ApiPrefix:       apiPrefix,
+			IpEndPoints:     ipEndPoints,
+		})
+	}
+
+	return models.NrfNfManagementNfProfile{
+		NfInstanceId:   nfCtx.NfId,
+		NfInstanceName: nfCtx.Name,
+		// ANYA is not a 3GPP NF type, so it announces itself as an Application Function.
+		NfType:         models.NrfNfManagementNfType_AF,
+		NfStatus:       models.NrfNfManagementNfStatus_REGISTERED,
+		HeartBeatTimer: nfCtx.HeartbeatTimer,
+		Ipv4Addresses:  ipv4Addresses,
+		Ipv6Addresses:  ipv6Addresses,
+		NfServices:     nfServices,
+	}
+}
+
+// RegisterNFInstance keeps sending the NF profile to the NRF until it is accepted or ctx is done.
+func (s *nnrfService) RegisterNFInstance(ctx context.Context) error {
+	nfCtx := s.consumer.Context()
+	client := s.getNFManagementClient(nfCtx.NrfUri)
+	if client == nil {
+		return errors.New("NRF URI is not configured")
+	}
+
+	req := &NFManagement.RegisterNFInstanceRequest{}
+	req.SetNfInstanceID(nfCtx.NfId)
+	req.SetNrfNfManagementNfProfile(BuildNfProfile(nfCtx))
+
+	for {
+		rsp, err := client.NFInstanceIDDocumentApi.RegisterNFInstance(ctx, req)
+		if err == nil {
+			s.setRegistered(true, rsp.NrfNfManagementNfProfile.HeartBeatTimer)
+			logger.ConsumerLog.Infof("Registered NF instance [%s] to NRF [%s]", nfCtx.NfId, nfCtx.NrfUri)
+			return nil
+		}
+		logger.ConsumerLog.Errorf("Register NF instance to NRF failed: %+v, retry in %s", err, registerRetryInterval)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(registerRetryInterval):
+		}
+	}
+}
+
+// SendHeartbeat reports the NF as alive; an unknown instance at the NRF triggers a re-registration.
+func (s *nnrfService) SendHeartbeat(ctx context.Context) error {
+	nfCtx := s.consumer.Context()
+	client := s.getNFManagementClient(nfCtx.NrfUri)
+	if client == nil {
+		return errors.New("NRF URI is not configured")
+	}
+
+	req := &NFManagement.UpdateNFInstanceRequest{}
+	req.SetNfInstanceID(nfCtx.NfId)
+	req.SetPatchItem([]models.PatchItem{
+		{
+			Op:    models.PatchOperation_REPLACE,
+			Path:  "/nfStatus",
+			Value: models.NrfNfManagementNfStatus_REGISTERED,
+		},
+	})
+
+	_, err := client.NFInstanceIDDocumentApi.UpdateNFInstance(ctx, req)
+	if err == nil {
+		return nil
+	}
+
+	var apiErr openapi.GenericOpenAPIError
+	if errors.As(err, &apiErr) && apiErr.ErrorStatus == http.StatusNotFound {
+		logger.ConsumerLog.Warnf("NF instance [%s] unknown to NRF, registering again", nfCtx.NfId)
+		s.setRegistered(false, 0)
+		return s.RegisterNFInstance(ctx)
+	}
+	return err
+}
+
+// heartbeatInterval returns the heartbeat timer granted by the NRF, the configured one until then.
+func (s *nnrfService) heartbeatInterval() time.Duration {
+	interval := s.getHeartbeatTimer()
+	if interval <= 0 {
+		interval = s.consumer.Context().HeartbeatTimer
+	}
+	return time.Duration(interval) * time.Second
+}
+
+// StartHeartbeat sends heartbeats every heartbeat timer until ctx is done.
+func (s *nnrfService) StartHeartbeat(ctx context.Context, wg *sync.WaitGroup) {
+	interval := s.heartbeatInterval()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.SendHeartbeat(ctx); err != nil {
+					logger.ConsumerLog.Errorf("NRF heartbeat failed: %+v", err)
+				}
+				// a re-registration may have been granted another heartbeat timer
+				if next := s.heartbeatInterval(); next != interval {
+					logger.ConsumerLog.Infof("NRF heartbeat timer changed from %s to %s", interval, next)
+					interval = next
+					ticker.Reset(interval)
+				}
+			}
+		}
+	}()
+}
+
+func (s *nnrfService) SendDeregisterNFInstance(ctx context.Context) error {
+	nfCtx := s.consumer.Context()
+	client := s.getNFManagementClient(nfCtx.NrfUri)
+	if client == nil {
+		return errors.New("NRF URI is not configured")
+	}
+
+	req := &NFManagement.DeregisterNFInstanceRequest{}
+	req.SetNfInstanceID(nfCtx.NfId)
+
+	if _, err := client.NFInstanceIDDocumentApi.DeregisterNFInstance(ctx, req); err != nil {
+		return err
+	}
+	s.setRegistered(false, 0)
+	logger.ConsumerLog.Infof("Deregistered NF instance [%s] from NRF", nfCtx.NfId)
+	return nil
+}
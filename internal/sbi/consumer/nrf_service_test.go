@@ -0,0 +1,223 @@
+package consumer_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/sbi/consumer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/free5gc/openapi/models"
+)
+
+const testNfId = "6f1c2d9e-1b7a-4c55-9a52-0f3b8c1d2e4f"
+
+// stubNrf records the NF management requests it receives
+type stubNrf struct {
+	mu         sync.Mutex
+	profile    models.NrfNfManagementNfProfile
+	registers  int
+	heartbeats int
+	deregister int
+	forget     bool
+	// heartBeatTimer replaces the heartbeat timer of the registered profile when set
+	heartBeatTimer int32
+}
+
+func (n *stubNrf) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !strings.HasSuffix(r.URL.Path, "/nnrf-nfm/v1/nf-instances/"+testNfId) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		n.registers++
+		if err := json.NewDecoder(r.Body).Decode(&n.profile); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if n.heartBeatTimer > 0 {
+			n.profile.HeartBeatTimer = n.heartBeatTimer
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(n.profile)
+	case http.MethodPatch:
+		if n.forget {
+			n.forget = false
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(models.ProblemDetails{Status: http.StatusNotFound})
+			return
+		}
+		n.heartbeats++
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		n.deregister++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (n *stubNrf) counts() (int, int, int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.registers, n.heartbeats, n.deregister
+}
+
+func setupConsumer(t *testing.T, nrf *stubNrf) *consumer.Consumer {
+	t.Helper()
+
+	server := httptest.NewServer(h2c.NewHandler(nrf, &http2.Server{}))
+	t.Cleanup(server.Close)
+
+	mockCtrl := gomock.NewController(t)
+	consumerNf := consumer.NewMockConsumerNf(mockCtrl)
+	consumerNf.EXPECT().Context().Return(&nf_context.NFContext{
+		NfId:            testNfId,
+		Name:            "ANYA",
+		UriScheme:       models.UriScheme_HTTP,
+		BindingIPv4:     "127.0.0.163",
+		SBIPort:         8000,
+		NrfUri:          server.URL,
+		HeartbeatTimer:  1,
+		ServiceNameList: []string{"nanya-default", "nanya-dragonball"},
+	}).AnyTimes()
+
+	c, err := consumer.NewConsumer(consumerNf)
+	require.NoError(t, err)
+	return c
+}
+
+func Test_NrfLifecycle(t *testing.T) {
+	nrf := &stubNrf{}
+	c := setupConsumer(t, nrf)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, c.RegisterNFInstance(ctx))
+	assert.True(t, c.IsRegistered())
+
+	nrf.mu.Lock()
+	profile := nrf.profile
+	nrf.mu.Unlock()
+	assert.Equal(t, testNfId, profile.NfInstanceId)
+	assert.Equal(t, models.NrfNfManagementNfStatus_REGISTERED, profile.NfStatus)
+	assert.Equal(t, []string{"127.0.0.163"}, profile.Ipv4Addresses)
+	require.Len(t, profile.NfServices, 2)
+	assert.Equal(t, models.ServiceName("nanya-dragonball"), profile.NfServices[1].ServiceName)
+	assert.Equal(t, "http://127.0.0.163:8000", profile.NfServices[1].ApiPrefix)
+
+	var wg sync.WaitGroup
+	c.StartHeartbeat(ctx, &wg)
+	assert.Eventually(t, func() bool {
+		_, heartbeats, _ := nrf.counts()
+		return heartbeats >= 1
+	}, 3*time.Second, 50*time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	require.NoError(t, c.SendDeregisterNFInstance(context.Background()))
+	assert.False(t, c.IsRegistered())
+
+	registers, _, deregister := nrf.counts()
+	assert.Equal(t, 1, registers)
+	assert.Equal(t, 1, deregister)
+}
+
+func Test_HeartbeatReRegisters(t *testing.T) {
+	nrf := &stubNrf{}
+	c := setupConsumer(t, nrf)
+
+	require.NoError(t, c.RegisterNFInstance(context.Background()))
+
+	nrf.mu.Lock()
+	nrf.forget = true
+	nrf.mu.Unlock()
+
+	require.NoError(t, c.SendHeartbeat(context.Background()))
+	assert.True(t, c.IsRegistered())
+
+	registers, heartbeats, _ := nrf.counts()
+	assert.Equal(t, 2, registers)
+	assert.Equal(t, 0, heartbeats)
+}
+
+func Test_HeartbeatFollowsReRegistrationTimer(t *testing.T) {
+	nrf := &stubNrf{}
+	c := setupConsumer(t, nrf)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, c.RegisterNFInstance(ctx))
+
+	// the first heartbeat re-registers and the NRF grants a much longer timer
+	nrf.mu.Lock()
+	nrf.forget = true
+	nrf.heartBeatTimer = 60
+	nrf.mu.Unlock()
+
+	var wg sync.WaitGroup
+	c.StartHeartbeat(ctx, &wg)
+	assert.Eventually(t, func() bool {
+		registers, _, _ := nrf.counts()
+		return registers == 2
+	}, 3*time.Second, 50*time.Millisecond)
+
+	// on the old 1s ticker the next heartbeats would be due already
+	time.Sleep(2500 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	_, heartbeats, _ := nrf.counts()
+	assert.Equal(t, 0, heartbeats)
+}
+
+func Test_BuildNfProfileDualStack(t *testing.T) {
+	profile := consumer.BuildNfProfile(&nf_context.NFContext{
+		NfId:            testNfId,
+		UriScheme:       models.UriScheme_HTTP,
+		BindingIPv4:     "127.0.0.163",
+		BindingIPs:      []string{"127.0.0.163", "fd00::163", "10.0.0.163"},
+		SBIPort:         8000,
+		ServiceNameList: []string{"nanya-default"},
+	})
+
+	assert.Equal(t, []string{"127.0.0.163", "10.0.0.163"}, profile.Ipv4Addresses)
+	assert.Equal(t, []string{"fd00::163"}, profile.Ipv6Addresses)
+	require.Len(t, profile.NfServices, 1)
+	assert.Equal(t, "http://127.0.0.163:8000", profile.NfServices[0].ApiPrefix)
+	assert.Equal(t, []models.IpEndPoint{
+		{Ipv4Address: "127.0.0.163", Transport: models.NrfNfManagementTransportProtocol_TCP, Port: 8000},
+		{Ipv6Address: "fd00::163", Transport: models.NrfNfManagementTransportProtocol_TCP, Port: 8000},
+		{Ipv4Address: "10.0.0.163", Transport: models.NrfNfManagementTransportProtocol_TCP, Port: 8000},
+	}, profile.NfServices[0].IpEndPoints)
+
+	t.Run("IPv6 Only", func(t *testing.T) {
+		profile := consumer.BuildNfProfile(&nf_context.NFContext{
+			UriScheme:       models.UriScheme_HTTPS,
+			BindingIPs:      []string{"fd00::163"},
+			SBIPort:         8000,
+			ServiceNameList: []string{"nanya-default"},
+		})
+		assert.Empty(t, profile.Ipv4Addresses)
+		assert.Equal(t, "https://[fd00::163]:8000", profile.NfServices[0].ApiPrefix)
+	})
+}
@@ -0,0 +1,25 @@
+package sbi_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/free5gc/openapi/models"
+)
+
+// decodeProblem checks the response is an application/problem+json body and decodes it.
+func decodeProblem(t *testing.T, recorder *httptest.ResponseRecorder) models.ProblemDetails {
+	t.Helper()
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/problem+json" {
+		t.Errorf("Expected Content-Type application/problem+json, got %s", contentType)
+	}
+	var problem models.ProblemDetails
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to decode problem details %q: %s", recorder.Body.String(), err)
+	}
+	if int(problem.Status) != recorder.Code {
+		t.Errorf("Expected problem status %d, got %d", recorder.Code, problem.Status)
+	}
+	return problem
+}
@@ -0,0 +1,354 @@
+package sbi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/storage"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const (
+	loadWorkers    = 8
+	loadIterations = 10
+)
+
+type loadRequest struct {
+	method string
+	path   string
+	body   string
+	// stream is only read up to its first event, it does not end by itself
+	stream bool
+}
+
+func newLoadRequest(method string, path string, body string) loadRequest {
+	return loadRequest{method: method, path: path, body: body}
+}
+
+// Test_RouterConcurrentLoad hits every route from parallel workers against one context.
+// Run it with -race, each domain store must keep its data consistent under the load.
+func Test_RouterConcurrentLoad(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	level := logger.Log.GetLevel()
+	logger.Log.SetLevel(logrus.WarnLevel)
+	t.Cleanup(func() { logger.Log.SetLevel(level) })
+
+	nfCtx := &nf_context.NFContext{}
+	nfCtx.UseStorage(storage.NewMemoryStorage())
+	nfCtx.MessageFeed = nf_context.NewMessageFeed(nf_context.MessageFeedCapacity)
+	require.NoError(t, nfCtx.Seed())
+
+	mockCtrl := gomock.NewController(t)
+	nfApp := NewMocknfApp(mockCtrl)
+	nfApp.EXPECT().Config().Return(&factory.Config{
+		Configuration: &factory.Configuration{
+			Sbi: &factory.Sbi{Port: 8000},
+		},
+	}).AnyTimes()
+	nfApp.EXPECT().Context().Return(nfCtx).AnyTimes()
+	proc, err := processor.NewProcessor(nfApp)
+	require.NoError(t, err)
+	nfApp.EXPECT().Processor().Return(proc).AnyTimes()
+	nfApp.EXPECT().ReloadConfig().Return(&factory.ReloadResult{}, nil).AnyTimes()
+
+	server := httptest.NewServer(NewServer(nfApp, "").router)
+	defer server.Close()
+
+	// a message and a task for the routes addressing an existing entry
+	resp, err := http.Post(server.URL+"/msg/", "application/json", strings.NewReader(`{"content":"Hi","author":"Anya"}`))
+	require.NoError(t, err)
+	var posted processor.PostMessageResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&posted))
+	resp.Body.Close()
+	messageID := posted.Data.ID
+	resp, err = http.Post(server.URL+"/task/tasks", "application/json", strings.NewReader(`{"name":"Spy"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	for i := 0; i < loadIterations; i++ {
+		require.NoError(t, nfCtx.DragonBall.Put(fmt.Sprintf("Cell%d", i), 6))
+	}
+	for i := 0; i < loadIterations; i++ {
+		require.NoError(t, nfCtx.SpyFamily.Put(fmt.Sprintf("Spy%d", i), nf_context.SpyFamilyCharacter{LastName: "Smith"}))
+	}
+	require.NoError(t, nfCtx.Sessions.Put("Load", nf_context.AttendanceSession{Name: "Load"}))
+	for i := 0; i < loadIterations; i++ {
+		require.NoError(t, nfCtx.Crew.Put(fmt.Sprintf("usopp%d", i), nf_context.CrewMember{Name: fmt.Sprintf("Usopp%d", i)}))
+	}
+
+	requests := map[string]func(worker, i int) loadRequest{
+		"Create Attendance Session": func(_, i int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/attendance/sessions", fmt.Sprintf(`{"name":"Class%d"}`, i))
+		},
+		"List Attendance Sessions": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/attendance/sessions", "") },
+		"Get Attendance Session": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodGet, "/attendance/sessions/Load", "")
+		},
+		"Check In Attendance": func(w, _ int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/attendance/sessions/Load/check-in", fmt.Sprintf(`{"name":"Student%d"}`, w))
+		},
+		"Check Out Attendance": func(w, _ int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/attendance/sessions/Load/check-out", fmt.Sprintf(`{"name":"Student%d"}`, w))
+		},
+		"Export Attendance Session": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodGet, "/attendance/sessions/Load/export", "")
+		},
+		"Get Person Attendance": func(w, _ int) loadRequest {
+			return newLoadRequest(http.MethodGet, fmt.Sprintf("/attendance/people/Student%d", w), "")
+		},
+		"Hello free5GC!": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/default/", "") },
+		"get messages":   func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/message/", "") },
+		"add message": func(w, i int) loadRequest {
+			return newLoadRequest(http.MethodPut, fmt.Sprintf("/message/w%di%d", w, i), "")
+		},
+		"empty input":          func(int, int) loadRequest { return newLoadRequest(http.MethodPut, "/message/", "") },
+		"Hello SPYxFAMILY!":    func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/spyfamily/", "") },
+		"SPYxFAMILY Character": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/spyfamily/character/Anya", "") },
+		"List SPYxFAMILY Characters": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodGet, "/spyfamily/characters?family=Forger", "")
+		},
+		"Add SPYxFAMILY Character": func(_, i int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/spyfamily/character", fmt.Sprintf(`{"firstName":"Agent%d","lastName":"Smith"}`, i))
+		},
+		"Update SPYxFAMILY Character": func(w, _ int) loadRequest {
+			return newLoadRequest(http.MethodPut, "/spyfamily/character/Franky", fmt.Sprintf(`{"lastName":"Franklin","codename":"Informant%d"}`, w))
+		},
+		"Delete SPYxFAMILY Character": func(_, i int) loadRequest {
+			return newLoadRequest(http.MethodDelete, fmt.Sprintf("/spyfamily/character/Spy%d", i), "")
+		},
+		"Hello Straw Hats": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/onepiece/", "") },
+		"Recruit Straw Hat": func(w, _ int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/onepiece/crew", fmt.Sprintf(`{"name":"Luffy%d","bounty":%d}`, w, w))
+		},
+		"List Straw Hat Crew": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/onepiece/crew", "") },
+		"Get Straw Hat": func(w, _ int) loadRequest {
+			return newLoadRequest(http.MethodGet, fmt.Sprintf("/onepiece/crew/Luffy%d", w), "")
+		},
+		"Remove Straw Hat": func(_, i int) loadRequest {
+			return newLoadRequest(http.MethodDelete, fmt.Sprintf("/onepiece/crew/Usopp%d", i), "")
+		},
+		"Get Attendance": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/attendance/", "") },
+		"Post Attendance": func(_, i int) loadRequest {
+			// every worker records the same names, only one of them may succeed per name
+			return newLoadRequest(http.MethodPost, "/attendance/", fmt.Sprintf("Student%d", i))
+		},
+		"Get All Tasks": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/task/tasks?sort=-priority", "") },
+		"Create New Task": func(w, i int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/task/tasks", fmt.Sprintf(`{"name":"Task w%di%d"}`, w, i))
+		},
+		"Get Task": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/task/tasks/1", "") },
+		"Update Task": func(w, _ int) loadRequest {
+			return newLoadRequest(http.MethodPut, "/task/tasks/1", fmt.Sprintf(`{"name":"Spy","priority":%d}`, w%5))
+		},
+		"Patch Task": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodPatch, "/task/tasks/1", `{"status":"doing"}`)
+		},
+		"Delete Task": func(w, i int) loadRequest {
+			return newLoadRequest(http.MethodDelete, fmt.Sprintf("/task/tasks/%d", 2+w*loadIterations+i), "")
+		},
+		"Post Message": func(w, _ int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/msg/", fmt.Sprintf(`{"content":"Reply","author":"Loid","parentId":%q}`, messageID))
+		},
+		"Get All Messages": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/msg/?limit=5", "") },
+		"Stream Messages": func(int, int) loadRequest {
+			return loadRequest{method: http.MethodGet, path: "/msg/stream?author=Loid", stream: true}
+		},
+		"Get Message by ID":  func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/msg/"+messageID, "") },
+		"Get Message Thread": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/msg/"+messageID+"/thread", "") },
+		"Edit Message": func(w, i int) loadRequest {
+			return newLoadRequest(http.MethodPatch, "/msg/"+messageID, fmt.Sprintf(`{"content":"Hi w%di%d"}`, w, i))
+		},
+		"Delete Message": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodDelete, "/msg/"+messageID+"-unknown", "")
+		},
+		"Hello Dragon Ball!":           func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/dragonball/", "") },
+		"Dragon Ball Search Character": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/dragonball/character/Goku", "") },
+		"Dragon Ball Fight": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/dragonball/battle", `{"name1":"Goku","name2":"Vegeta"}`)
+		},
+		"Add Dragon Ball Character": func(_, i int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/dragonball/character", fmt.Sprintf(`{"name":"Saibaman%d","powerLevel":1}`, i))
+		},
+		"Update Dragon Ball Character's Powerlevel": func(w, _ int) loadRequest {
+			return newLoadRequest(http.MethodPut, "/dragonball/character/Vegeta", fmt.Sprintf(`{"powerLevel":%d}`, w))
+		},
+		"Delete Dragon Ball Character": func(_, i int) loadRequest {
+			return newLoadRequest(http.MethodDelete, fmt.Sprintf("/dragonball/character/Cell%d", i), "")
+		},
+		"List Dragon Ball Characters": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodGet, "/dragonball/characters?sort=name&limit=3", "")
+		},
+		"Dragon Ball Power Ranking": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodGet, "/dragonball/ranking", "")
+		},
+		"Run Dragon Ball Tournament": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/dragonball/tournament", `{"characters":"all"}`)
+		},
+		"Get Dragon Ball Tournament": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodGet, "/dragonball/tournament/1", "")
+		},
+		"Get Today's Fortune": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/fortune/", "") },
+		"Add a new Fortune": func(w, i int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/fortune/", fmt.Sprintf(`{"fortune":"吉 w%di%d"}`, w, i))
+		},
+		"Get Daily Fortune": func(w, _ int) loadRequest {
+			return newLoadRequest(http.MethodGet, fmt.Sprintf("/fortune/daily?user=Anya%d", w), "")
+		},
+		"List Fortunes": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/fortune/list?rank=吉", "") },
+		"Get Fortune":   func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/fortune/1", "") },
+		"Update Fortune": func(w, _ int) loadRequest {
+			return newLoadRequest(http.MethodPut, "/fortune/4", fmt.Sprintf(`{"fortune":"Good fortune is with you.","weight":%d}`, w+1))
+		},
+		"Delete Fortune": func(_, i int) loadRequest {
+			// fortunes added during the run, not all of them exist yet
+			return newLoadRequest(http.MethodDelete, fmt.Sprintf("/fortune/%d", 8+i), "")
+		},
+		"Welcome to timezone service": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/timezone/", "") },
+		"Query city time zone":        func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/timezone/city/Tokyo", "") },
+		"Query city local time":       func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/timezone/city/Paris/time", "") },
+		"Convert time between cities": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodGet, "/timezone/convert?from=Taipei&to=Paris", "")
+		},
+		"List cities by UTC offset": func(int, int) loadRequest { return newLoadRequest(http.MethodGet, "/timezone/offset/+09:00", "") },
+		"Add new city time zone": func(_, i int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/timezone/city", fmt.Sprintf(`{"City":"Osaka%d","TimeZone":"Asia/Tokyo"}`, i))
+		},
+		"Reset city time zone": func(int, int) loadRequest {
+			return newLoadRequest(http.MethodPost, "/timezone/city/Seoul", `{"TimeZone":"Asia/Seoul"}`)
+		},
+		"Delete city time zone": func(_, i int) loadRequest {
+			return newLoadRequest(http.MethodDelete, fmt.Sprintf("/timezone/city/Osaka%d", i), "")
+		},
+		"Reload Config": func(int, int) loadRequest { return newLoadRequest(http.MethodPut, "/nf-management/config", "") },
+	}
+
+	s := &Server{}
+	groups := append(s.getRouteGroups(), routeGroup{factory.ServiceNameManagement, "/nf-management", s.getManagementRoute()})
+	for _, group := range groups {
+		for _, route := range group.Routes {
+			require.Contains(t, requests, route.Name, "route %s %s has no load request", route.Method, route.Pattern)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		statuses = make(map[string]map[int]int)
+		wg       sync.WaitGroup
+	)
+	for name, build := range requests {
+		statuses[name] = make(map[int]int)
+		for w := 0; w < loadWorkers; w++ {
+			wg.Add(1)
+			go func(name string, build func(worker, i int) loadRequest, w int) {
+				defer wg.Done()
+				for i := 0; i < loadIterations; i++ {
+					status := send(t, server.URL, build(w, i))
+					mu.Lock()
+					statuses[name][status]++
+					mu.Unlock()
+				}
+			}(name, build, w)
+		}
+	}
+	wg.Wait()
+
+	for name, counts := range statuses {
+		for status := range counts {
+			assert.Less(t, status, http.StatusInternalServerError, "route %s answered %d", name, status)
+		}
+	}
+
+	total := loadWorkers * loadIterations
+	assert.Equal(t, loadIterations, statuses["Post Attendance"][http.StatusOK], "each name is recorded once")
+	assert.Equal(t, loadIterations, statuses["Add Dragon Ball Character"][http.StatusCreated], "each character is added once")
+	assert.Equal(t, loadIterations, statuses["Delete Dragon Ball Character"][http.StatusOK], "each character is deleted once")
+	assert.Equal(t, loadIterations, statuses["Add SPYxFAMILY Character"][http.StatusCreated], "each character is added once")
+	assert.Equal(t, loadIterations, statuses["Delete SPYxFAMILY Character"][http.StatusNoContent], "each character is deleted once")
+	assert.Equal(t, loadWorkers, statuses["Recruit Straw Hat"][http.StatusCreated], "each crew member is recruited once")
+	assert.Equal(t, loadIterations, statuses["Remove Straw Hat"][http.StatusOK], "each crew member is removed once")
+	assert.Equal(t, total, statuses["Create New Task"][http.StatusCreated])
+
+	attendance, err := nfCtx.Attendance.Len()
+	require.NoError(t, err)
+	assert.Equal(t, loadIterations, attendance)
+	assert.Equal(t, loadIterations, statuses["Create Attendance Session"][http.StatusCreated], "each session is created once")
+
+	// every check-in opened a record and every check-out closed one, nobody is checked in twice
+	session, ok, err := nfCtx.Sessions.Get("Load")
+	require.NoError(t, err)
+	require.True(t, ok)
+	closed, present := 0, make(map[string]bool)
+	for _, record := range session.Records {
+		if record.CheckOut != nil {
+			closed++
+			continue
+		}
+		assert.False(t, present[record.Name], "%s is checked in twice", record.Name)
+		present[record.Name] = true
+	}
+	assert.Len(t, session.Records, statuses["Check In Attendance"][http.StatusCreated])
+	assert.Equal(t, statuses["Check Out Attendance"][http.StatusOK], closed)
+	assert.Equal(t, total, statuses["Add a new Fortune"][http.StatusCreated], "every added fortune is different")
+	records, err := nfCtx.MessageRecords.Len()
+	require.NoError(t, err)
+	assert.Equal(t, total, records)
+
+	// no edit is lost, every edit but the last one is kept in the history
+	message, ok, err := nfCtx.Messages.Get(messageID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Len(t, message.History, total)
+	messages, err := nfCtx.Messages.Len()
+	require.NoError(t, err)
+	assert.Equal(t, 1+total, messages)
+
+	tasks, err := nfCtx.Tasks.All()
+	require.NoError(t, err)
+	ids := make(map[int]bool)
+	for _, task := range tasks {
+		assert.False(t, ids[task.ID], "task ID %d is used twice", task.ID)
+		ids[task.ID] = true
+	}
+}
+
+// send issues the request and returns the status, a stream is closed after its first event.
+func send(t *testing.T, baseURL string, r loadRequest) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, r.method, baseURL+r.path, strings.NewReader(r.body))
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	defer resp.Body.Close()
+	if r.stream {
+		if _, err = bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+			t.Error(err)
+		}
+		return resp.StatusCode
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode
+}
@@ -0,0 +1,149 @@
+package sbi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/util"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+)
+
+const clientIdentityKey = "clientIdentity"
+
+// ClientIdentity is the verified client certificate of a mutual TLS request.
+type ClientIdentity struct {
+	Subject        string   `json:"subject"`
+	CommonName     string   `json:"commonName,omitempty"`
+	DNSNames       []string `json:"dnsNames,omitempty"`
+	URIs           []string `json:"uris,omitempty"`
+	EmailAddresses []string `json:"emailAddresses,omitempty"`
+	IPAddresses    []string `json:"ipAddresses,omitempty"`
+}
+
+func newClientIdentity(cert *x509.Certificate) *ClientIdentity {
+	identity := &ClientIdentity{
+		Subject:        cert.Subject.String(),
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+	}
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+	for _, ip := range cert.IPAddresses {
+		identity.IPAddresses = append(identity.IPAddresses, ip.String())
+	}
+	return identity
+}
+
+// names returns the common name and every subject alternative name of the certificate.
+func (i *ClientIdentity) names() []string {
+	var names []string
+	if i.CommonName != "" {
+		names = append(names, i.CommonName)
+	}
+	names = append(names, i.DNSNames...)
+	names = append(names, i.URIs...)
+	names = append(names, i.EmailAddresses...)
+	return append(names, i.IPAddresses...)
+}
+
+// GetClientIdentity returns the verified client certificate of the request, it is only
+// set when mutual TLS is enabled and the client presented a certificate.
+func GetClientIdentity(c *gin.Context) (*ClientIdentity, bool) {
+	value, ok := c.Get(clientIdentityKey)
+	if !ok {
+		return nil, false
+	}
+	identity, ok := value.(*ClientIdentity)
+	return identity, ok
+}
+
+type clientVerifier struct {
+	clientCAs  *x509.CertPool
+	clientAuth tls.ClientAuthType
+	// allowed maps an identity to the service names it may use, nil when every client is allowed
+	allowed map[string][]string
+}
+
+func newClientVerifier(tlsCfg *factory.Tls) (*clientVerifier, error) {
+	content, err := os.ReadFile(tlsCfg.ClientCa)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA bundle: %+v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("client CA bundle [%s] has no PEM certificate", tlsCfg.ClientCa)
+	}
+
+	v := &clientVerifier{clientCAs: pool}
+	switch tlsCfg.GetClientAuth() {
+	case factory.TlsClientAuthOptional:
+		v.clientAuth = tls.VerifyClientCertIfGiven
+	case factory.TlsClientAuthRequire:
+		v.clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		v.clientAuth = tls.NoClientCert
+	}
+	if len(tlsCfg.ClientAllowList) > 0 {
+		v.allowed = make(map[string][]string)
+		for _, client := range tlsCfg.ClientAllowList {
+			v.allowed[client.Identity] = append(v.allowed[client.Identity], client.Services...)
+		}
+	}
+	return v, nil
+}
+
+// apply sets the client certificate policy on the TLS config of the SBI server.
+func (v *clientVerifier) apply(server *http.Server) {
+	if server.TLSConfig == nil {
+		server.TLSConfig = &tls.Config{}
+	}
+	server.TLSConfig.ClientCAs = v.clientCAs
+	server.TLSConfig.ClientAuth = v.clientAuth
+}
+
+func (v *clientVerifier) allows(identity *ClientIdentity, serviceName string) bool {
+	for _, name := range append(identity.names(), factory.TlsClientAllowAny) {
+		for _, allowed := range v.allowed[name] {
+			if allowed == serviceName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// authenticateClient exposes the verified client certificate to the handlers and, with
+// tls.clientAllowList, rejects the clients not allowed to use the route group.
+func (s *Server) authenticateClient(serviceName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var identity *ClientIdentity
+		if state := c.Request.TLS; state != nil && len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+			identity = newClientIdentity(state.VerifiedChains[0][0])
+			c.Set(clientIdentityKey, identity)
+		}
+
+		if s.clientVerifier.allowed != nil {
+			if identity == nil {
+				logger.SBILog.Warnf("Request to [%s] without client certificate", c.Request.URL.Path)
+				util.SendProblem(c, http.StatusForbidden, util.CauseClientNotAllowed,
+					fmt.Sprintf("service %s requires a client certificate", serviceName))
+				return
+			}
+			if !s.clientVerifier.allows(identity, serviceName) {
+				logger.SBILog.Warnf("Client certificate [%s] is not allowed to use [%s]", identity.Subject, serviceName)
+				util.SendProblem(c, http.StatusForbidden, util.CauseClientNotAllowed,
+					fmt.Sprintf("client %s is not allowed to use service %s", identity.Subject, serviceName))
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,209 @@
+package sbi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi/models"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs a leaf certificate, a server one when ips are given.
+func (ca *testCA) issue(t *testing.T, commonName string, uris []string, ips ...net.IP) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IPAddresses:  ips,
+	}
+	if len(ips) > 0 {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+	for _, uri := range uris {
+		parsed, err := url.Parse(uri)
+		require.NoError(t, err)
+		template.URIs = append(template.URIs, parsed)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func setupMutualTLSServer(t *testing.T, ca *testCA, clientAuth string) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caPath, ca.pem, 0o600))
+
+	mockCtrl := gomock.NewController(t)
+	nfApp := NewMocknfApp(mockCtrl)
+	nfApp.EXPECT().Config().Return(&factory.Config{
+		Configuration: &factory.Configuration{
+			Sbi: &factory.Sbi{
+				Scheme: "https",
+				Port:   8000,
+				Tls: &factory.Tls{
+					Pem:        "cert/nf.pem",
+					Key:        "cert/nf.key",
+					ClientCa:   caPath,
+					ClientAuth: clientAuth,
+					ClientAllowList: []factory.TlsClient{
+						{Identity: "amf", Services: []string{factory.ServiceNameDragonBall}},
+						{Identity: "spiffe://anya/smf", Services: []string{factory.ServiceNameOnePiece}},
+						{Identity: factory.TlsClientAllowAny, Services: []string{factory.ServiceNameManagement}},
+					},
+				},
+			},
+		},
+	}).AnyTimes()
+	nfApp.EXPECT().Context().Return(&nf_context.NFContext{NfId: testNfId}).AnyTimes()
+	nfApp.EXPECT().ReloadConfig().Return(&factory.ReloadResult{}, nil).AnyTimes()
+
+	server := NewServer(nfApp, "")
+	server.router.GET("/whoami", server.authenticateClient(factory.ServiceNameDragonBall), func(c *gin.Context) {
+		identity, _ := GetClientIdentity(c)
+		c.JSON(http.StatusOK, identity)
+	})
+
+	ts := httptest.NewUnstartedServer(server.router)
+	ts.TLS = server.httpServer.TLSConfig.Clone()
+	ts.TLS.Certificates = []tls.Certificate{ca.issue(t, "anya", nil, net.ParseIP("127.0.0.1"))}
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newMutualTLSClient(ca *testCA, certs ...tls.Certificate) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: certs,
+	}}}
+}
+
+func Test_MutualTLSAllowList(t *testing.T) {
+	ca := newTestCA(t)
+	ts := setupMutualTLSServer(t, ca, factory.TlsClientAuthOptional)
+
+	amf := newMutualTLSClient(ca, ca.issue(t, "amf", nil))
+	smf := newMutualTLSClient(ca, ca.issue(t, "smf", []string{"spiffe://anya/smf"}))
+	anonymous := newMutualTLSClient(ca)
+	stranger := newMutualTLSClient(ca, newTestCA(t).issue(t, "amf", nil))
+
+	tests := []struct {
+		name           string
+		client         *http.Client
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"Common Name Allowed", amf, http.MethodGet, "/dragonball/", http.StatusOK},
+		{"Common Name Not Allowed", amf, http.MethodGet, "/onepiece/", http.StatusForbidden},
+		{"URI SAN Allowed", smf, http.MethodGet, "/onepiece/", http.StatusOK},
+		{"URI SAN Not Allowed", smf, http.MethodGet, "/dragonball/", http.StatusForbidden},
+		{"Any Client Certificate", smf, http.MethodPut, "/nf-management/config", http.StatusOK},
+		{"No Client Certificate", anonymous, http.MethodGet, "/dragonball/", http.StatusForbidden},
+		{"No Client Certificate On Management", anonymous, http.MethodPut, "/nf-management/config", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, ts.URL+tt.path, nil)
+			require.NoError(t, err)
+			resp, err := tt.client.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus == http.StatusForbidden {
+				var problem models.ProblemDetails
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+				assert.Equal(t, "CLIENT_NOT_ALLOWED", problem.Cause)
+			}
+		})
+	}
+
+	t.Run("Unknown CA", func(t *testing.T) {
+		_, err := stranger.Get(ts.URL + "/dragonball/")
+		assert.Error(t, err)
+	})
+}
+
+func Test_MutualTLSClientIdentity(t *testing.T) {
+	ca := newTestCA(t)
+	ts := setupMutualTLSServer(t, ca, factory.TlsClientAuthRequire)
+
+	client := newMutualTLSClient(ca, ca.issue(t, "amf", []string{"spiffe://anya/amf"}))
+	resp, err := client.Get(ts.URL + "/whoami")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var identity ClientIdentity
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&identity))
+	assert.Equal(t, ClientIdentity{
+		Subject:    "CN=amf",
+		CommonName: "amf",
+		URIs:       []string{"spiffe://anya/amf"},
+	}, identity)
+
+	t.Run("Required Certificate", func(t *testing.T) {
+		_, err := newMutualTLSClient(ca).Get(ts.URL + "/dragonball/")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,111 @@
+package sbi
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/util"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AccessTokenClaims are the 3GPP TS 29.510 access token claims checked by the SBI.
+type AccessTokenClaims struct {
+	jwt.RegisteredClaims
+	Scope string `json:"scope"`
+}
+
+type tokenVerifier struct {
+	issuer    string
+	publicKey crypto.PublicKey
+}
+
+func newTokenVerifier(oauth2 *factory.OAuth2) (*tokenVerifier, error) {
+	content, err := os.ReadFile(oauth2.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("read OAuth2 public key: %+v", err)
+	}
+
+	var publicKey crypto.PublicKey
+	if rsaKey, rsaErr := jwt.ParseRSAPublicKeyFromPEM(content); rsaErr == nil {
+		publicKey = rsaKey
+	} else if ecKey, ecErr := jwt.ParseECPublicKeyFromPEM(content); ecErr == nil {
+		publicKey = ecKey
+	} else {
+		return nil, fmt.Errorf("parse OAuth2 public key [%s]: %+v", oauth2.PublicKey, rsaErr)
+	}
+
+	return &tokenVerifier{
+		issuer:    oauth2.Issuer,
+		publicKey: publicKey,
+	}, nil
+}
+
+func (v *tokenVerifier) verify(tokenString string, audience string) (*AccessTokenClaims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+
+	claims := &AccessTokenClaims{}
+	if _, err := parser.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+		return v.publicKey, nil
+	}); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (c *AccessTokenClaims) hasScope(scope string) bool {
+	for _, granted := range strings.Fields(c.Scope) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize rejects requests whose bearer token does not grant every scope of the route.
+func (s *Server) authorize(scopes []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		tokenString, found := strings.CutPrefix(authHeader, "Bearer ")
+		if !found || tokenString == "" {
+			abortUnauthorized(c, errors.New("missing bearer token"))
+			return
+		}
+
+		claims, err := s.tokenVerifier.verify(tokenString, s.Context().NfId)
+		if err != nil {
+			abortUnauthorized(c, err)
+			return
+		}
+
+		for _, scope := range scopes {
+			if !claims.hasScope(scope) {
+				logger.SBILog.Warnf("Access token of [%s] lacks scope [%s]", claims.Subject, scope)
+				c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+				util.SendProblem(c, http.StatusForbidden, util.CauseInsufficientScope,
+					fmt.Sprintf("access token does not grant scope %s", scope))
+				return
+			}
+		}
+
+		c.Set("accessTokenClaims", claims)
+		c.Next()
+	}
+}
+
+func abortUnauthorized(c *gin.Context, err error) {
+	logger.SBILog.Warnf("Unauthorized request to [%s]: %+v", c.Request.URL.Path, err)
+	c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	util.SendProblem(c, http.StatusUnauthorized, util.CauseInvalidToken, err.Error())
+}
@@ -0,0 +1,132 @@
+package sbi
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi/models"
+)
+
+const (
+	testNrfId = "nrf-instance"
+	testNfId  = "anya-instance"
+)
+
+func setupOAuth2Server(t *testing.T) (*Server, *rsa.PrivateKey) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "nrf.pub")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	mockCtrl := gomock.NewController(t)
+	nfApp := NewMocknfApp(mockCtrl)
+	nfApp.EXPECT().Config().Return(&factory.Config{
+		Configuration: &factory.Configuration{
+			Sbi: &factory.Sbi{
+				Port: 8000,
+				OAuth2: &factory.OAuth2{
+					Issuer:    testNrfId,
+					PublicKey: keyPath,
+				},
+			},
+		},
+	}).AnyTimes()
+	nfApp.EXPECT().Context().Return(&nf_context.NFContext{NfId: testNfId}).AnyTimes()
+
+	return NewServer(nfApp, ""), privateKey
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, audience string, scope string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, AccessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testNrfId,
+			Subject:   "consumer-instance",
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Scope: scope,
+	})
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func Test_OAuth2Authorize(t *testing.T) {
+	server, privateKey := setupOAuth2Server(t)
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+		expectedCause  string
+	}{
+		{
+			name:           "Missing token",
+			token:          "",
+			expectedStatus: http.StatusUnauthorized,
+			expectedCause:  "INVALID_TOKEN",
+		},
+		{
+			name:           "Wrong audience",
+			token:          signToken(t, privateKey, "someone-else", "nanya-dragonball"),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCause:  "INVALID_TOKEN",
+		},
+		{
+			name:           "Missing scope",
+			token:          signToken(t, privateKey, testNfId, "nanya-fortune"),
+			expectedStatus: http.StatusForbidden,
+			expectedCause:  "INSUFFICIENT_SCOPE",
+		},
+		{
+			name:           "Authorized",
+			token:          signToken(t, privateKey, testNfId, "nanya-fortune nanya-dragonball"),
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/dragonball/", nil)
+			require.NoError(t, err)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			recorder := httptest.NewRecorder()
+
+			server.router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedCause == "" {
+				return
+			}
+			assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+			var problem models.ProblemDetails
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+			assert.Equal(t, tt.expectedCause, problem.Cause)
+		})
+	}
+}
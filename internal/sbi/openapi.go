@@ -0,0 +1,334 @@
+package sbi
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Alonza0314/nf-example/internal/util"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+)
+
+const (
+	openAPIVersion    = "3.0.3"
+	openAPIDocVersion = "1.0.0"
+)
+
+// plainText documents a text/plain body, routes answering with c.String use it as Response.
+type plainText string
+
+// eventStream documents a text/event-stream response of Server-Sent Events.
+type eventStream string
+
+// csvText documents a text/csv download.
+type csvText string
+
+// schemaProvider is implemented by body types whose JSON form differs from their Go fields.
+type schemaProvider interface {
+	OpenAPISchema() map[string]any
+}
+
+var (
+	schemaProviderType = reflect.TypeOf((*schemaProvider)(nil)).Elem()
+	plainTextType      = reflect.TypeOf(plainText(""))
+	eventStreamType    = reflect.TypeOf(eventStream(""))
+	csvTextType        = reflect.TypeOf(csvText(""))
+	timeType           = reflect.TypeOf(time.Time{})
+)
+
+func (s *Server) HTTPGetOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, OpenAPIDocument(s.Config()))
+}
+
+// OpenAPIDocument describes every route group registered in newRouter as an OpenAPI 3 document.
+// cfg only adds the server URL and the OAuth2 security scheme, it may be nil.
+func OpenAPIDocument(cfg *factory.Config) map[string]any {
+	// The route tables only bind method values, a bare Server is enough to read them
+	s := &Server{}
+	groups := append(s.getRouteGroups(), routeGroup{factory.ServiceNameManagement, "/nf-management", s.getManagementRoute()})
+
+	b := &schemaBuilder{
+		schemas: make(map[string]any),
+		names:   make(map[string]reflect.Type),
+	}
+	problem := b.schema(reflect.TypeOf(models.ProblemDetails{}))
+
+	var sbiCfg *factory.Sbi
+	if cfg != nil && cfg.Configuration != nil {
+		sbiCfg = cfg.Configuration.Sbi
+	}
+	secured := sbiCfg != nil && sbiCfg.OAuth2 != nil
+
+	paths := make(map[string]any)
+	operationIDs := make(map[string]int)
+	tags := make([]any, 0, len(groups))
+	for _, group := range groups {
+		tags = append(tags, map[string]any{"name": group.ServiceName})
+		for _, route := range group.Routes {
+			pattern, params := openAPIPath(path.Join(group.Prefix, route.Pattern), route.Pattern)
+			item, ok := paths[pattern].(map[string]any)
+			if !ok {
+				item = make(map[string]any)
+				paths[pattern] = item
+			}
+
+			operation := b.operation(route, params, problem)
+			operation["tags"] = []string{group.ServiceName}
+			operation["operationId"] = uniqueOperationID(operationIDs, route.Name)
+			if secured {
+				scopes := route.Scopes
+				if len(scopes) == 0 {
+					scopes = []string{group.ServiceName}
+				}
+				operation["security"] = []any{map[string]any{"oAuth2ClientCredentials": scopes}}
+			}
+			item[strings.ToLower(route.Method)] = operation
+		}
+	}
+
+	components := map[string]any{"schemas": b.schemas}
+	doc := map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":       "ANYA",
+			"description": "SBI of the ANYA network function",
+			"version":     openAPIDocVersion,
+		},
+		"tags":       tags,
+		"paths":      paths,
+		"components": components,
+	}
+	if sbiCfg != nil {
+		servers := make([]any, 0, len(sbiCfg.GetBindingIPs()))
+		for _, ip := range sbiCfg.GetBindingIPs() {
+			servers = append(servers, map[string]any{
+				"url": fmt.Sprintf("%s://%s", sbiCfg.Scheme, net.JoinHostPort(ip, strconv.Itoa(sbiCfg.Port))),
+			})
+		}
+		doc["servers"] = servers
+	}
+	if secured {
+		components["securitySchemes"] = map[string]any{
+			"oAuth2ClientCredentials": map[string]any{
+				"type": "oauth2",
+				"flows": map[string]any{
+					"clientCredentials": map[string]any{
+						"tokenUrl": cfg.GetNrfUri() + "/oauth2/token",
+						"scopes":   map[string]any{},
+					},
+				},
+			},
+		}
+	}
+	return doc
+}
+
+// openAPIPath turns the gin parameters of fullPath into OpenAPI templates and returns
+// the names of the parameters. A group root such as "/fortune/" keeps its trailing slash.
+func openAPIPath(fullPath string, pattern string) (string, []string) {
+	if strings.HasSuffix(pattern, "/") && !strings.HasSuffix(fullPath, "/") {
+		fullPath += "/"
+	}
+	segments := strings.Split(fullPath, "/")
+	var params []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// uniqueOperationID derives a camelCase operationId from the route name.
+func uniqueOperationID(seen map[string]int, name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var id strings.Builder
+	for i, word := range words {
+		runes := []rune(word)
+		if i == 0 {
+			runes[0] = unicode.ToLower(runes[0])
+		} else {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+		id.WriteString(string(runes))
+	}
+
+	operationID := id.String()
+	seen[operationID]++
+	if n := seen[operationID]; n > 1 {
+		operationID += strconv.Itoa(n)
+	}
+	return operationID
+}
+
+type schemaBuilder struct {
+	// schemas are the named struct types, referenced from the operations
+	schemas map[string]any
+	names   map[string]reflect.Type
+}
+
+func (b *schemaBuilder) operation(route Route, params []string, problem map[string]any) map[string]any {
+	operation := map[string]any{"summary": route.Name}
+
+	parameters := make([]any, 0, len(params)+len(route.Query))
+	for _, name := range params {
+		parameters = append(parameters, map[string]any{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+	for _, name := range route.Query {
+		parameters = append(parameters, map[string]any{
+			"name":   name,
+			"in":     "query",
+			"schema": map[string]any{"type": "string"},
+		})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if route.Request != nil {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  b.content(reflect.TypeOf(route.Request)),
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	if route.Response != nil {
+		success["content"] = b.content(reflect.TypeOf(route.Response))
+	}
+	operation["responses"] = map[string]any{
+		strconv.Itoa(status): success,
+		"default": map[string]any{
+			"description": "Problem details",
+			"content": map[string]any{
+				util.ProblemDetailsContentType: map[string]any{"schema": problem},
+			},
+		},
+	}
+	return operation
+}
+
+func (b *schemaBuilder) content(t reflect.Type) map[string]any {
+	switch t {
+	case plainTextType:
+		return map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}}
+	case eventStreamType:
+		return map[string]any{"text/event-stream": map[string]any{"schema": map[string]any{"type": "string"}}}
+	case csvTextType:
+		return map[string]any{"text/csv": map[string]any{"schema": map[string]any{"type": "string"}}}
+	}
+	return map[string]any{"application/json": map[string]any{"schema": b.schema(t)}}
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if t.Implements(schemaProviderType) {
+		return reflect.Zero(t).Interface().(schemaProvider).OpenAPISchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return b.ref(t)
+	}
+	// interfaces accept any value
+	return map[string]any{}
+}
+
+func (b *schemaBuilder) ref(t reflect.Type) map[string]any {
+	name := t.Name()
+	if known, ok := b.names[name]; ok && known != t {
+		// a different type already uses the name, qualify this one with its package
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	if _, ok := b.names[name]; !ok {
+		b.names[name] = t
+		// reserve the name first, recursive types refer to themselves
+		b.schemas[name] = map[string]any{}
+		b.schemas[name] = b.structSchema(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	b.addFields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			b.addFields(field.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
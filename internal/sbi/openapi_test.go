@@ -0,0 +1,120 @@
+package sbi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// decodeDocument round trips the document through JSON, as clients see it.
+func decodeDocument(t *testing.T, raw []byte) map[string]any {
+	t.Helper()
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(raw, &doc))
+	return doc
+}
+
+// collectRefs returns every $ref found in v.
+func collectRefs(v any) []string {
+	var refs []string
+	switch value := v.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				refs = append(refs, ref)
+				continue
+			}
+			refs = append(refs, collectRefs(child)...)
+		}
+	case []any:
+		for _, child := range value {
+			refs = append(refs, collectRefs(child)...)
+		}
+	}
+	return refs
+}
+
+func Test_OpenAPIDocument(t *testing.T) {
+	raw, err := json.Marshal(OpenAPIDocument(nil))
+	require.NoError(t, err)
+	doc := decodeDocument(t, raw)
+
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.NotContains(t, doc, "servers")
+
+	paths := doc["paths"].(map[string]any)
+	s := &Server{}
+	groups := append(s.getRouteGroups(), routeGroup{factory.ServiceNameManagement, "/nf-management", s.getManagementRoute()})
+	for _, group := range groups {
+		for _, route := range group.Routes {
+			pattern, _ := openAPIPath(path.Join(group.Prefix, route.Pattern), route.Pattern)
+			item, ok := paths[pattern].(map[string]any)
+			require.True(t, ok, "missing path %s", pattern)
+			operation, ok := item[strings.ToLower(route.Method)].(map[string]any)
+			require.True(t, ok, "missing %s %s", route.Method, pattern)
+			assert.Equal(t, route.Name, operation["summary"])
+			assert.Equal(t, []any{group.ServiceName}, operation["tags"])
+		}
+	}
+
+	getTask := paths["/task/tasks/{id}"].(map[string]any)["get"].(map[string]any)
+	assert.Equal(t, "getTask", getTask["operationId"])
+	assert.Equal(t, []any{map[string]any{
+		"name": "id", "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+	}}, getTask["parameters"])
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	task := schemas["TaskRequest"].(map[string]any)
+	assert.Equal(t, []any{"name"}, task["required"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"},
+		task["properties"].(map[string]any)["dueDate"])
+
+	for _, ref := range collectRefs(doc) {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		assert.Contains(t, schemas, name, "dangling $ref %s", ref)
+	}
+}
+
+func Test_HTTPGetOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	nfApp := NewMocknfApp(mockCtrl)
+	nfApp.EXPECT().Config().Return(&factory.Config{
+		Configuration: &factory.Configuration{
+			Sbi: &factory.Sbi{
+				Scheme:      "http",
+				BindingIPv4: "127.0.0.163",
+				Port:        8000,
+				OAuth2:      &factory.OAuth2{Issuer: "nrf", PublicKey: "cert/nrf.pem"},
+			},
+			NrfUri: "http://127.0.0.10:8000",
+		},
+	}).AnyTimes()
+	server := &Server{nfApp: nfApp}
+	server.router = newRouter(server)
+
+	req, err := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	doc := decodeDocument(t, recorder.Body.Bytes())
+	assert.Equal(t, []any{map[string]any{"url": "http://127.0.0.163:8000"}}, doc["servers"])
+
+	reload := doc["paths"].(map[string]any)["/nf-management/config"].(map[string]any)["put"].(map[string]any)
+	assert.Equal(t, []any{map[string]any{"oAuth2ClientCredentials": []any{"nanya-management"}}}, reload["security"])
+	flows := doc["components"].(map[string]any)["securitySchemes"].(map[string]any)["oAuth2ClientCredentials"].(map[string]any)["flows"]
+	assert.Equal(t, "http://127.0.0.10:8000/oauth2/token",
+		flows.(map[string]any)["clientCredentials"].(map[string]any)["tokenUrl"])
+}
@@ -1,20 +1,36 @@
 package processor
 
 import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"mime"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
 )
 
 func (p *Processor) ReturnAttendance(c *gin.Context) {
-	con := p.Context()
+	attendance, err := p.Context().Attendance.All()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
 
-	if len(con.AttendanceData) == 0 {
+	if len(attendance) == 0 {
 		c.String(http.StatusOK, "No attendance recorded")
 		return
 	} else {
 		names := ""
-		for _, name := range con.AttendanceData {
+		for _, name := range attendance {
 			names += name + ", "
 		}
 		c.String(http.StatusOK, "Attendance: "+names[:len(names)-2])
@@ -23,16 +39,265 @@ func (p *Processor) ReturnAttendance(c *gin.Context) {
 }
 
 func (p *Processor) PostAttendance(c *gin.Context, targetName string) {
-	con := p.Context()
+	_, inserted, err := p.Context().Attendance.Insert(targetName, targetName)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !inserted {
+		util.SendProblem(c, http.StatusConflict, util.CauseDataConflict, "Attendance already recorded: "+targetName)
+		return
+	}
+
+	c.String(http.StatusOK, "Attendance recorded: "+targetName)
+}
+
+// AttendanceNameRequest names the session to create or the person checking in or out.
+type AttendanceNameRequest struct {
+	Name string `json:"name" binding:"required"`
+}
 
-	for n := range con.AttendanceData {
-		if con.AttendanceData[n] == targetName {
-			c.String(http.StatusConflict, "Attendance already recorded: "+targetName)
-			return
+// AttendanceSessionSummary lists a session without its records.
+type AttendanceSessionSummary struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Attendees is the number of different people who checked in, Present the number still checked in
+	Attendees int `json:"attendees"`
+	Present   int `json:"present"`
+}
+
+// PersonAttendanceRecord is a record of a person with the session it belongs to.
+type PersonAttendanceRecord struct {
+	Session string `json:"session"`
+	nf_context.AttendanceRecord
+}
+
+func summarizeSession(session nf_context.AttendanceSession) AttendanceSessionSummary {
+	summary := AttendanceSessionSummary{Name: session.Name, CreatedAt: session.CreatedAt}
+	attendees := make(map[string]bool)
+	for _, record := range session.Records {
+		attendees[record.Name] = true
+		if record.CheckOut == nil {
+			summary.Present++
 		}
 	}
+	summary.Attendees = len(attendees)
+	return summary
+}
 
-	con.AttendanceData = append(con.AttendanceData, targetName)
+func sessionNotFound(session string) *models.ProblemDetails {
+	return util.NewProblemDetails(http.StatusNotFound, util.CauseDataNotFound,
+		fmt.Sprintf("Attendance session %s not found", session))
+}
 
-	c.String(http.StatusOK, "Attendance recorded: "+targetName)
+func (p *Processor) CreateAttendanceSession(c *gin.Context, name string) {
+	session := nf_context.AttendanceSession{
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+		Records:   []nf_context.AttendanceRecord{},
+	}
+	_, inserted, err := p.Context().Sessions.Insert(name, session)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !inserted {
+		util.SendProblem(c, http.StatusConflict, util.CauseDataConflict,
+			fmt.Sprintf("Attendance session %s already exists", name))
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// ListAttendanceSessions lists the sessions by creation, paged with offset and limit.
+func (p *Processor) ListAttendanceSessions(c *gin.Context) {
+	offset, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := p.Context().Sessions.All()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	summaries := make([]AttendanceSessionSummary, 0, len(sessions))
+	for _, session := range sessions {
+		summaries = append(summaries, summarizeSession(session))
+	}
+
+	c.JSON(http.StatusOK, paginate(c, summaries, offset, limit))
+}
+
+// GetAttendanceSession returns the session with its records, ?present=true only keeps the
+// people still checked in.
+func (p *Processor) GetAttendanceSession(c *gin.Context, name string) {
+	present, err := strconv.ParseBool(c.DefaultQuery("present", "false"))
+	if err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, "present must be true or false",
+			models.InvalidParam{Param: "present", Reason: "must be true or false"})
+		return
+	}
+
+	session, found, err := p.Context().Sessions.Get(name)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !found {
+		util.SendProblemDetails(c, sessionNotFound(name))
+		return
+	}
+	if present {
+		records := make([]nf_context.AttendanceRecord, 0, len(session.Records))
+		for _, record := range session.Records {
+			if record.CheckOut == nil {
+				records = append(records, record)
+			}
+		}
+		session.Records = records
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// CheckInAttendance opens a record of the person in the session.
+func (p *Processor) CheckInAttendance(c *gin.Context, session string, name string) {
+	var (
+		record  nf_context.AttendanceRecord
+		problem *models.ProblemDetails
+	)
+	err := p.Context().Sessions.Do(func(tx *nf_context.StoreTx[nf_context.AttendanceSession]) error {
+		stored, found, err := tx.Get(session)
+		if err != nil {
+			return err
+		}
+		if !found {
+			problem = sessionNotFound(session)
+			return nil
+		}
+		if stored.Present(name) >= 0 {
+			problem = util.NewProblemDetails(http.StatusConflict, util.CauseDataConflict,
+				fmt.Sprintf("%s is already checked in to %s", name, session))
+			return nil
+		}
+
+		record = nf_context.AttendanceRecord{Name: name, CheckIn: time.Now().UTC()}
+		stored.Records = append(stored.Records, record)
+		return tx.Put(session, stored)
+	})
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if problem != nil {
+		util.SendProblemDetails(c, problem)
+		return
+	}
+
+	c.JSON(http.StatusCreated, record)
+}
+
+// CheckOutAttendance closes the open record of the person in the session.
+func (p *Processor) CheckOutAttendance(c *gin.Context, session string, name string) {
+	var (
+		record  nf_context.AttendanceRecord
+		problem *models.ProblemDetails
+	)
+	err := p.Context().Sessions.Do(func(tx *nf_context.StoreTx[nf_context.AttendanceSession]) error {
+		stored, found, err := tx.Get(session)
+		if err != nil {
+			return err
+		}
+		if !found {
+			problem = sessionNotFound(session)
+			return nil
+		}
+		i := stored.Present(name)
+		if i < 0 {
+			problem = util.NewProblemDetails(http.StatusConflict, util.CauseDataConflict,
+				fmt.Sprintf("%s is not checked in to %s", name, session))
+			return nil
+		}
+
+		checkOut := time.Now().UTC()
+		stored.Records[i].CheckOut = &checkOut
+		record = stored.Records[i]
+		return tx.Put(session, stored)
+	})
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if problem != nil {
+		util.SendProblemDetails(c, problem)
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// GetPersonAttendance returns the records of the person in every session, by check-in.
+func (p *Processor) GetPersonAttendance(c *gin.Context, name string) {
+	sessions, err := p.Context().Sessions.All()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+
+	records := make([]PersonAttendanceRecord, 0)
+	for _, session := range sessions {
+		for _, record := range session.Records {
+			if record.Name == name {
+				records = append(records, PersonAttendanceRecord{Session: session.Name, AttendanceRecord: record})
+			}
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CheckIn.Before(records[j].CheckIn)
+	})
+
+	c.JSON(http.StatusOK, records)
+}
+
+// ExportAttendanceSession answers the records of the session as CSV, the duration is empty
+// for the people still checked in.
+func (p *Processor) ExportAttendanceSession(c *gin.Context, name string) {
+	session, found, err := p.Context().Sessions.Get(name)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !found {
+		util.SendProblemDetails(c, sessionNotFound(name))
+		return
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{{"name", "check_in", "check_out", "duration_seconds"}}
+	for _, record := range session.Records {
+		row := []string{csvCell(record.Name), record.CheckIn.Format(time.RFC3339), "", ""}
+		if record.CheckOut != nil {
+			row[2] = record.CheckOut.Format(time.RFC3339)
+			row[3] = strconv.FormatInt(int64(record.CheckOut.Sub(record.CheckIn)/time.Second), 10)
+		}
+		rows = append(rows, row)
+	}
+	if err = w.WriteAll(rows); err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": session.Name + ".csv"}))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// csvCell quotes a value a spreadsheet would otherwise evaluate as a formula.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
 }
@@ -1,12 +1,18 @@
 package processor_test
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	nf_context "github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/storage"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -23,9 +29,7 @@ func Test_ReturnAttendance(t *testing.T) {
 	t.Run("No Attendance Recorded", func(t *testing.T) {
 		const EXPECTED_STATUS = 200
 		const EXPECTED_BODY = "No attendance recorded"
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			AttendanceData: []string{},
-		})
+		processorNf.EXPECT().Context().Return(newTestListContext(t, nf_context.AttendanceCollection, []string{}, nil))
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -43,9 +47,8 @@ func Test_ReturnAttendance(t *testing.T) {
 	t.Run("Some Attendance Recorded", func(t *testing.T) {
 		const EXPECTED_STATUS = 200
 		const EXPECTED_BODY = "Attendance: Alice, Bob, Charlie"
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			AttendanceData: []string{"Alice", "Bob", "Charlie"},
-		})
+		processorNf.EXPECT().Context().Return(newTestListContext(t, nf_context.AttendanceCollection,
+			[]string{"Alice", "Bob", "Charlie"}, func(name string) string { return name }))
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
 		processor.ReturnAttendance(ginCtx)
@@ -74,9 +77,8 @@ func Test_PostAttandence(t *testing.T) {
 		const INPUT_NAME = "David"
 		const EXPECTED_STATUS = 200
 		const EXPECTED_BODY = "Attendance recorded: " + INPUT_NAME
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			AttendanceData: []string{"Alice", "Bob", "Charlie"},
-		})
+		processorNf.EXPECT().Context().Return(newTestListContext(t, nf_context.AttendanceCollection,
+			[]string{"Alice", "Bob", "Charlie"}, func(name string) string { return name }))
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
 		processor.PostAttendance(ginCtx, INPUT_NAME)
@@ -93,10 +95,9 @@ func Test_PostAttandence(t *testing.T) {
 	t.Run("Post Duplicate Attendance", func(t *testing.T) {
 		const INPUT_NAME = "Alice"
 		const EXPECTED_STATUS = 409
-		const EXPECTED_BODY = "Attendance already recorded: " + INPUT_NAME
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			AttendanceData: []string{"Alice", "Bob", "Charlie"},
-		})
+		const EXPECTED_DETAIL = "Attendance already recorded: " + INPUT_NAME
+		processorNf.EXPECT().Context().Return(newTestListContext(t, nf_context.AttendanceCollection,
+			[]string{"Alice", "Bob", "Charlie"}, func(name string) string { return name }))
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
 		processor.PostAttendance(ginCtx, INPUT_NAME)
@@ -105,8 +106,162 @@ func Test_PostAttandence(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		assertProblem(t, httpRecorder, "DATA_CONFLICT", EXPECTED_DETAIL)
+	})
+}
+
+func Test_AttendanceSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	processorNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(processorNf)
+	require.NoError(t, err)
+	processorNf.EXPECT().Context().Return(newStorageContext(storage.NewMemoryStorage())).AnyTimes()
+
+	call := func(handler func(c *gin.Context), target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		ginCtx.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		handler(ginCtx)
+		return rec
+	}
+	checkIn := func(session, name string) *httptest.ResponseRecorder {
+		return call(func(c *gin.Context) { p.CheckInAttendance(c, session, name) }, "/")
+	}
+	checkOut := func(session, name string) *httptest.ResponseRecorder {
+		return call(func(c *gin.Context) { p.CheckOutAttendance(c, session, name) }, "/")
+	}
+
+	t.Run("Create Session", func(t *testing.T) {
+		rec := call(func(c *gin.Context) { p.CreateAttendanceSession(c, "math") }, "/")
+		require.Equal(t, http.StatusCreated, rec.Code)
+		var session nf_context.AttendanceSession
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &session))
+		assert.Equal(t, "math", session.Name)
+		assert.Empty(t, session.Records)
+
+		rec = call(func(c *gin.Context) { p.CreateAttendanceSession(c, "math") }, "/")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assertProblem(t, rec, "DATA_CONFLICT", "Attendance session math already exists")
+
+		require.Equal(t, http.StatusCreated, call(func(c *gin.Context) { p.CreateAttendanceSession(c, "art") }, "/").Code)
+	})
+
+	t.Run("Check In And Out", func(t *testing.T) {
+		rec := checkIn("math", "Anya")
+		require.Equal(t, http.StatusCreated, rec.Code)
+		var record nf_context.AttendanceRecord
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &record))
+		assert.Equal(t, "Anya", record.Name)
+		assert.False(t, record.CheckIn.IsZero())
+		assert.Nil(t, record.CheckOut)
+
+		rec = checkIn("math", "Anya")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assertProblem(t, rec, "DATA_CONFLICT", "Anya is already checked in to math")
+
+		rec = checkOut("math", "Anya")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &record))
+		require.NotNil(t, record.CheckOut)
+		assert.False(t, record.CheckOut.Before(record.CheckIn))
+
+		rec = checkOut("math", "Anya")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assertProblem(t, rec, "DATA_CONFLICT", "Anya is not checked in to math")
+
+		// checking in again opens a new record
+		require.Equal(t, http.StatusCreated, checkIn("math", "Anya").Code)
+		require.Equal(t, http.StatusCreated, checkIn("math", "Becky").Code)
+		require.Equal(t, http.StatusCreated, checkIn("art", "Anya").Code)
+
+		rec = checkIn("history", "Anya")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assertProblem(t, rec, "DATA_NOT_FOUND", "Attendance session history not found")
+	})
+
+	t.Run("Get Session", func(t *testing.T) {
+		rec := call(func(c *gin.Context) { p.GetAttendanceSession(c, "math") }, "/?present=true")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var session nf_context.AttendanceSession
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &session))
+		require.Len(t, session.Records, 2)
+		assert.Equal(t, "Anya", session.Records[0].Name)
+		assert.Equal(t, "Becky", session.Records[1].Name)
+
+		rec = call(func(c *gin.Context) { p.GetAttendanceSession(c, "math") }, "/")
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &session))
+		assert.Len(t, session.Records, 3)
+
+		rec = call(func(c *gin.Context) { p.GetAttendanceSession(c, "math") }, "/?present=maybe")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assertProblem(t, rec, "INVALID_QUERY_PARAM", "present must be true or false")
+	})
+
+	t.Run("List Sessions", func(t *testing.T) {
+		rec := call(p.ListAttendanceSessions, "/?limit=1")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "2", rec.Header().Get("X-Total-Count"))
+		var summaries []processor.AttendanceSessionSummary
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &summaries))
+		require.Len(t, summaries, 1)
+		assert.Equal(t, "math", summaries[0].Name)
+		assert.Equal(t, 2, summaries[0].Attendees)
+		assert.Equal(t, 2, summaries[0].Present)
+	})
+
+	t.Run("Person Attendance", func(t *testing.T) {
+		rec := call(func(c *gin.Context) { p.GetPersonAttendance(c, "Anya") }, "/")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var records []processor.PersonAttendanceRecord
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &records))
+		require.Len(t, records, 3)
+		assert.Equal(t, []string{"math", "math", "art"},
+			[]string{records[0].Session, records[1].Session, records[2].Session})
+		assert.NotNil(t, records[0].CheckOut)
+
+		rec = call(func(c *gin.Context) { p.GetPersonAttendance(c, "Damian") }, "/")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, "[]", rec.Body.String())
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		rec := call(func(c *gin.Context) { p.ExportAttendanceSession(c, "math") }, "/")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "attachment; filename=math.csv", rec.Header().Get("Content-Disposition"))
+
+		rows, err := csv.NewReader(rec.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 4)
+		assert.Equal(t, []string{"name", "check_in", "check_out", "duration_seconds"}, rows[0])
+		assert.Equal(t, "Anya", rows[1][0])
+		assert.NotEmpty(t, rows[1][2])
+		assert.Equal(t, "0", rows[1][3])
+		assert.Equal(t, []string{"Becky", "", ""}, []string{rows[3][0], rows[3][2], rows[3][3]})
+
+		rec = call(func(c *gin.Context) { p.ExportAttendanceSession(c, "history") }, "/")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("Export Quotes Formulas", func(t *testing.T) {
+		require.Equal(t, http.StatusCreated, call(func(c *gin.Context) { p.CreateAttendanceSession(c, "café") }, "/").Code)
+		names := []string{"=HYPERLINK(\"x\")", "+1", "-1", "@SUM(A1)", "Anya"}
+		for _, name := range names {
+			require.Equal(t, http.StatusCreated, checkIn("café", name).Code)
+		}
+
+		rec := call(func(c *gin.Context) { p.ExportAttendanceSession(c, "café") }, "/")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "attachment; filename*=utf-8''caf%C3%A9.csv", rec.Header().Get("Content-Disposition"))
+
+		rows, err := csv.NewReader(rec.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, len(names)+1)
+		exported := make([]string, 0, len(names))
+		for _, row := range rows[1:] {
+			exported = append(exported, row[0])
 		}
+		assert.Equal(t, []string{"'=HYPERLINK(\"x\")", "'+1", "'-1", "'@SUM(A1)", "Anya"}, exported)
 	})
 }
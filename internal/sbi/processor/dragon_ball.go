@@ -1,16 +1,29 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
 )
 
 func (p *Processor) SearchDragonBallCharacter(c *gin.Context, targetName string) {
-	pl, ok := p.Context().DragonBallData[targetName]
+	pl, ok, err := p.Context().DragonBall.Get(targetName)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
 	if !ok {
-		c.String(http.StatusNotFound, fmt.Sprintf("[%s] not found in Dragon Ball\n", targetName))
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("[%s] not found in Dragon Ball", targetName))
 		return
 	}
 
@@ -18,15 +31,26 @@ func (p *Processor) SearchDragonBallCharacter(c *gin.Context, targetName string)
 }
 
 func (p *Processor) FightDragonBall(c *gin.Context, targetName1 string, targetName2 string) {
-	pl1, ok1 := p.Context().DragonBallData[targetName1]
-	pl2, ok2 := p.Context().DragonBallData[targetName2]
+	var pl1, pl2 int32
+	var ok1, ok2 bool
+	// read both power levels at once, an update in between would mix two states
+	err := p.Context().DragonBall.Do(func(tx *nf_context.StoreTx[int32]) error {
+		var err1, err2 error
+		pl1, ok1, err1 = tx.Get(targetName1)
+		pl2, ok2, err2 = tx.Get(targetName2)
+		return errors.Join(err1, err2)
+	})
 
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
 	if !ok1 {
-		c.String(http.StatusNotFound, fmt.Sprintf("[%s] not found in Dragon Ball\n", targetName1))
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("[%s] not found in Dragon Ball", targetName1))
 		return
 	}
 	if !ok2 {
-		c.String(http.StatusNotFound, fmt.Sprintf("[%s] not found in Dragon Ball\n", targetName2))
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("[%s] not found in Dragon Ball", targetName2))
 		return
 	}
 
@@ -40,20 +64,332 @@ func (p *Processor) FightDragonBall(c *gin.Context, targetName1 string, targetNa
 }
 
 func (p *Processor) AddDragonBallCharacter(c *gin.Context, targetName string, powerlevel int32) {
-	pl, ok := p.Context().DragonBallData[targetName]
-	if ok {
-		c.String(http.StatusConflict, fmt.Sprintf("Character %s already exists with Powerlevel %d\n", targetName, pl))
+	pl, inserted, err := p.Context().DragonBall.Insert(targetName, powerlevel)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !inserted {
+		util.SendProblem(c, http.StatusConflict, util.CauseDataConflict, fmt.Sprintf("Character %s already exists with Powerlevel %d", targetName, pl))
 		return
 	}
-	p.Context().DragonBallData[targetName] = powerlevel
 	c.String(http.StatusCreated, fmt.Sprintf("Add Character %s with Powerlevel %d\n", targetName, powerlevel))
 }
 
 func (p *Processor) UpdateDragonBallCharacter(c *gin.Context, targetName string, powerlevel int32) {
-	if _, ok := p.Context().DragonBallData[targetName]; !ok {
-		c.String(http.StatusNotFound, fmt.Sprintf("Character %s not found\n", targetName))
+	_, found, err := p.Context().DragonBall.Update(targetName, func(pl *int32) error {
+		*pl = powerlevel
+		return nil
+	})
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !found {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("Character %s not found", targetName))
 		return
 	}
-	p.Context().DragonBallData[targetName] = powerlevel
 	c.String(http.StatusOK, fmt.Sprintf("Update Character %s with Powerlevel %d\n", targetName, powerlevel))
 }
+
+func (p *Processor) DeleteDragonBallCharacter(c *gin.Context, targetName string) {
+	deleted, err := p.Context().DragonBall.Delete(targetName)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !deleted {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("Character %s not found", targetName))
+		return
+	}
+	c.String(http.StatusOK, fmt.Sprintf("Delete Character %s\n", targetName))
+}
+
+type DragonBallCharacter struct {
+	Name       string `json:"name"`
+	PowerLevel int32  `json:"powerLevel"`
+}
+
+// DragonBallRank is a leaderboard entry, characters with the same power level share the
+// rank and the next rank skips the shared places: 1, 2, 2, 4.
+type DragonBallRank struct {
+	Rank       int    `json:"rank"`
+	Name       string `json:"name"`
+	PowerLevel int32  `json:"powerLevel"`
+}
+
+var dragonBallSortFields = map[string]func(a, b *DragonBallCharacter) int{
+	"name":       func(a, b *DragonBallCharacter) int { return strings.Compare(a.Name, b.Name) },
+	"powerLevel": func(a, b *DragonBallCharacter) int { return int(a.PowerLevel) - int(b.PowerLevel) },
+}
+
+// loadDragonBallCharacters returns the characters from the strongest to the weakest,
+// equal power levels in name order.
+func (p *Processor) loadDragonBallCharacters() ([]DragonBallCharacter, error) {
+	entries, err := p.Context().DragonBall.Entries()
+	if err != nil {
+		return nil, err
+	}
+	characters := make([]DragonBallCharacter, 0, len(entries))
+	for _, entry := range entries {
+		characters = append(characters, DragonBallCharacter{Name: entry.Key, PowerLevel: entry.Value})
+	}
+	sort.Slice(characters, func(i, j int) bool {
+		if characters[i].PowerLevel != characters[j].PowerLevel {
+			return characters[i].PowerLevel > characters[j].PowerLevel
+		}
+		return characters[i].Name < characters[j].Name
+	})
+	return characters, nil
+}
+
+// ListDragonBallCharacters lists the characters. Query parameters:
+//
+//	sort:          powerLevel or name, prefixed with "-" for descending order, defaults to -powerLevel
+//	offset, limit: pagination, the total number of characters is returned in X-Total-Count
+func (p *Processor) ListDragonBallCharacters(c *gin.Context) {
+	sortBy := c.DefaultQuery("sort", "-powerLevel")
+	desc := strings.HasPrefix(sortBy, "-")
+	compare, ok := dragonBallSortFields[strings.TrimPrefix(sortBy, "-")]
+	if !ok {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, fmt.Sprintf("invalid sort field %q", sortBy),
+			models.InvalidParam{Param: "sort", Reason: "must be powerLevel or name"})
+		return
+	}
+	offset, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	characters, err := p.loadDragonBallCharacters()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	// stable, so equal power levels stay in name order
+	sort.SliceStable(characters, func(i, j int) bool {
+		if desc {
+			return compare(&characters[j], &characters[i]) < 0
+		}
+		return compare(&characters[i], &characters[j]) < 0
+	})
+
+	c.JSON(http.StatusOK, paginate(c, characters, offset, limit))
+}
+
+// GetDragonBallRanking returns the leaderboard by power level, offset and limit page it.
+func (p *Processor) GetDragonBallRanking(c *gin.Context) {
+	offset, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	characters, err := p.loadDragonBallCharacters()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	ranking := make([]DragonBallRank, 0, len(characters))
+	for i, character := range characters {
+		rank := i + 1
+		if i > 0 && character.PowerLevel == characters[i-1].PowerLevel {
+			rank = ranking[i-1].Rank
+		}
+		ranking = append(ranking, DragonBallRank{Rank: rank, Name: character.Name, PowerLevel: character.PowerLevel})
+	}
+
+	c.JSON(http.StatusOK, paginate(c, ranking, offset, limit))
+}
+
+// Decisions of a tournament match
+const (
+	TournamentDecisionPower = "power"
+	TournamentDecisionSeed  = "seed"
+	TournamentDecisionBye   = "bye"
+)
+
+// RunDragonBallTournament seeds a single-elimination bracket by power level, resolves every
+// round and stores the result. names nil enters every character.
+func (p *Processor) RunDragonBallTournament(c *gin.Context, names []string) {
+	nfCtx := p.Context()
+
+	characters, err := nfCtx.DragonBall.Entries()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	powerLevels := make(map[string]int32, len(characters))
+	for _, character := range characters {
+		powerLevels[character.Key] = character.Value
+	}
+
+	var entrants []nf_context.TournamentEntrant
+	if names == nil {
+		for _, character := range characters {
+			entrants = append(entrants, nf_context.TournamentEntrant{Name: character.Key, PowerLevel: character.Value})
+		}
+	} else {
+		entered := make(map[string]bool, len(names))
+		var missing []string
+		for _, name := range names {
+			if entered[name] {
+				util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, fmt.Sprintf("[%s] is entered twice", name),
+					models.InvalidParam{Param: "characters", Reason: "must not contain duplicates"})
+				return
+			}
+			entered[name] = true
+			pl, ok := powerLevels[name]
+			if !ok {
+				missing = append(missing, name)
+				continue
+			}
+			entrants = append(entrants, nf_context.TournamentEntrant{Name: name, PowerLevel: pl})
+		}
+		if len(missing) > 0 {
+			util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound,
+				fmt.Sprintf("[%s] not found in Dragon Ball", strings.Join(missing, ", ")))
+			return
+		}
+	}
+	if len(entrants) < 2 {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, "A tournament needs at least 2 characters",
+			models.InvalidParam{Param: "characters", Reason: "must name at least 2 characters"})
+		return
+	}
+
+	seedEntrants(entrants)
+	rounds, champion := resolveBracket(entrants)
+
+	tournament, err := nfCtx.Tournaments.Append(func(seq uint64) nf_context.Tournament {
+		return nf_context.Tournament{
+			ID:        int(seq),
+			CreatedAt: time.Now().UTC(),
+			Entrants:  entrants,
+			Rounds:    rounds,
+			Champion:  champion,
+		}
+	})
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, tournament)
+}
+
+func (p *Processor) GetDragonBallTournament(c *gin.Context, id string) {
+	seq, err := strconv.Atoi(id)
+	if err != nil || seq <= 0 {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, "Invalid tournament ID")
+		return
+	}
+
+	tournament, ok, err := p.Context().Tournaments.Get(strconv.Itoa(seq))
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !ok {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("Tournament %d not found", seq))
+		return
+	}
+	c.JSON(http.StatusOK, tournament)
+}
+
+// seedEntrants orders the entrants by power level, equal power levels by name, and numbers the seeds.
+func seedEntrants(entrants []nf_context.TournamentEntrant) {
+	sort.Slice(entrants, func(i, j int) bool {
+		if entrants[i].PowerLevel != entrants[j].PowerLevel {
+			return entrants[i].PowerLevel > entrants[j].PowerLevel
+		}
+		return entrants[i].Name < entrants[j].Name
+	})
+	for i := range entrants {
+		entrants[i].Seed = i + 1
+	}
+}
+
+// bracketOrder returns the seeds of a bracket of size slots in bracket order, so the top
+// seeds only meet in the last rounds: 1 8 4 5 2 7 3 6 for 8 slots.
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, 2*len(order))
+		for _, seed := range order {
+			next = append(next, seed, 2*len(order)+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// resolveBracket plays the bracket of the seeded entrants. Seeds beyond the entrants are
+// byes, they only face the top seeds in the first round.
+func resolveBracket(entrants []nf_context.TournamentEntrant) ([]nf_context.TournamentRound, nf_context.TournamentEntrant) {
+	size := 1
+	for size < len(entrants) {
+		size *= 2
+	}
+	slots := make([]*nf_context.TournamentEntrant, 0, size)
+	for _, seed := range bracketOrder(size) {
+		if seed <= len(entrants) {
+			slots = append(slots, &entrants[seed-1])
+		} else {
+			slots = append(slots, nil)
+		}
+	}
+
+	var rounds []nf_context.TournamentRound
+	match := 0
+	for len(slots) > 1 {
+		round := nf_context.TournamentRound{
+			Round: len(rounds) + 1,
+			Name:  roundName(len(slots) / 2),
+		}
+		winners := make([]*nf_context.TournamentEntrant, 0, len(slots)/2)
+		for i := 0; i < len(slots); i += 2 {
+			match++
+			result := fight(slots[i], slots[i+1])
+			result.Match = match
+			round.Matches = append(round.Matches, result)
+			if result.Winner == slots[i].Name {
+				winners = append(winners, slots[i])
+			} else {
+				winners = append(winners, slots[i+1])
+			}
+		}
+		rounds = append(rounds, round)
+		slots = winners
+	}
+	return rounds, *slots[0]
+}
+
+// fight decides a match by power level, equal power levels go to the better seed.
+// fighter2 is nil for a bye.
+func fight(fighter1, fighter2 *nf_context.TournamentEntrant) nf_context.TournamentMatch {
+	result := nf_context.TournamentMatch{Fighter1: *fighter1, Fighter2: fighter2}
+	switch {
+	case fighter2 == nil:
+		result.Winner, result.Decision = fighter1.Name, TournamentDecisionBye
+	case fighter1.PowerLevel > fighter2.PowerLevel:
+		result.Winner, result.Decision = fighter1.Name, TournamentDecisionPower
+	case fighter1.PowerLevel < fighter2.PowerLevel:
+		result.Winner, result.Decision = fighter2.Name, TournamentDecisionPower
+	case fighter1.Seed < fighter2.Seed:
+		result.Winner, result.Decision = fighter1.Name, TournamentDecisionSeed
+	default:
+		result.Winner, result.Decision = fighter2.Name, TournamentDecisionSeed
+	}
+	return result
+}
+
+func roundName(matches int) string {
+	switch matches {
+	case 1:
+		return "Final"
+	case 2:
+		return "Semifinals"
+	case 4:
+		return "Quarterfinals"
+	}
+	return fmt.Sprintf("Round of %d", 2*matches)
+}
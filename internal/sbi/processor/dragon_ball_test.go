@@ -1,6 +1,8 @@
 package processor_test
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,6 +10,8 @@ import (
 	nf_context "github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -26,11 +30,9 @@ func Test_SearchDragonBallCharacter(t *testing.T) {
 		const INPUT_NAME = "Goku"
 		const EXPECTED_STATUS = http.StatusOK
 		const EXPECTED_BODY = "Character: " + INPUT_NAME + ", Powerlevel: 7\n"
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			DragonBallData: map[string]int32{
-				"Goku": 7,
-			},
-		})
+		processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.DragonBallCollection, map[string]int32{
+			"Goku": 7,
+		}))
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -48,13 +50,11 @@ func Test_SearchDragonBallCharacter(t *testing.T) {
 	t.Run("Find Character That Does Not Exist", func(t *testing.T) {
 		const INPUT_NAME = "Andy"
 		const EXPECTED_STATUS = http.StatusNotFound
-		const EXPECTED_BODY = "[" + INPUT_NAME + "] not found in Dragon Ball\n"
+		const EXPECTED_DETAIL = "[" + INPUT_NAME + "] not found in Dragon Ball"
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			DragonBallData: map[string]int32{
-				"Goku": 7,
-			},
-		})
+		processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.DragonBallCollection, map[string]int32{
+			"Goku": 7,
+		}))
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -64,9 +64,7 @@ func Test_SearchDragonBallCharacter(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
-		}
+		assertProblem(t, httpRecorder, "DATA_NOT_FOUND", EXPECTED_DETAIL)
 	})
 }
 
@@ -81,13 +79,11 @@ func Test_FightDragonBall(t *testing.T) {
 		return
 	}
 
-	processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-		DragonBallData: map[string]int32{
-			"Goku":    7,
-			"Vegeta":  6,
-			"Krillin": 7,
-		},
-	}).AnyTimes()
+	processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.DragonBallCollection, map[string]int32{
+		"Goku":    7,
+		"Vegeta":  6,
+		"Krillin": 7,
+	})).AnyTimes()
 
 	tests := []struct {
 		name           string
@@ -101,14 +97,14 @@ func Test_FightDragonBall(t *testing.T) {
 			targetName1:    "Andy",
 			targetName2:    "Vegeta",
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   "[Andy] not found in Dragon Ball\n",
+			expectedBody:   "[Andy] not found in Dragon Ball",
 		},
 		{
 			name:           "targetName2 not found",
 			targetName1:    "Goku",
 			targetName2:    "Andy",
 			expectedStatus: http.StatusNotFound,
-			expectedBody:   "[Andy] not found in Dragon Ball\n",
+			expectedBody:   "[Andy] not found in Dragon Ball",
 		},
 		{
 			name:           "Goku defeats Vegeta",
@@ -142,6 +138,10 @@ func Test_FightDragonBall(t *testing.T) {
 			if httpRecorder.Code != tc.expectedStatus {
 				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, httpRecorder.Code)
 			}
+			if tc.expectedStatus != http.StatusOK {
+				assertProblem(t, httpRecorder, "DATA_NOT_FOUND", tc.expectedBody)
+				return
+			}
 			if httpRecorder.Body.String() != tc.expectedBody {
 				t.Errorf("Expected body %q, got %q", tc.expectedBody, httpRecorder.Body.String())
 			}
@@ -156,11 +156,9 @@ func Test_AddDragonBallCharacter(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	processorNf := processor.NewMockProcessorNf(mockCtrl)
 
-	processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-		DragonBallData: map[string]int32{
-			"Goku": 7,
-		},
-	}).AnyTimes()
+	processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.DragonBallCollection, map[string]int32{
+		"Goku": 7,
+	})).AnyTimes()
 	processor, err := processor.NewProcessor(processorNf)
 	if err != nil {
 		t.Errorf("Failed to create processor: %s", err)
@@ -175,10 +173,7 @@ func Test_AddDragonBallCharacter(t *testing.T) {
 		if httpRecorder.Code != http.StatusConflict {
 			t.Errorf("Expected status code %d, got %d", http.StatusConflict, httpRecorder.Code)
 		}
-		expectedBody := "Character Goku already exists with Powerlevel 7\n"
-		if httpRecorder.Body.String() != expectedBody {
-			t.Errorf("Expected body %q, got %q", expectedBody, httpRecorder.Body.String())
-		}
+		assertProblem(t, httpRecorder, "DATA_CONFLICT", "Character Goku already exists with Powerlevel 7")
 	})
 
 	t.Run("Add new character", func(t *testing.T) {
@@ -203,11 +198,9 @@ func Test_UpdateDragonBallCharacter(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	processorNf := processor.NewMockProcessorNf(mockCtrl)
 
-	processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-		DragonBallData: map[string]int32{
-			"Goku": 7,
-		},
-	}).AnyTimes()
+	processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.DragonBallCollection, map[string]int32{
+		"Goku": 7,
+	})).AnyTimes()
 	processor, err := processor.NewProcessor(processorNf)
 	if err != nil {
 		t.Errorf("Failed to create processor: %s", err)
@@ -222,10 +215,7 @@ func Test_UpdateDragonBallCharacter(t *testing.T) {
 		if httpRecorder.Code != http.StatusNotFound {
 			t.Errorf("Expected status code %d, got %d", http.StatusNotFound, httpRecorder.Code)
 		}
-		expectedBody := "Character Vegeta not found\n"
-		if httpRecorder.Body.String() != expectedBody {
-			t.Errorf("Expected body %q, got %q", expectedBody, httpRecorder.Body.String())
-		}
+		assertProblem(t, httpRecorder, "DATA_NOT_FOUND", "Character Vegeta not found")
 	})
 
 	t.Run("Update existing character", func(t *testing.T) {
@@ -242,3 +232,282 @@ func Test_UpdateDragonBallCharacter(t *testing.T) {
 		}
 	})
 }
+
+func Test_RunDragonBallTournament(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	processorNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(processorNf)
+	require.NoError(t, err)
+
+	nfCtx := newTestContext(t, nf_context.DragonBallCollection, map[string]int32{
+		"Goku":    7,
+		"Vegeta":  6,
+		"Gohan":   5,
+		"Trunks":  5,
+		"Piccolo": 3,
+	})
+
+	t.Run("Bracket With Byes And Ties", func(t *testing.T) {
+		processorNf.EXPECT().Context().Return(nfCtx).Times(1)
+
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		p.RunDragonBallTournament(ginCtx, []string{"Piccolo", "Trunks", "Gohan", "Vegeta", "Goku"})
+
+		require.Equal(t, http.StatusCreated, httpRecorder.Code, httpRecorder.Body.String())
+		var tournament nf_context.Tournament
+		require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &tournament))
+
+		assert.Equal(t, 1, tournament.ID)
+		// equal power levels are seeded by name
+		seeds := make([]string, 0, len(tournament.Entrants))
+		for _, entrant := range tournament.Entrants {
+			seeds = append(seeds, fmt.Sprintf("%d:%s", entrant.Seed, entrant.Name))
+		}
+		assert.Equal(t, []string{"1:Goku", "2:Vegeta", "3:Gohan", "4:Trunks", "5:Piccolo"}, seeds)
+
+		// 8 slots: 1 v bye, 4 v 5, 2 v bye, 3 v bye
+		require.Len(t, tournament.Rounds, 3)
+		assert.Equal(t, "Quarterfinals", tournament.Rounds[0].Name)
+		first := tournament.Rounds[0].Matches
+		require.Len(t, first, 4)
+		assert.Nil(t, first[0].Fighter2)
+		assert.Equal(t, processor.TournamentDecisionBye, first[0].Decision)
+		assert.Equal(t, "Trunks", first[1].Fighter1.Name)
+		assert.Equal(t, "Piccolo", first[1].Fighter2.Name)
+		assert.Equal(t, "Trunks", first[1].Winner)
+		assert.Equal(t, processor.TournamentDecisionPower, first[1].Decision)
+
+		semifinals := tournament.Rounds[1].Matches
+		require.Len(t, semifinals, 2)
+		assert.Equal(t, "Semifinals", tournament.Rounds[1].Name)
+		assert.Equal(t, "Gohan", semifinals[1].Fighter2.Name)
+		// Vegeta is stronger than Gohan, the tie between Gohan and Trunks never happens here
+		assert.Equal(t, "Vegeta", semifinals[1].Winner)
+
+		final := tournament.Rounds[2]
+		assert.Equal(t, "Final", final.Name)
+		assert.Equal(t, 7, final.Matches[0].Match)
+		assert.Equal(t, "Goku", tournament.Champion.Name)
+
+		stored, ok, err := nfCtx.Tournaments.Get("1")
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, tournament.Rounds, stored.Rounds)
+	})
+
+	t.Run("Tie Goes To The Better Seed", func(t *testing.T) {
+		processorNf.EXPECT().Context().Return(nfCtx).Times(1)
+
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		p.RunDragonBallTournament(ginCtx, []string{"Trunks", "Gohan"})
+
+		require.Equal(t, http.StatusCreated, httpRecorder.Code)
+		var tournament nf_context.Tournament
+		require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &tournament))
+		require.Len(t, tournament.Rounds, 1)
+		match := tournament.Rounds[0].Matches[0]
+		assert.Equal(t, "Gohan", match.Winner)
+		assert.Equal(t, processor.TournamentDecisionSeed, match.Decision)
+	})
+
+	t.Run("All Characters", func(t *testing.T) {
+		processorNf.EXPECT().Context().Return(nfCtx).Times(1)
+
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		p.RunDragonBallTournament(ginCtx, nil)
+
+		require.Equal(t, http.StatusCreated, httpRecorder.Code)
+		var tournament nf_context.Tournament
+		require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &tournament))
+		assert.Len(t, tournament.Entrants, 5)
+		assert.Equal(t, 3, tournament.ID)
+	})
+
+	invalid := []struct {
+		name           string
+		names          []string
+		expectedStatus int
+		expectedCause  string
+		expectedDetail string
+	}{
+		{"Unknown Characters", []string{"Goku", "Frieza", "Cell"}, http.StatusNotFound, "DATA_NOT_FOUND", "[Frieza, Cell] not found in Dragon Ball"},
+		{"Duplicate Character", []string{"Goku", "Goku"}, http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "[Goku] is entered twice"},
+		{"Single Character", []string{"Goku"}, http.StatusBadRequest, "MANDATORY_IE_INCORRECT", "A tournament needs at least 2 characters"},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			processorNf.EXPECT().Context().Return(nfCtx).Times(1)
+
+			httpRecorder := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(httpRecorder)
+			p.RunDragonBallTournament(ginCtx, tc.names)
+
+			assert.Equal(t, tc.expectedStatus, httpRecorder.Code)
+			assertProblem(t, httpRecorder, tc.expectedCause, tc.expectedDetail)
+		})
+	}
+}
+
+func Test_GetDragonBallTournament(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	processorNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(processorNf)
+	require.NoError(t, err)
+
+	nfCtx := newTestContext(t, nf_context.TournamentCollection, map[string]nf_context.Tournament{
+		"1": {ID: 1, Champion: nf_context.TournamentEntrant{Seed: 1, Name: "Goku", PowerLevel: 7}},
+	})
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedStatus int
+	}{
+		{"Stored Tournament", "1", http.StatusOK},
+		{"Unknown Tournament", "2", http.StatusNotFound},
+		{"Invalid ID", "final", http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.expectedStatus != http.StatusBadRequest {
+				processorNf.EXPECT().Context().Return(nfCtx).Times(1)
+			}
+
+			httpRecorder := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(httpRecorder)
+			p.GetDragonBallTournament(ginCtx, tc.id)
+
+			assert.Equal(t, tc.expectedStatus, httpRecorder.Code)
+			if tc.expectedStatus == http.StatusOK {
+				var tournament nf_context.Tournament
+				require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &tournament))
+				assert.Equal(t, "Goku", tournament.Champion.Name)
+			}
+		})
+	}
+}
+
+func Test_DeleteDragonBallCharacter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	processorNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(processorNf)
+	require.NoError(t, err)
+
+	nfCtx := newTestContext(t, nf_context.DragonBallCollection, map[string]int32{"Yamcha": 1})
+	processorNf.EXPECT().Context().Return(nfCtx).Times(2)
+
+	httpRecorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(httpRecorder)
+	p.DeleteDragonBallCharacter(ginCtx, "Yamcha")
+	assert.Equal(t, http.StatusOK, httpRecorder.Code)
+	assert.Equal(t, "Delete Character Yamcha\n", httpRecorder.Body.String())
+
+	httpRecorder = httptest.NewRecorder()
+	ginCtx, _ = gin.CreateTestContext(httpRecorder)
+	p.DeleteDragonBallCharacter(ginCtx, "Yamcha")
+	assert.Equal(t, http.StatusNotFound, httpRecorder.Code)
+	assertProblem(t, httpRecorder, "DATA_NOT_FOUND", "Character Yamcha not found")
+}
+
+func Test_ListDragonBallCharacters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	processorNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(processorNf)
+	require.NoError(t, err)
+
+	nfCtx := newTestContext(t, nf_context.DragonBallCollection, map[string]int32{
+		"Goku":    7,
+		"Vegeta":  6,
+		"Trunks":  5,
+		"Gohan":   5,
+		"Krillin": 2,
+	})
+
+	tests := []struct {
+		name          string
+		query         string
+		expectedNames []string
+	}{
+		{"Default Strongest First", "", []string{"Goku", "Vegeta", "Gohan", "Trunks", "Krillin"}},
+		{"Weakest First", "?sort=powerLevel", []string{"Krillin", "Gohan", "Trunks", "Vegeta", "Goku"}},
+		{"By Name", "?sort=name", []string{"Gohan", "Goku", "Krillin", "Trunks", "Vegeta"}},
+		{"Paged", "?offset=1&limit=2", []string{"Vegeta", "Gohan"}},
+		{"Offset Past The End", "?offset=10", []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			processorNf.EXPECT().Context().Return(nfCtx).Times(1)
+
+			httpRecorder := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(httpRecorder)
+			ginCtx.Request = httptest.NewRequest(http.MethodGet, "/dragonball/characters"+tc.query, nil)
+			p.ListDragonBallCharacters(ginCtx)
+
+			require.Equal(t, http.StatusOK, httpRecorder.Code)
+			assert.Equal(t, "5", httpRecorder.Header().Get("X-Total-Count"))
+			var characters []processor.DragonBallCharacter
+			require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &characters))
+			names := make([]string, 0, len(characters))
+			for _, character := range characters {
+				names = append(names, character.Name)
+			}
+			assert.Equal(t, tc.expectedNames, names)
+		})
+	}
+
+	t.Run("Invalid Sort Field", func(t *testing.T) {
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		ginCtx.Request = httptest.NewRequest(http.MethodGet, "/dragonball/characters?sort=age", nil)
+		p.ListDragonBallCharacters(ginCtx)
+
+		assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+		assertProblem(t, httpRecorder, "INVALID_QUERY_PARAM", `invalid sort field "age"`)
+	})
+}
+
+func Test_GetDragonBallRanking(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	processorNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(processorNf)
+	require.NoError(t, err)
+
+	processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.DragonBallCollection, map[string]int32{
+		"Goku":    7,
+		"Vegeta":  7,
+		"Trunks":  5,
+		"Gohan":   5,
+		"Piccolo": 5,
+		"Krillin": 2,
+	})).Times(1)
+
+	httpRecorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(httpRecorder)
+	ginCtx.Request = httptest.NewRequest(http.MethodGet, "/dragonball/ranking", nil)
+	p.GetDragonBallRanking(ginCtx)
+
+	require.Equal(t, http.StatusOK, httpRecorder.Code)
+	var ranking []processor.DragonBallRank
+	require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &ranking))
+	assert.Equal(t, []processor.DragonBallRank{
+		{Rank: 1, Name: "Goku", PowerLevel: 7},
+		{Rank: 1, Name: "Vegeta", PowerLevel: 7},
+		{Rank: 3, Name: "Gohan", PowerLevel: 5},
+		{Rank: 3, Name: "Piccolo", PowerLevel: 5},
+		{Rank: 3, Name: "Trunks", PowerLevel: 5},
+		{Rank: 6, Name: "Krillin", PowerLevel: 2},
+	}, ranking)
+}
@@ -1,51 +1,335 @@
 package processor
 
 import (
-	"math/rand"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+)
+
+const (
+	FortuneDefaultWeight = 1
+	FortuneMaxWeight     = nf_context.FortuneMaxWeight
 )
 
+// PostFortuneRequest adds or replaces a fortune. A fortune such as "大吉: text" also sets
+// the rank, which defaults to 吉.
 type PostFortuneRequest struct {
-	Fortune string `json:"fortune" binding:"required"`
+	Fortune string                 `json:"fortune" binding:"required"`
+	Rank    nf_context.FortuneRank `json:"rank"`
+	// Weight is the relative chance of drawing the fortune, defaults to 1
+	Weight int `json:"weight"`
+}
+
+type FortuneResponse struct {
+	Message string                 `json:"message"`
+	Fortune string                 `json:"fortune"`
+	ID      int                    `json:"id"`
+	Rank    nf_context.FortuneRank `json:"rank"`
+	Weight  int                    `json:"weight"`
+}
+
+type DailyFortuneResponse struct {
+	FortuneResponse
+	User string `json:"user"`
+	// Date is the UTC day the fortune holds for
+	Date string `json:"date"`
+}
+
+func newFortuneResponse(message string, fortune nf_context.Fortune) FortuneResponse {
+	return FortuneResponse{
+		Message: message,
+		Fortune: fortune.Text,
+		ID:      fortune.ID,
+		Rank:    fortune.Rank,
+		Weight:  fortune.Weight,
+	}
+}
+
+// fortune builds the fortune of the request, the ID is left to the caller.
+func (r PostFortuneRequest) fortune() (nf_context.Fortune, *models.ProblemDetails) {
+	fallback := r.Rank
+	if fallback == "" {
+		fallback = nf_context.FortuneRankKichi
+	}
+	rank, text := nf_context.ParseFortuneText(r.Fortune, fallback)
+	if r.Rank != "" && rank != r.Rank {
+		return nf_context.Fortune{}, util.NewProblemDetails(http.StatusBadRequest, util.CauseMandatoryIeIncorrect,
+			fmt.Sprintf("rank %s does not match the %s prefix of the fortune", r.Rank, rank),
+			models.InvalidParam{Param: "rank"})
+	}
+	if !rank.IsValid() {
+		return nf_context.Fortune{}, util.NewProblemDetails(http.StatusBadRequest, util.CauseMandatoryIeIncorrect,
+			fmt.Sprintf("invalid rank %q", rank),
+			models.InvalidParam{Param: "rank", Reason: "must be one of 大吉, 中吉, 小吉, 吉, 末吉, 凶, 大凶"})
+	}
+	if text == "" {
+		return nf_context.Fortune{}, util.NewProblemDetails(http.StatusBadRequest, util.CauseMandatoryIeIncorrect,
+			"fortune must not be empty", models.InvalidParam{Param: "fortune"})
+	}
+
+	weight := r.Weight
+	if weight == 0 {
+		weight = FortuneDefaultWeight
+	}
+	if weight < 1 || weight > FortuneMaxWeight {
+		return nf_context.Fortune{}, util.NewProblemDetails(http.StatusBadRequest, util.CauseMandatoryIeIncorrect,
+			fmt.Sprintf("weight must be between 1 and %d", FortuneMaxWeight), models.InvalidParam{Param: "weight"})
+	}
+	return nf_context.Fortune{Rank: rank, Text: text, Weight: weight}, nil
+}
+
+// sameFortune compares the texts ignoring case and spacing.
+func sameFortune(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
+
+// fortuneEntries returns the stored fortunes, fortunes stored as plain strings get their key as ID.
+func fortuneEntries(entries []nf_context.StoreEntry[nf_context.Fortune]) []nf_context.Fortune {
+	fortunes := make([]nf_context.Fortune, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Value.ID == 0 {
+			entry.Value.ID, _ = strconv.Atoi(entry.Key)
+		}
+		fortunes = append(fortunes, entry.Value)
+	}
+	return fortunes
+}
+
+func (p *Processor) loadFortunes() ([]nf_context.Fortune, error) {
+	entries, err := p.Context().Fortunes.Entries()
+	if err != nil {
+		return nil, err
+	}
+	return fortuneEntries(entries), nil
+}
+
+// drawFortune picks a fortune with a chance proportional to its weight, intN returns a
+// number in [0, n).
+func drawFortune(fortunes []nf_context.Fortune, intN func(n int) int) nf_context.Fortune {
+	total := 0
+	for _, fortune := range fortunes {
+		total += max(fortune.Weight, 1)
+	}
+	n := intN(total)
+	for _, fortune := range fortunes {
+		if n -= max(fortune.Weight, 1); n < 0 {
+			return fortune
+		}
+	}
+	return fortunes[len(fortunes)-1]
 }
 
 func (p *Processor) GetFortune(c *gin.Context) {
-	ctx := p.Context()
+	fortunes, err := p.loadFortunes()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
 
-	ctx.FortuneMutex.RLock()
-	defer ctx.FortuneMutex.RUnlock()
+	if len(fortunes) == 0 {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, "No fortunes available.")
+		return
+	}
 
-	if len(ctx.Fortunes) == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": "No fortunes available.",
-		})
+	c.JSON(http.StatusOK, newFortuneResponse("Here is your fortune for today!", drawFortune(fortunes, rand.IntN)))
+}
+
+// GetDailyFortune draws the fortune of the user for the current UTC day, the user gets the
+// same fortune all day as long as the fortunes are not changed.
+func (p *Processor) GetDailyFortune(c *gin.Context, user string) {
+	fortunes, err := p.loadFortunes()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+
+	if len(fortunes) == 0 {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, "No fortunes available.")
 		return
 	}
 
-	// Seed the random number generator
-	rand.New(rand.NewSource(time.Now().UnixNano()))
-	// Get a random fortune
-	fortune := ctx.Fortunes[rand.Intn(len(ctx.Fortunes))]
+	date := time.Now().UTC().Format(time.DateOnly)
+	h := fnv.New64a()
+	h.Write([]byte(user))
+	h.Write([]byte{0})
+	h.Write([]byte(date))
+	seed := h.Sum64()
+	r := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Here is your fortune for today!",
-		"fortune": fortune,
+	c.JSON(http.StatusOK, DailyFortuneResponse{
+		FortuneResponse: newFortuneResponse("Here is your fortune for today!", drawFortune(fortunes, r.IntN)),
+		User:            user,
+		Date:            date,
 	})
 }
 
 func (p *Processor) PostFortune(c *gin.Context, req PostFortuneRequest) {
-	ctx := p.Context()
+	fortune, problem := req.fortune()
+	if problem != nil {
+		util.SendProblemDetails(c, problem)
+		return
+	}
 
-	ctx.FortuneMutex.Lock()
-	defer ctx.FortuneMutex.Unlock()
+	err := p.Context().Fortunes.Do(func(tx *nf_context.StoreTx[nf_context.Fortune]) error {
+		entries, err := tx.Entries()
+		if err != nil {
+			return err
+		}
+		for _, stored := range fortuneEntries(entries) {
+			if sameFortune(stored.Text, fortune.Text) {
+				problem = util.NewProblemDetails(http.StatusConflict, util.CauseDataConflict,
+					fmt.Sprintf("Fortune already exists with ID %d", stored.ID))
+				return nil
+			}
+		}
 
-	ctx.Fortunes = append(ctx.Fortunes, req.Fortune)
+		fortune, err = tx.Append(func(seq uint64) nf_context.Fortune {
+			fortune.ID = int(seq)
+			return fortune
+		})
+		return err
+	})
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if problem != nil {
+		util.SendProblemDetails(c, problem)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newFortuneResponse("Fortune added successfully", fortune))
+}
+
+// ListFortunes lists the fortunes by ID. Query parameters:
+//
+//	rank:          only keep the fortunes of the rank
+//	offset, limit: pagination, the total number of matching fortunes is returned in X-Total-Count
+func (p *Processor) ListFortunes(c *gin.Context) {
+	rank := nf_context.FortuneRank(c.Query("rank"))
+	if rank != "" && !rank.IsValid() {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, fmt.Sprintf("invalid rank %q", rank),
+			models.InvalidParam{Param: "rank", Reason: "must be one of 大吉, 中吉, 小吉, 吉, 末吉, 凶, 大凶"})
+		return
+	}
+	offset, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	fortunes, err := p.loadFortunes()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	filtered := make([]nf_context.Fortune, 0, len(fortunes))
+	for _, fortune := range fortunes {
+		if rank == "" || fortune.Rank == rank {
+			filtered = append(filtered, fortune)
+		}
+	}
+
+	c.JSON(http.StatusOK, paginate(c, filtered, offset, limit))
+}
+
+func fortuneKey(c *gin.Context, id string) (string, bool) {
+	seq, err := strconv.Atoi(id)
+	if err != nil || seq <= 0 {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, "Invalid fortune ID")
+		return "", false
+	}
+	return strconv.Itoa(seq), true
+}
+
+func (p *Processor) GetFortuneByID(c *gin.Context, id string) {
+	key, ok := fortuneKey(c, id)
+	if !ok {
+		return
+	}
+
+	fortune, found, err := p.Context().Fortunes.Get(key)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !found {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("Fortune %s not found", key))
+		return
+	}
+	fortune.ID, _ = strconv.Atoi(key)
+	c.JSON(http.StatusOK, fortune)
+}
+
+// UpdateFortune replaces the fortune, its text must stay different from the other fortunes.
+func (p *Processor) UpdateFortune(c *gin.Context, id string, req PostFortuneRequest) {
+	key, ok := fortuneKey(c, id)
+	if !ok {
+		return
+	}
+	fortune, problem := req.fortune()
+	if problem != nil {
+		util.SendProblemDetails(c, problem)
+		return
+	}
+	fortune.ID, _ = strconv.Atoi(key)
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "Fortune added successfully",
-		"fortune": req.Fortune,
+	err := p.Context().Fortunes.Do(func(tx *nf_context.StoreTx[nf_context.Fortune]) error {
+		entries, err := tx.Entries()
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, stored := range fortuneEntries(entries) {
+			if stored.ID == fortune.ID {
+				found = true
+			} else if sameFortune(stored.Text, fortune.Text) {
+				problem = util.NewProblemDetails(http.StatusConflict, util.CauseDataConflict,
+					fmt.Sprintf("Fortune already exists with ID %d", stored.ID))
+				return nil
+			}
+		}
+		if !found {
+			problem = util.NewProblemDetails(http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("Fortune %s not found", key))
+			return nil
+		}
+		return tx.Put(key, fortune)
 	})
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if problem != nil {
+		util.SendProblemDetails(c, problem)
+		return
+	}
+
+	c.JSON(http.StatusOK, newFortuneResponse("Fortune updated successfully", fortune))
+}
+
+func (p *Processor) DeleteFortune(c *gin.Context, id string) {
+	key, ok := fortuneKey(c, id)
+	if !ok {
+		return
+	}
+
+	deleted, err := p.Context().Fortunes.Delete(key)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !deleted {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("Fortune %s not found", key))
+		return
+	}
+	c.Status(http.StatusNoContent)
 }
@@ -4,12 +4,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	nf_context "github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -23,9 +27,7 @@ func Test_PostFortune_AddsFortune(t *testing.T) {
 	p, err := processor.NewProcessor(mockNf)
 	assert.NoError(t, err)
 
-	mockCtx := &nf_context.NFContext{
-		Fortunes: []string{},
-	}
+	mockCtx := newTestListContext(t, nf_context.FortuneCollection, []string{}, nil)
 	mockNf.EXPECT().Context().Return(mockCtx).Times(1)
 
 	rec := httptest.NewRecorder()
@@ -42,8 +44,9 @@ func Test_PostFortune_AddsFortune(t *testing.T) {
 
 	assert.Equal(t, "Fortune added successfully", resp["message"])
 	assert.Equal(t, "Lucky day", resp["fortune"])
-	assert.Len(t, mockCtx.Fortunes, 1)
-	assert.Equal(t, "Lucky day", mockCtx.Fortunes[0])
+	stored, err := storage.LoadAll[nf_context.Fortune](mockCtx.Storage, nf_context.FortuneCollection)
+	assert.NoError(t, err)
+	assert.Equal(t, []nf_context.Fortune{{ID: 1, Rank: nf_context.FortuneRankKichi, Text: "Lucky day", Weight: 1}}, stored)
 }
 
 func Test_GetFortune_ReturnsOneOfFortunes(t *testing.T) {
@@ -57,9 +60,7 @@ func Test_GetFortune_ReturnsOneOfFortunes(t *testing.T) {
 	assert.NoError(t, err)
 
 	fortunes := []string{"f1", "f2", "f3"}
-	mockCtx := &nf_context.NFContext{
-		Fortunes: fortunes,
-	}
+	mockCtx := newTestListContext(t, nf_context.FortuneCollection, fortunes, nil)
 	mockNf.EXPECT().Context().Return(mockCtx).Times(1)
 
 	rec := httptest.NewRecorder()
@@ -97,9 +98,7 @@ func Test_GetFortune_NoFortunes_ReturnsError(t *testing.T) {
 	p, err := processor.NewProcessor(mockNf)
 	assert.NoError(t, err)
 
-	mockCtx := &nf_context.NFContext{
-		Fortunes: []string{},
-	}
+	mockCtx := newTestListContext(t, nf_context.FortuneCollection, []string{}, nil)
 	mockNf.EXPECT().Context().Return(mockCtx).Times(1)
 
 	rec := httptest.NewRecorder()
@@ -107,10 +106,235 @@ func Test_GetFortune_NoFortunes_ReturnsError(t *testing.T) {
 
 	p.GetFortune(ginCtx)
 
-	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assertProblem(t, rec, "DATA_NOT_FOUND", "No fortunes available.")
+}
 
-	var resp map[string]interface{}
-	err = json.Unmarshal(rec.Body.Bytes(), &resp)
-	assert.NoError(t, err)
-	assert.Equal(t, "No fortunes available.", resp["message"])
+func fortuneKey(f nf_context.Fortune) string {
+	return strconv.Itoa(f.ID)
+}
+
+func Test_GetFortune_Weighted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	mockNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(mockNf)
+	require.NoError(t, err)
+
+	mockCtx := newTestListContext(t, nf_context.FortuneCollection, []nf_context.Fortune{
+		{ID: 1, Rank: nf_context.FortuneRankDaikichi, Text: "rare", Weight: 1},
+		{ID: 2, Rank: nf_context.FortuneRankKichi, Text: "common", Weight: 99},
+	}, fortuneKey)
+	const draws = 200
+	mockNf.EXPECT().Context().Return(mockCtx).Times(draws)
+
+	counts := make(map[string]int)
+	for i := 0; i < draws; i++ {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		p.GetFortune(ginCtx)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp processor.FortuneResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		counts[resp.Fortune]++
+	}
+	assert.Greater(t, counts["common"], draws*3/4, "the heavy fortune must dominate the draws: %v", counts)
+}
+
+func Test_GetDailyFortune(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	mockNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(mockNf)
+	require.NoError(t, err)
+
+	fortunes := make([]nf_context.Fortune, 0, 20)
+	for i := 1; i <= 20; i++ {
+		fortunes = append(fortunes, nf_context.Fortune{ID: i, Rank: nf_context.FortuneRankKichi, Text: strconv.Itoa(i), Weight: 1})
+	}
+	mockCtx := newTestListContext(t, nf_context.FortuneCollection, fortunes, fortuneKey)
+	mockNf.EXPECT().Context().Return(mockCtx).AnyTimes()
+
+	draw := func(user string) processor.DailyFortuneResponse {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		p.GetDailyFortune(ginCtx, user)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp processor.DailyFortuneResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	first := draw("Anya")
+	assert.Equal(t, "Anya", first.User)
+	assert.Equal(t, time.Now().UTC().Format(time.DateOnly), first.Date)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, first.ID, draw("Anya").ID, "a user draws the same fortune all day")
+	}
+
+	ids := make(map[int]bool)
+	for _, user := range []string{"Loid", "Yor", "Bond", "Becky", "Damian", "Franky"} {
+		ids[draw(user).ID] = true
+	}
+	assert.Greater(t, len(ids), 1, "users do not all share one fortune")
+}
+
+func Test_PostFortune_Validation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	mockNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(mockNf)
+	require.NoError(t, err)
+
+	// fortunes stored before ranks existed are plain strings
+	mockCtx := newTestListContext(t, nf_context.FortuneCollection, []string{"大吉: All your endeavors will be successful."}, nil)
+	mockNf.EXPECT().Context().Return(mockCtx).AnyTimes()
+
+	t.Run("Rank From Prefix", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		p.PostFortune(ginCtx, processor.PostFortuneRequest{Fortune: "凶: Mind the stairs.", Weight: 3})
+
+		require.Equal(t, http.StatusCreated, rec.Code)
+		var resp processor.FortuneResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, processor.FortuneResponse{
+			Message: "Fortune added successfully",
+			Fortune: "Mind the stairs.",
+			ID:      2,
+			Rank:    nf_context.FortuneRankKyo,
+			Weight:  3,
+		}, resp)
+	})
+
+	tests := []struct {
+		name           string
+		req            processor.PostFortuneRequest
+		expectedStatus int
+		expectedCause  string
+		expectedDetail string
+	}{
+		{
+			name:           "Duplicate Of A Legacy Fortune",
+			req:            processor.PostFortuneRequest{Fortune: "all your  endeavors will be SUCCESSFUL.", Rank: "大吉"},
+			expectedStatus: http.StatusConflict,
+			expectedCause:  "DATA_CONFLICT",
+			expectedDetail: "Fortune already exists with ID 1",
+		},
+		{
+			name:           "Invalid Rank",
+			req:            processor.PostFortuneRequest{Fortune: "Maybe", Rank: "超吉"},
+			expectedStatus: http.StatusBadRequest,
+			expectedCause:  "MANDATORY_IE_INCORRECT",
+			expectedDetail: `invalid rank "超吉"`,
+		},
+		{
+			name:           "Rank Contradicts Prefix",
+			req:            processor.PostFortuneRequest{Fortune: "大吉: Great", Rank: "凶"},
+			expectedStatus: http.StatusBadRequest,
+			expectedCause:  "MANDATORY_IE_INCORRECT",
+			expectedDetail: "rank 凶 does not match the 大吉 prefix of the fortune",
+		},
+		{
+			name:           "Weight Out Of Range",
+			req:            processor.PostFortuneRequest{Fortune: "Heavy", Weight: 101},
+			expectedStatus: http.StatusBadRequest,
+			expectedCause:  "MANDATORY_IE_INCORRECT",
+			expectedDetail: "weight must be between 1 and 100",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(rec)
+			p.PostFortune(ginCtx, tc.req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assertProblem(t, rec, tc.expectedCause, tc.expectedDetail)
+		})
+	}
+}
+
+func Test_FortuneCRUD(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	mockNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(mockNf)
+	require.NoError(t, err)
+
+	mockCtx := newTestListContext(t, nf_context.FortuneCollection, []nf_context.Fortune{
+		{ID: 1, Rank: nf_context.FortuneRankDaikichi, Text: "Great", Weight: 1},
+		{ID: 2, Rank: nf_context.FortuneRankKyo, Text: "Bad", Weight: 2},
+		{ID: 3, Rank: nf_context.FortuneRankDaikichi, Text: "Splendid", Weight: 1},
+	}, fortuneKey)
+	mockNf.EXPECT().Context().Return(mockCtx).AnyTimes()
+
+	t.Run("List By Rank", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		ginCtx.Request = httptest.NewRequest(http.MethodGet, "/fortune/list?rank=大吉&limit=1", nil)
+		p.ListFortunes(ginCtx)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "2", rec.Header().Get("X-Total-Count"))
+		var fortunes []nf_context.Fortune
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &fortunes))
+		assert.Equal(t, []nf_context.Fortune{{ID: 1, Rank: nf_context.FortuneRankDaikichi, Text: "Great", Weight: 1}}, fortunes)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		p.UpdateFortune(ginCtx, "2", processor.PostFortuneRequest{Fortune: "Not that bad", Rank: "末吉", Weight: 5})
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = httptest.NewRecorder()
+		ginCtx, _ = gin.CreateTestContext(rec)
+		p.GetFortuneByID(ginCtx, "2")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var fortune nf_context.Fortune
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &fortune))
+		assert.Equal(t, nf_context.Fortune{ID: 2, Rank: nf_context.FortuneRankSuekichi, Text: "Not that bad", Weight: 5}, fortune)
+	})
+
+	t.Run("Update To A Duplicate", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		p.UpdateFortune(ginCtx, "2", processor.PostFortuneRequest{Fortune: "great"})
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assertProblem(t, rec, "DATA_CONFLICT", "Fortune already exists with ID 1")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		p.DeleteFortune(ginCtx, "3")
+		assert.Equal(t, http.StatusNoContent, ginCtx.Writer.Status())
+
+		rec = httptest.NewRecorder()
+		ginCtx, _ = gin.CreateTestContext(rec)
+		p.GetFortuneByID(ginCtx, "3")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assertProblem(t, rec, "DATA_NOT_FOUND", "Fortune 3 not found")
+	})
+
+	t.Run("Unknown And Invalid IDs", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		p.UpdateFortune(ginCtx, "9", processor.PostFortuneRequest{Fortune: "Nothing"})
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = httptest.NewRecorder()
+		ginCtx, _ = gin.CreateTestContext(rec)
+		p.DeleteFortune(ginCtx, "first")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assertProblem(t, rec, "MANDATORY_IE_INCORRECT", "Invalid fortune ID")
+	})
 }
@@ -0,0 +1,75 @@
+package processor_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/storage"
+
+	"github.com/free5gc/openapi/models"
+)
+
+// newTestContext returns an NFContext whose memory storage holds data in the collection.
+func newTestContext[T any](t *testing.T, collection string, data map[string]T) *nf_context.NFContext {
+	t.Helper()
+	st := storage.NewMemoryStorage()
+	for key, value := range data {
+		if err := storage.Store(st, collection, key, value); err != nil {
+			t.Fatalf("Failed to seed storage: %s", err)
+		}
+	}
+	return newStorageContext(st)
+}
+
+// newTestListContext stores values in order under the key returned by keyOf,
+// or under sequence numbers when keyOf is nil.
+func newTestListContext[T any](t *testing.T, collection string, values []T, keyOf func(T) string) *nf_context.NFContext {
+	t.Helper()
+	st := storage.NewMemoryStorage()
+	for _, value := range values {
+		var key string
+		if keyOf != nil {
+			key = keyOf(value)
+		} else {
+			seq, err := st.NextSequence(collection)
+			if err != nil {
+				t.Fatalf("Failed to seed storage: %s", err)
+			}
+			key = strconv.FormatUint(seq, 10)
+		}
+		if err := storage.Store(st, collection, key, value); err != nil {
+			t.Fatalf("Failed to seed storage: %s", err)
+		}
+	}
+	return newStorageContext(st)
+}
+
+func newStorageContext(st storage.Storage) *nf_context.NFContext {
+	nfCtx := &nf_context.NFContext{}
+	nfCtx.UseStorage(st)
+	return nfCtx
+}
+
+// assertProblem checks the response is an application/problem+json body with the cause and detail.
+func assertProblem(t *testing.T, recorder *httptest.ResponseRecorder, cause string, detail string) {
+	t.Helper()
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/problem+json" {
+		t.Errorf("Expected Content-Type application/problem+json, got %s", contentType)
+	}
+	var problem models.ProblemDetails
+	if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to decode problem details %q: %s", recorder.Body.String(), err)
+	}
+	if int(problem.Status) != recorder.Code {
+		t.Errorf("Expected problem status %d, got %d", recorder.Code, problem.Status)
+	}
+	if problem.Cause != cause {
+		t.Errorf("Expected cause %s, got %s", cause, problem.Cause)
+	}
+	if problem.Detail != detail {
+		t.Errorf("Expected detail %q, got %q", detail, problem.Detail)
+	}
+}
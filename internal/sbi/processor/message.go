@@ -4,29 +4,34 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
 )
 
 func (p *Processor) AddNewMessage(c *gin.Context, newMessage string) {
-	p.Context().MessageMu.Lock()
-	defer p.Context().MessageMu.Unlock()
 	// add message
-	p.Context().MessageRecord = append(p.Context().MessageRecord, newMessage)
+	if _, err := p.Context().MessageRecords.Append(func(uint64) string { return newMessage }); err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
 	c.String(http.StatusOK, "add a new message!")
 }
 
 func (p *Processor) GetMessageRecord(c *gin.Context) {
-	p.Context().MessageMu.Lock()
-	defer p.Context().MessageMu.Unlock()
+	messageRecord, err := p.Context().MessageRecords.All()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
 
 	// no content
-	if len(p.Context().MessageRecord) == 0 {
+	if len(messageRecord) == 0 {
 		c.String(http.StatusOK, "no message now, add some messagess!")
 		return
 	}
 	// get record
 	Record := ""
-	for _, s := range p.Context().MessageRecord {
+	for _, s := range messageRecord {
 		Record += fmt.Sprintf("%s\n", s)
 	}
 	c.String(http.StatusOK, Record)
@@ -26,9 +26,7 @@ func Test_AddNewMessage(t *testing.T) {
 		const EXPECTED_STATUS = 200
 		const EXPECTED_BODY = "add a new message!"
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			MessageRecord: []string{},
-		}).AnyTimes()
+		processorNf.EXPECT().Context().Return(newTestListContext(t, nf_context.MessageRecordCollection, []string{}, nil)).AnyTimes()
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -59,12 +57,10 @@ func Test_GetMessageNotEmpty(t *testing.T) {
 		const EXPECTED_STATUS = 200
 		const EXPECTED_BODY = "ABC\n123\n"
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			MessageRecord: []string{
-				"ABC",
-				"123",
-			},
-		}).AnyTimes()
+		processorNf.EXPECT().Context().Return(newTestListContext(t, nf_context.MessageRecordCollection, []string{
+			"ABC",
+			"123",
+		}, nil)).AnyTimes()
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -94,9 +90,7 @@ func Test_GetMessageEmpty(t *testing.T) {
 		const EXPECTED_STATUS = 200
 		const EXPECTED_BODY = "no message now, add some messagess!"
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			MessageRecord: []string{},
-		}).AnyTimes()
+		processorNf.EXPECT().Context().Return(newTestListContext(t, nf_context.MessageRecordCollection, []string{}, nil)).AnyTimes()
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -1,17 +1,49 @@
 package processor
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
 	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+
+	"github.com/free5gc/openapi/models"
+)
+
+const (
+	MessageDefaultLimit = 50
+	MessageMaxLimit     = 100
 )
 
+// errMessageDeleted aborts a store update of a deleted message.
+var errMessageDeleted = errors.New("message is deleted")
+
 type PostMessageRequest struct {
 	Content string `json:"content" binding:"required"`
 	Author  string `json:"author" binding:"required"`
+	// ParentID makes the message a reply
+	ParentID string `json:"parentId"`
+}
+
+type EditMessageRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// MessageQuery filters the message list, Since and Until bound the message time inclusively.
+type MessageQuery struct {
+	Author         string     `form:"author"`
+	Since          *time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until          *time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	IncludeDeleted bool       `form:"includeDeleted"`
+	// Cursor is the nextCursor of the previous page
+	Cursor string `form:"cursor"`
+	// Limit is the page size, at most MessageMaxLimit
+	Limit int `form:"limit" binding:"omitempty,min=1"`
 }
 
 type PostMessageResponse struct {
@@ -22,19 +54,56 @@ type PostMessageResponse struct {
 type GetMessagesResponse struct {
 	Message string               `json:"message"`
 	Data    []nf_context.Message `json:"data"`
+	// NextCursor is set when more messages match the query
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 func (p *Processor) PostMessage(c *gin.Context, req PostMessageRequest) {
+	nfCtx := p.Context()
+
 	newMessage := nf_context.Message{
 		ID:      uuid.New().String(),
 		Content: req.Content,
 		Author:  req.Author,
 		Time:    time.Now().Format(time.RFC3339),
 	}
+	newMessage.ThreadID = newMessage.ID
 
-	// add message to context
-	ctx := p.Context()
-	ctx.Messages = append(ctx.Messages, newMessage)
+	var problem *models.ProblemDetails
+	err := nfCtx.Messages.Do(func(tx *nf_context.StoreTx[nf_context.Message]) error {
+		if req.ParentID != "" {
+			parent, ok, err := tx.Get(req.ParentID)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				problem = util.NewProblemDetails(http.StatusNotFound, util.CauseDataNotFound, "No parent message found with the specified ID")
+				return nil
+			}
+			if parent.Deleted {
+				problem = util.NewProblemDetails(http.StatusConflict, util.CauseDataConflict, "Cannot reply to a deleted message")
+				return nil
+			}
+			newMessage.ParentID = parent.ID
+			newMessage.ThreadID = parent.Thread()
+		}
+
+		// add message to storage
+		if err := tx.Put(newMessage.ID, newMessage); err != nil {
+			return err
+		}
+		// publish under the store lock, so the stream delivers messages in storage order
+		nfCtx.MessageFeed.Publish(newMessage)
+		return nil
+	})
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if problem != nil {
+		util.SendProblemDetails(c, problem)
+		return
+	}
 
 	// return success response
 	response := PostMessageResponse{
@@ -45,35 +114,204 @@ func (p *Processor) PostMessage(c *gin.Context, req PostMessageRequest) {
 	c.JSON(http.StatusCreated, response)
 }
 
-func (p *Processor) GetMessages(c *gin.Context) {
-	ctx := p.Context()
+// matches reports whether the message passes the filters of q, the cursor is not considered.
+func (q *MessageQuery) matches(m *nf_context.Message) bool {
+	if m.Deleted && !q.IncludeDeleted {
+		return false
+	}
+	if q.Author != "" && m.Author != q.Author {
+		return false
+	}
+	if q.Since == nil && q.Until == nil {
+		return true
+	}
+	postedAt, err := time.Parse(time.RFC3339, m.Time)
+	if err != nil {
+		return false
+	}
+	if q.Since != nil && postedAt.Before(*q.Since) {
+		return false
+	}
+	return q.Until == nil || !postedAt.After(*q.Until)
+}
+
+func encodeMessageCursor(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+func decodeMessageCursor(cursor string) (string, error) {
+	id, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(id) == 0 {
+		return "", fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return string(id), nil
+}
+
+// GetMessages lists the messages in posting order, a page at a time.
+func (p *Processor) GetMessages(c *gin.Context, query MessageQuery) {
+	after := ""
+	if query.Cursor != "" {
+		var err error
+		if after, err = decodeMessageCursor(query.Cursor); err != nil {
+			util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, err.Error(),
+				models.InvalidParam{Param: "cursor", Reason: "must be the nextCursor of a previous page"})
+			return
+		}
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = MessageDefaultLimit
+	}
+
+	messages, err := p.Context().Messages.All()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+
+	start := 0
+	if after != "" {
+		start = -1
+		for i := range messages {
+			if messages[i].ID == after {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, "cursor refers to an unknown message",
+				models.InvalidParam{Param: "cursor", Reason: "must be the nextCursor of a previous page"})
+			return
+		}
+	}
+
+	page := make([]nf_context.Message, 0, min(limit, len(messages)))
+	nextCursor := ""
+	for i := start; i < len(messages); i++ {
+		if !query.matches(&messages[i]) {
+			continue
+		}
+		if len(page) == limit {
+			nextCursor = encodeMessageCursor(page[len(page)-1].ID)
+			break
+		}
+		page = append(page, messages[i].Redacted())
+	}
 
 	response := GetMessagesResponse{
-		Message: "Messages retrieved successfully",
-		Data:    ctx.Messages,
+		Message:    "Messages retrieved successfully",
+		Data:       page,
+		NextCursor: nextCursor,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
 func (p *Processor) GetMessageByID(c *gin.Context, messageID string) {
-	ctx := p.Context()
-
 	// find message with specified ID
-	for _, message := range ctx.Messages {
-		if message.ID == messageID {
-			response := PostMessageResponse{
-				Message: "Message found",
-				Data:    message,
-			}
-			c.JSON(http.StatusOK, response)
-			return
+	message, ok, err := p.Context().Messages.Get(messageID)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if ok {
+		response := PostMessageResponse{
+			Message: "Message found",
+			Data:    message.Redacted(),
 		}
+		c.JSON(http.StatusOK, response)
+		return
 	}
 
 	// if message not found
-	c.JSON(http.StatusNotFound, gin.H{
-		"message": "Message not found",
-		"error":   "No message found with the specified ID",
+	util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, "No message found with the specified ID")
+}
+
+// GetMessageThread returns every message of the thread containing messageID in posting order.
+// Deleted messages stay in the thread without their content.
+func (p *Processor) GetMessageThread(c *gin.Context, messageID string) {
+	messages, err := p.Context().Messages.All()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+
+	threadID := ""
+	for i := range messages {
+		if messages[i].ID == messageID {
+			threadID = messages[i].Thread()
+			break
+		}
+	}
+	if threadID == "" {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, "No message found with the specified ID")
+		return
+	}
+
+	thread := make([]nf_context.Message, 0)
+	for i := range messages {
+		if messages[i].Thread() == threadID {
+			thread = append(thread, messages[i].Redacted())
+		}
+	}
+
+	c.JSON(http.StatusOK, GetMessagesResponse{
+		Message: "Thread retrieved successfully",
+		Data:    thread,
+	})
+}
+
+// EditMessage replaces the content and keeps the previous one in the edit history.
+func (p *Processor) EditMessage(c *gin.Context, messageID string, req EditMessageRequest) {
+	message, found, err := p.Context().Messages.Update(messageID, func(message *nf_context.Message) error {
+		if message.Deleted {
+			return errMessageDeleted
+		}
+		if req.Content != message.Content {
+			now := time.Now().Format(time.RFC3339)
+			message.History = append(message.History, nf_context.MessageEdit{
+				Content:  message.Content,
+				EditedAt: now,
+			})
+			message.Content = req.Content
+			message.EditedAt = now
+		}
+		return nil
 	})
+	switch {
+	case errors.Is(err, errMessageDeleted):
+		util.SendProblem(c, http.StatusConflict, util.CauseDataConflict, "Cannot edit a deleted message")
+		return
+	case err != nil:
+		util.SendSystemFailure(c, err)
+		return
+	case !found:
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, "No message found with the specified ID")
+		return
+	}
+
+	c.JSON(http.StatusOK, PostMessageResponse{
+		Message: "Message edited successfully",
+		Data:    message,
+	})
+}
+
+// DeleteMessage marks the message deleted, deleting it again is a no-op.
+func (p *Processor) DeleteMessage(c *gin.Context, messageID string) {
+	_, found, err := p.Context().Messages.Update(messageID, func(message *nf_context.Message) error {
+		if !message.Deleted {
+			message.Deleted = true
+			message.DeletedAt = time.Now().Format(time.RFC3339)
+		}
+		return nil
+	})
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !found {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, "No message found with the specified ID")
+		return
+	}
+	c.Status(http.StatusNoContent)
 }
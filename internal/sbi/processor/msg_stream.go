@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/util"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// MessageStreamHeartbeat is the interval of the comments keeping idle streams open through proxies.
+var MessageStreamHeartbeat = 15 * time.Second
+
+const messageEventName = "message"
+
+// StreamMessages pushes the messages posted from now on as Server-Sent Events, restricted to
+// authors when not empty. A client sending Last-Event-ID first gets the buffered messages it missed.
+func (p *Processor) StreamMessages(c *gin.Context, authors []string, lastEventID string) {
+	feed := p.Context().MessageFeed
+	if feed == nil {
+		util.SendProblem(c, http.StatusServiceUnavailable, util.CauseSystemFailure, "Message stream is not available")
+		return
+	}
+
+	var resumeFrom uint64
+	if lastEventID != "" {
+		var err error
+		if resumeFrom, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, "Last-Event-ID must be an event ID of this stream")
+			return
+		}
+	}
+
+	var accept func(*nf_context.Message) bool
+	if len(authors) > 0 {
+		accept = func(m *nf_context.Message) bool { return slices.Contains(authors, m.Author) }
+	}
+	sub, backlog, complete := feed.Subscribe(accept, lastEventID != "", resumeFrom)
+	defer feed.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// ask nginx style proxies not to buffer the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if !complete {
+		writeComment(c, "some messages were missed, they are no longer buffered")
+	}
+	for _, event := range backlog {
+		writeMessageEvent(c, event)
+	}
+	writeComment(c, "connected")
+
+	heartbeat := time.NewTicker(MessageStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				// dropped for lagging behind or shutting down, the client reconnects with Last-Event-ID
+				return false
+			}
+			writeMessageEvent(c, event)
+		case <-heartbeat.C:
+			writeComment(c, "heartbeat")
+		}
+		return true
+	})
+}
+
+func writeMessageEvent(c *gin.Context, event nf_context.MessageEvent) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(event.ID, 10),
+		Event: messageEventName,
+		Data:  event.Message,
+	})
+	c.Writer.Flush()
+}
+
+func writeComment(c *gin.Context, comment string) {
+	_, _ = io.WriteString(c.Writer, ": "+comment+"\n\n")
+	c.Writer.Flush()
+}
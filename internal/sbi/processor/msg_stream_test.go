@@ -0,0 +1,128 @@
+package processor_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// readEvent returns the lines of the next event or comment block of the stream.
+func readEvent(t *testing.T, reader *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func Test_StreamMessages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	heartbeat := processor.MessageStreamHeartbeat
+	processor.MessageStreamHeartbeat = 50 * time.Millisecond
+	t.Cleanup(func() { processor.MessageStreamHeartbeat = heartbeat })
+
+	mockCtrl := gomock.NewController(t)
+	processorNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(processorNf)
+	require.NoError(t, err)
+
+	nfCtx := newStorageContext(storage.NewMemoryStorage())
+	nfCtx.MessageFeed = nf_context.NewMessageFeed(nf_context.MessageFeedCapacity)
+	processorNf.EXPECT().Context().Return(nfCtx).AnyTimes()
+
+	router := gin.New()
+	router.GET("/msg/stream", func(c *gin.Context) {
+		p.StreamMessages(c, c.QueryArray("author"), c.GetHeader("Last-Event-ID"))
+	})
+	router.POST("/msg/", func(c *gin.Context) {
+		var req processor.PostMessageRequest
+		require.NoError(t, c.ShouldBindJSON(&req))
+		p.PostMessage(c, req)
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	post := func(author string) {
+		resp, err := http.Post(server.URL+"/msg/", "application/json",
+			strings.NewReader(`{"content":"Hi","author":"`+author+`"}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+	open := func(query string, lastEventID string) (*bufio.Reader, func()) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/msg/stream"+query, nil)
+		require.NoError(t, err)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		return bufio.NewReader(resp.Body), func() { resp.Body.Close() }
+	}
+
+	t.Run("Live With Author Filter", func(t *testing.T) {
+		reader, done := open("?author=Anya", "")
+		defer done()
+		assert.Equal(t, []string{": connected"}, readEvent(t, reader))
+
+		post("Loid")
+		post("Anya")
+		event := readEvent(t, reader)
+		require.Len(t, event, 3)
+		assert.Equal(t, "id:2", event[0])
+		assert.Equal(t, "event:message", event[1])
+		assert.Contains(t, event[2], `"author":"Anya"`)
+
+		// nothing else is posted, so the next block is a heartbeat
+		assert.Equal(t, []string{": heartbeat"}, readEvent(t, reader))
+	})
+
+	t.Run("Resume With Last-Event-ID", func(t *testing.T) {
+		post("Yor")
+		reader, done := open("", "1")
+		defer done()
+
+		assert.Equal(t, "id:2", readEvent(t, reader)[0])
+		assert.Equal(t, "id:3", readEvent(t, reader)[0])
+		assert.Equal(t, []string{": connected"}, readEvent(t, reader))
+	})
+
+	t.Run("Invalid Last-Event-ID", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/msg/stream", nil)
+		require.NoError(t, err)
+		req.Header.Set("Last-Event-ID", "yesterday")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Closed Feed Ends Streams", func(t *testing.T) {
+		reader, done := open("", "")
+		defer done()
+		readEvent(t, reader)
+
+		nfCtx.MessageFeed.Close()
+		_, err := reader.ReadString('\n')
+		assert.Error(t, err)
+	})
+}
@@ -5,6 +5,7 @@ package processor_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http/httptest"
 	"testing"
 	"time"
@@ -49,9 +50,7 @@ func Test_PostMessage(t *testing.T) {
 
 		// Set up mock context with empty messages array
 		// This represents the initial state before any messages are created
-		mockContext := &nf_context.NFContext{
-			Messages: []nf_context.Message{}, // Start with empty message store
-		}
+		mockContext := newTestListContext(t, nf_context.MessageCollection, []nf_context.Message{}, messageID)
 
 		// Set expectation that Context() method will be called exactly once
 		// This ensures the processor accesses the storage context as expected
@@ -130,8 +129,8 @@ func Test_PostMessage(t *testing.T) {
 
 		// Verify that the message was properly stored in the context
 		// This confirms that the data persistence logic works correctly
-		if len(mockContext.Messages) != 1 {
-			t.Errorf("Expected 1 message in context, got %d", len(mockContext.Messages))
+		if stored, _ := mockContext.Storage.Len(nf_context.MessageCollection); stored != 1 {
+			t.Errorf("Expected 1 message in context, got %d", stored)
 		}
 	})
 }
@@ -165,9 +164,7 @@ func Test_GetMessages(t *testing.T) {
 
 		// Set up mock context representing an empty message store
 		// This simulates the system state when no messages have been created
-		mockContext := &nf_context.NFContext{
-			Messages: []nf_context.Message{}, // Empty slice represents no stored messages
-		}
+		mockContext := newTestListContext(t, nf_context.MessageCollection, []nf_context.Message{}, messageID)
 
 		// Set expectation for Context() method call
 		// The processor needs to access the context to retrieve messages
@@ -178,7 +175,7 @@ func Test_GetMessages(t *testing.T) {
 		ginCtx, _ := gin.CreateTestContext(httpRecorder) // Provides Gin context
 
 		// Execute the business logic method for retrieving messages
-		p.GetMessages(ginCtx)
+		p.GetMessages(ginCtx, processor.MessageQuery{})
 
 		// Verify the HTTP response status code
 		// 200 OK indicates successful data retrieval
@@ -231,9 +228,7 @@ func Test_GetMessages(t *testing.T) {
 
 		// Set up mock context with the test data
 		// This represents a populated message store with existing messages
-		mockContext := &nf_context.NFContext{
-			Messages: testMessages, // Pre-populated message slice
-		}
+		mockContext := newTestListContext(t, nf_context.MessageCollection, testMessages, messageID)
 
 		// Set expectation for Context() method access
 		processorNf.EXPECT().Context().Return(mockContext).Times(1)
@@ -243,7 +238,7 @@ func Test_GetMessages(t *testing.T) {
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
 
 		// Execute the message retrieval business logic
-		p.GetMessages(ginCtx)
+		p.GetMessages(ginCtx, processor.MessageQuery{})
 
 		// Verify successful HTTP response
 		if httpRecorder.Code != EXPECTED_STATUS {
@@ -329,9 +324,7 @@ func Test_GetMessageByID(t *testing.T) {
 
 		// Set up mock context with the test messages
 		// This simulates a populated message store containing our target message
-		mockContext := &nf_context.NFContext{
-			Messages: testMessages, // Use the predefined test data
-		}
+		mockContext := newTestListContext(t, nf_context.MessageCollection, testMessages, messageID)
 
 		// Set expectation for Context() method call during message lookup
 		processorNf.EXPECT().Context().Return(mockContext).Times(1)
@@ -383,16 +376,14 @@ func Test_GetMessageByID(t *testing.T) {
 	// This test verifies proper error handling when the requested message ID doesn't exist
 	t.Run("Find Message That Does Not Exist", func(t *testing.T) {
 		// Define test parameters for failed lookup scenario
-		const INPUT_ID = "non-existing-id"                              // ID that doesn't exist in test data
-		const EXPECTED_STATUS = 404                                     // HTTP Not Found status
-		const EXPECTED_MESSAGE = "Message not found"                    // Error message for user
-		const EXPECTED_ERROR = "No message found with the specified ID" // Detailed error description
+		const INPUT_ID = "non-existing-id"                               // ID that doesn't exist in test data
+		const EXPECTED_STATUS = 404                                      // HTTP Not Found status
+		const EXPECTED_CAUSE = "DATA_NOT_FOUND"                          // Stable cause clients branch on
+		const EXPECTED_DETAIL = "No message found with the specified ID" // Detailed error description
 
 		// Set up mock context with the existing test messages
 		// The requested ID will not be found in this dataset
-		mockContext := &nf_context.NFContext{
-			Messages: testMessages, // Same test data, but won't contain the requested ID
-		}
+		mockContext := newTestListContext(t, nf_context.MessageCollection, testMessages, messageID)
 
 		// Set expectation for Context() method call during failed lookup
 		processorNf.EXPECT().Context().Return(mockContext).Times(1)
@@ -410,22 +401,209 @@ func Test_GetMessageByID(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		// Parse the error response to verify proper error formatting
-		var response map[string]interface{}
-		err = json.Unmarshal(httpRecorder.Body.Bytes(), &response)
-		if err != nil {
-			t.Errorf("Failed to unmarshal response: %s", err)
+		// Verify that the error is reported as problem details with an informative detail
+		// This helps with debugging and provides context to API consumers
+		assertProblem(t, httpRecorder, EXPECTED_CAUSE, EXPECTED_DETAIL)
+	})
+}
+
+// Test_MessageThreadLifecycle tests replies, edits and soft deletion on one thread
+// Every step works on the same storage, as consecutive requests would
+func Test_MessageThreadLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	processorNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(processorNf)
+	if err != nil {
+		t.Fatalf("Failed to create processor: %s", err)
+	}
+
+	// The thread starts with a root message, replies point at it directly or through another reply
+	mockContext := newTestListContext(t, nf_context.MessageCollection, []nf_context.Message{
+		{ID: "root", Content: "Who wants peanuts?", Author: "Anya", Time: "2023-01-01T12:00:00Z", ThreadID: "root"},
+		{ID: "other", Content: "Another thread", Author: "Loid", Time: "2023-01-01T12:01:00Z"},
+	}, messageID)
+	processorNf.EXPECT().Context().Return(mockContext).AnyTimes()
+
+	// send runs one processor call and returns the recorder
+	send := func(call func(*gin.Context)) *httptest.ResponseRecorder {
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		call(ginCtx)
+		return httpRecorder
+	}
+	decode := func(t *testing.T, httpRecorder *httptest.ResponseRecorder, v any) {
+		t.Helper()
+		if err := json.Unmarshal(httpRecorder.Body.Bytes(), v); err != nil {
+			t.Fatalf("Failed to unmarshal response %q: %s", httpRecorder.Body.String(), err)
 		}
+	}
 
-		// Verify that the error message is user-friendly and informative
-		if response["message"] != EXPECTED_MESSAGE {
-			t.Errorf("Expected message %s, got %s", EXPECTED_MESSAGE, response["message"])
+	var reply processor.PostMessageResponse
+	t.Run("Reply To Reply", func(t *testing.T) {
+		recorder := send(func(c *gin.Context) {
+			p.PostMessage(c, processor.PostMessageRequest{Content: "Me!", Author: "Bond", ParentID: "root"})
+		})
+		if recorder.Code != 201 {
+			t.Fatalf("Expected status code 201, got %d", recorder.Code)
+		}
+		decode(t, recorder, &reply)
+
+		recorder = send(func(c *gin.Context) {
+			p.PostMessage(c, processor.PostMessageRequest{Content: "Not you", Author: "Yor", ParentID: reply.Data.ID})
+		})
+		var nested processor.PostMessageResponse
+		decode(t, recorder, &nested)
+		if nested.Data.ParentID != reply.Data.ID || nested.Data.ThreadID != "root" {
+			t.Errorf("Expected parent %s in thread root, got parent %s in thread %s",
+				reply.Data.ID, nested.Data.ParentID, nested.Data.ThreadID)
 		}
+	})
 
-		// Verify that detailed error information is provided
-		// This helps with debugging and provides context to API consumers
-		if response["error"] != EXPECTED_ERROR {
-			t.Errorf("Expected error %s, got %s", EXPECTED_ERROR, response["error"])
+	t.Run("Reply To Unknown Parent", func(t *testing.T) {
+		recorder := send(func(c *gin.Context) {
+			p.PostMessage(c, processor.PostMessageRequest{Content: "Hello?", Author: "Anya", ParentID: "missing"})
+		})
+		if recorder.Code != 404 {
+			t.Errorf("Expected status code 404, got %d", recorder.Code)
+		}
+		assertProblem(t, recorder, "DATA_NOT_FOUND", "No parent message found with the specified ID")
+	})
+
+	t.Run("Edit Keeps History", func(t *testing.T) {
+		for _, content := range []string{"Who wants nuts?", "Who wants peanuts and nuts?"} {
+			recorder := send(func(c *gin.Context) {
+				p.EditMessage(c, "root", processor.EditMessageRequest{Content: content})
+			})
+			if recorder.Code != 200 {
+				t.Fatalf("Expected status code 200, got %d", recorder.Code)
+			}
+		}
+
+		var response processor.PostMessageResponse
+		decode(t, send(func(c *gin.Context) { p.GetMessageByID(c, "root") }), &response)
+		if response.Data.Content != "Who wants peanuts and nuts?" || response.Data.EditedAt == "" {
+			t.Errorf("Expected edited content with editedAt, got %+v", response.Data)
+		}
+		if len(response.Data.History) != 2 || response.Data.History[0].Content != "Who wants peanuts?" {
+			t.Errorf("Expected the two previous contents in history, got %+v", response.Data.History)
 		}
 	})
+
+	t.Run("Soft Delete", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			p.DeleteMessage(ginCtx, "root")
+			// no body is written, so the status is only set on the writer
+			if status := ginCtx.Writer.Status(); status != 204 {
+				t.Fatalf("Expected status code 204, got %d", status)
+			}
+		}
+
+		// The deleted root stays in the thread without its content
+		var thread processor.GetMessagesResponse
+		decode(t, send(func(c *gin.Context) { p.GetMessageThread(c, reply.Data.ID) }), &thread)
+		if len(thread.Data) != 3 {
+			t.Fatalf("Expected 3 messages in the thread, got %d", len(thread.Data))
+		}
+		if root := thread.Data[0]; !root.Deleted || root.Content != "" || root.History != nil {
+			t.Errorf("Expected a redacted deleted root, got %+v", root)
+		}
+
+		// Deleted messages are hidden from the list unless asked for
+		var list processor.GetMessagesResponse
+		decode(t, send(func(c *gin.Context) { p.GetMessages(c, processor.MessageQuery{}) }), &list)
+		if len(list.Data) != 3 {
+			t.Errorf("Expected 3 messages without the deleted one, got %d", len(list.Data))
+		}
+
+		recorder := send(func(c *gin.Context) {
+			p.EditMessage(c, "root", processor.EditMessageRequest{Content: "Back"})
+		})
+		assertProblem(t, recorder, "DATA_CONFLICT", "Cannot edit a deleted message")
+		recorder = send(func(c *gin.Context) {
+			p.PostMessage(c, processor.PostMessageRequest{Content: "Hi", Author: "Anya", ParentID: "root"})
+		})
+		assertProblem(t, recorder, "DATA_CONFLICT", "Cannot reply to a deleted message")
+	})
+}
+
+// Test_GetMessagesFilterAndPagination tests author and time range filters combined with cursor pages
+func Test_GetMessagesFilterAndPagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	processorNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(processorNf)
+	if err != nil {
+		t.Fatalf("Failed to create processor: %s", err)
+	}
+
+	messages := []nf_context.Message{
+		{ID: "m1", Content: "1", Author: "Anya", Time: "2023-01-01T12:00:00Z"},
+		{ID: "m2", Content: "2", Author: "Loid", Time: "2023-01-01T13:00:00Z"},
+		{ID: "m3", Content: "3", Author: "Anya", Time: "2023-01-01T14:00:00Z"},
+		{ID: "m4", Content: "4", Author: "Anya", Time: "2023-01-01T15:00:00Z", Deleted: true},
+		{ID: "m5", Content: "5", Author: "Anya", Time: "2023-01-01T16:00:00Z"},
+		{ID: "m6", Content: "6", Author: "Anya", Time: "2023-01-01T17:00:00Z"},
+	}
+	processorNf.EXPECT().Context().Return(newTestListContext(t, nf_context.MessageCollection, messages, messageID)).AnyTimes()
+
+	list := func(t *testing.T, query processor.MessageQuery) processor.GetMessagesResponse {
+		t.Helper()
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		p.GetMessages(ginCtx, query)
+		if httpRecorder.Code != 200 {
+			t.Fatalf("Expected status code 200, got %d: %s", httpRecorder.Code, httpRecorder.Body.String())
+		}
+		var response processor.GetMessagesResponse
+		if err := json.Unmarshal(httpRecorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %s", err)
+		}
+		return response
+	}
+	ids := func(response processor.GetMessagesResponse) []string {
+		result := make([]string, 0, len(response.Data))
+		for _, m := range response.Data {
+			result = append(result, m.ID)
+		}
+		return result
+	}
+
+	since := time.Date(2023, 1, 1, 13, 0, 0, 0, time.UTC)
+	until := time.Date(2023, 1, 1, 16, 0, 0, 0, time.UTC)
+	query := processor.MessageQuery{Author: "Anya", Since: &since, Limit: 2}
+
+	// Walk the pages until there is no next cursor
+	var pages [][]string
+	for {
+		response := list(t, query)
+		pages = append(pages, ids(response))
+		if response.NextCursor == "" {
+			break
+		}
+		query.Cursor = response.NextCursor
+	}
+	if fmt.Sprint(pages) != "[[m3 m5] [m6]]" {
+		t.Errorf("Expected pages [[m3 m5] [m6]], got %v", pages)
+	}
+
+	response := list(t, processor.MessageQuery{Since: &since, Until: &until, IncludeDeleted: true})
+	if got := fmt.Sprint(ids(response)); got != "[m2 m3 m4 m5]" {
+		t.Errorf("Expected [m2 m3 m4 m5] in the time range, got %s", got)
+	}
+	if response.Data[2].Content != "" {
+		t.Errorf("Expected the deleted message to be redacted, got %q", response.Data[2].Content)
+	}
+
+	httpRecorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(httpRecorder)
+	p.GetMessages(ginCtx, processor.MessageQuery{Cursor: "not a cursor"})
+	assertProblem(t, httpRecorder, "INVALID_QUERY_PARAM", `invalid cursor "not a cursor"`)
+}
+
+func messageID(m nf_context.Message) string {
+	return m.ID
 }
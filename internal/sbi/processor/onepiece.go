@@ -0,0 +1,98 @@
+package processor
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+// OnePieceCrewResponse lists the crew by bounty, the highest first, with the bounty total.
+type OnePieceCrewResponse struct {
+	Members     []nf_context.CrewMember `json:"members"`
+	Count       int                     `json:"count"`
+	TotalBounty int64                   `json:"totalBounty"`
+}
+
+func crewKey(name string) string {
+	return strings.ToLower(name)
+}
+
+func crewMemberNotFound(c *gin.Context, name string) {
+	util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("%s is not in the Straw Hat crew", name))
+}
+
+func (p *Processor) RecruitOnePieceCrewMember(c *gin.Context, name string, role string, bounty int64) {
+	member := nf_context.CrewMember{
+		Name:     name,
+		Role:     role,
+		Bounty:   bounty,
+		JoinedAt: time.Now().UTC(),
+	}
+	existing, inserted, err := p.Context().Crew.Insert(crewKey(name), member)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !inserted {
+		util.SendProblem(c, http.StatusConflict, util.CauseDataConflict,
+			fmt.Sprintf("%s is already in the Straw Hat crew", existing.Name))
+		return
+	}
+
+	c.JSON(http.StatusCreated, fmt.Sprintf("%s has joined the Straw Hat crew!", name))
+}
+
+func (p *Processor) ListOnePieceCrew(c *gin.Context) {
+	members, err := p.Context().Crew.All()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		if members[i].Bounty != members[j].Bounty {
+			return members[i].Bounty > members[j].Bounty
+		}
+		return members[i].Name < members[j].Name
+	})
+	response := OnePieceCrewResponse{Members: members, Count: len(members)}
+	for _, member := range members {
+		response.TotalBounty += member.Bounty
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (p *Processor) GetOnePieceCrewMember(c *gin.Context, name string) {
+	member, found, err := p.Context().Crew.Get(crewKey(name))
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !found {
+		crewMemberNotFound(c, name)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (p *Processor) DeleteOnePieceCrewMember(c *gin.Context, name string) {
+	deleted, err := p.Context().Crew.Delete(crewKey(name))
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !deleted {
+		crewMemberNotFound(c, name)
+		return
+	}
+
+	c.JSON(http.StatusOK, fmt.Sprintf("%s has left the Straw Hat crew!", name))
+}
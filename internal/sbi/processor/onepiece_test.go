@@ -7,9 +7,15 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/sbi"
+	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/storage"
 	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/free5gc/openapi/models"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
@@ -23,6 +29,12 @@ func newTestServer(t *testing.T) *sbi.Server {
 			Sbi: &factory.Sbi{Port: 8000},
 		},
 	}).AnyTimes()
+	nfApp.EXPECT().Context().Return(newStorageContext(storage.NewMemoryStorage())).AnyTimes()
+	p, err := processor.NewProcessor(nfApp)
+	if err != nil {
+		t.Fatalf("create processor: %v", err)
+	}
+	nfApp.EXPECT().Processor().Return(p).AnyTimes()
 	return sbi.NewServer(nfApp, "")
 }
 
@@ -99,13 +111,119 @@ func Test_HTTPOnePieceRecruit(t *testing.T) {
 			t.Fatalf("unexpected status: got %d want %d", recorder.Code, http.StatusBadRequest)
 		}
 
-		var payload map[string]string
-		if err = json.Unmarshal(recorder.Body.Bytes(), &payload); err != nil {
-			t.Fatalf("decode error response: %v", err)
+		assertProblem(t, recorder, "MANDATORY_IE_MISSING", "name is required")
+	})
+
+	malformed := []struct {
+		name string
+		body string
+	}{
+		{"MalformedJSON", `{"name":`},
+		{"BountyTypeMismatch", `{"name":"Usopp","bounty":"lots"}`},
+	}
+	for _, tc := range malformed {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			req, err := http.NewRequest(http.MethodPost, "/onepiece/crew", bytes.NewBufferString(tc.body))
+			if err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			ctx.Request = req
+
+			server.HTTPOnePieceRecruit(ctx)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("unexpected status: got %d want %d", recorder.Code, http.StatusBadRequest)
+			}
+			var problem models.ProblemDetails
+			if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("decode problem: %v", err)
+			}
+			if problem.Cause != "INVALID_MSG_FORMAT" {
+				t.Fatalf("unexpected cause: got %q want INVALID_MSG_FORMAT", problem.Cause)
+			}
+		})
+	}
+}
+
+func Test_OnePieceCrew(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	processorNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(processorNf)
+	require.NoError(t, err)
+	processorNf.EXPECT().Context().Return(newStorageContext(storage.NewMemoryStorage())).AnyTimes()
+
+	call := func(handler func(c *gin.Context)) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		handler(ctx)
+		return recorder
+	}
+
+	t.Run("Recruit", func(t *testing.T) {
+		recruits := []struct {
+			name   string
+			role   string
+			bounty int64
+		}{
+			{"Luffy", "Captain", 3000000000},
+			{"Zoro", "Swordsman", 1111000000},
+			{"Chopper", "Doctor", 1000},
+			{"Vivi", "", 0},
+		}
+		for _, recruit := range recruits {
+			recorder := call(func(c *gin.Context) { p.RecruitOnePieceCrewMember(c, recruit.name, recruit.role, recruit.bounty) })
+			require.Equal(t, http.StatusCreated, recorder.Code)
 		}
-		const expected = "name is required"
-		if payload["error"] != expected {
-			t.Fatalf("unexpected error message: got %q want %q", payload["error"], expected)
+
+		recorder := call(func(c *gin.Context) { p.RecruitOnePieceCrewMember(c, "ZORO", "Navigator", 0) })
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assertProblem(t, recorder, "DATA_CONFLICT", "Zoro is already in the Straw Hat crew")
+	})
+
+	t.Run("List", func(t *testing.T) {
+		recorder := call(p.ListOnePieceCrew)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var crew processor.OnePieceCrewResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &crew))
+		assert.Equal(t, 4, crew.Count)
+		assert.Equal(t, int64(4111001000), crew.TotalBounty)
+		names := make([]string, 0, len(crew.Members))
+		for _, member := range crew.Members {
+			names = append(names, member.Name)
 		}
+		assert.Equal(t, []string{"Luffy", "Zoro", "Chopper", "Vivi"}, names)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		recorder := call(func(c *gin.Context) { p.GetOnePieceCrewMember(c, "zoro") })
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var member nf_context.CrewMember
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &member))
+		assert.Equal(t, "Zoro", member.Name)
+		assert.Equal(t, "Swordsman", member.Role)
+		assert.Equal(t, int64(1111000000), member.Bounty)
+		assert.False(t, member.JoinedAt.IsZero())
+
+		recorder = call(func(c *gin.Context) { p.GetOnePieceCrewMember(c, "Buggy") })
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assertProblem(t, recorder, "DATA_NOT_FOUND", "Buggy is not in the Straw Hat crew")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		recorder := call(func(c *gin.Context) { p.DeleteOnePieceCrewMember(c, "Vivi") })
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `"Vivi has left the Straw Hat crew!"`, recorder.Body.String())
+
+		recorder = call(func(c *gin.Context) { p.DeleteOnePieceCrewMember(c, "Vivi") })
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+		// a member who left can join again
+		recorder = call(func(c *gin.Context) { p.RecruitOnePieceCrewMember(c, "Vivi", "Princess", 0) })
+		assert.Equal(t, http.StatusCreated, recorder.Code)
 	})
 }
@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Alonza0314/nf-example/internal/util"
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+)
+
+// pageQuery reads the offset and limit query parameters, a zero limit keeps every item.
+// It answers the problem itself when a value is invalid.
+func pageQuery(c *gin.Context) (offset int, limit int, ok bool) {
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, "offset must be a non-negative integer",
+			models.InvalidParam{Param: "offset", Reason: "must be a non-negative integer"})
+		return 0, 0, false
+	}
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, "limit must be a positive integer",
+				models.InvalidParam{Param: "limit", Reason: "must be a positive integer"})
+			return 0, 0, false
+		}
+	}
+	return offset, limit, true
+}
+
+// paginate returns the page of items and reports their total number in X-Total-Count.
+func paginate[T any](c *gin.Context, items []T, offset int, limit int) []T {
+	c.Header("X-Total-Count", strconv.Itoa(len(items)))
+	if offset > len(items) {
+		offset = len(items)
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
@@ -3,14 +3,190 @@ package processor
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
 )
 
+// SpyFamilyCharacterRequest adds or replaces a character. The first name is the key, an update
+// takes it from the path and refuses a different one in the body.
+type SpyFamilyCharacterRequest struct {
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName" binding:"required"`
+	Family      string `json:"family"`
+	Affiliation string `json:"affiliation"`
+	Codename    string `json:"codename"`
+}
+
+// SpyFamilyCharacterResponse is a character with its full name.
+type SpyFamilyCharacterResponse struct {
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	FullName    string `json:"fullName"`
+	Family      string `json:"family"`
+	Affiliation string `json:"affiliation,omitempty"`
+	Codename    string `json:"codename,omitempty"`
+}
+
+func newSpyFamilyCharacterResponse(character nf_context.SpyFamilyCharacter) SpyFamilyCharacterResponse {
+	return SpyFamilyCharacterResponse{
+		FirstName:   character.FirstName,
+		LastName:    character.LastName,
+		FullName:    character.FullName(),
+		Family:      character.Family,
+		Affiliation: character.Affiliation,
+		Codename:    character.Codename,
+	}
+}
+
+func (r SpyFamilyCharacterRequest) character(firstName string) nf_context.SpyFamilyCharacter {
+	character := nf_context.SpyFamilyCharacter{
+		FirstName:   firstName,
+		LastName:    strings.TrimSpace(r.LastName),
+		Family:      strings.TrimSpace(r.Family),
+		Affiliation: strings.TrimSpace(r.Affiliation),
+		Codename:    strings.TrimSpace(r.Codename),
+	}
+	if character.Family == "" {
+		character.Family = character.LastName
+	}
+	return character
+}
+
+func spyFamilyCharacterNotFound(c *gin.Context, firstName string) {
+	util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("[%s] not found in SPYxFAMILY", firstName))
+}
+
 func (p *Processor) FindSpyFamilyCharacterName(c *gin.Context, targetName string) {
-	if lastName, ok := p.Context().SpyFamilyData[targetName]; ok {
-		c.String(http.StatusOK, fmt.Sprintf("Character: %s %s", targetName, lastName))
+	character, ok, err := p.Context().SpyFamily.Get(targetName)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if ok {
+		character.FirstName = targetName
+		c.JSON(http.StatusOK, newSpyFamilyCharacterResponse(character))
+		return
+	}
+	spyFamilyCharacterNotFound(c, targetName)
+}
+
+// ListSpyFamilyCharacters lists the characters by first name. Query parameters:
+//
+//	family:        only keep the characters of the family
+//	affiliation:   only keep the characters of the affiliation
+//	q:             only keep the characters whose full name or codename contains the text
+//	offset, limit: pagination, the total number of matching characters is returned in X-Total-Count
+//
+// The filters ignore case.
+func (p *Processor) ListSpyFamilyCharacters(c *gin.Context) {
+	offset, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	family := c.Query("family")
+	affiliation := c.Query("affiliation")
+	q := strings.ToLower(c.Query("q"))
+
+	entries, err := p.Context().SpyFamily.Entries()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	characters := make([]SpyFamilyCharacterResponse, 0, len(entries))
+	for _, entry := range entries {
+		character := entry.Value
+		character.FirstName = entry.Key
+		if family != "" && !strings.EqualFold(character.Family, family) {
+			continue
+		}
+		if affiliation != "" && !strings.EqualFold(character.Affiliation, affiliation) {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(character.FullName()), q) &&
+			!strings.Contains(strings.ToLower(character.Codename), q) {
+			continue
+		}
+		characters = append(characters, newSpyFamilyCharacterResponse(character))
+	}
+	sort.Slice(characters, func(i, j int) bool {
+		return characters[i].FirstName < characters[j].FirstName
+	})
+
+	c.JSON(http.StatusOK, paginate(c, characters, offset, limit))
+}
+
+func (p *Processor) AddSpyFamilyCharacter(c *gin.Context, req SpyFamilyCharacterRequest) {
+	character := req.character(strings.TrimSpace(req.FirstName))
+	if character.FirstName == "" {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No firstName provided",
+			models.InvalidParam{Param: "firstName"})
+		return
+	}
+	if character.LastName == "" {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No lastName provided",
+			models.InvalidParam{Param: "lastName"})
+		return
+	}
+
+	_, inserted, err := p.Context().SpyFamily.Insert(character.FirstName, character)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !inserted {
+		util.SendProblem(c, http.StatusConflict, util.CauseDataConflict,
+			fmt.Sprintf("[%s] already exists in SPYxFAMILY", character.FirstName))
+		return
+	}
+
+	c.JSON(http.StatusCreated, newSpyFamilyCharacterResponse(character))
+}
+
+func (p *Processor) UpdateSpyFamilyCharacter(c *gin.Context, firstName string, req SpyFamilyCharacterRequest) {
+	if name := strings.TrimSpace(req.FirstName); name != "" && name != firstName {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect,
+			fmt.Sprintf("firstName %s does not match the character %s", name, firstName),
+			models.InvalidParam{Param: "firstName"})
+		return
+	}
+	character := req.character(firstName)
+	if character.LastName == "" {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeMissing, "No lastName provided",
+			models.InvalidParam{Param: "lastName"})
+		return
+	}
+
+	_, found, err := p.Context().SpyFamily.Update(firstName, func(stored *nf_context.SpyFamilyCharacter) error {
+		*stored = character
+		return nil
+	})
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !found {
+		spyFamilyCharacterNotFound(c, firstName)
+		return
+	}
+
+	c.JSON(http.StatusOK, newSpyFamilyCharacterResponse(character))
+}
+
+func (p *Processor) DeleteSpyFamilyCharacter(c *gin.Context, firstName string) {
+	deleted, err := p.Context().SpyFamily.Delete(firstName)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !deleted {
+		spyFamilyCharacterNotFound(c, firstName)
 		return
 	}
-	c.String(http.StatusNotFound, fmt.Sprintf("[%s] not found in SPYxFAMILY", targetName))
+	c.Status(http.StatusNoContent)
 }
@@ -1,12 +1,17 @@
 package processor_test
 
 import (
+	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	nf_context "github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/storage"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -24,13 +29,11 @@ func Test_FindSpyFamilyCharacterName(t *testing.T) {
 	t.Run("Find Character That Exists", func(t *testing.T) {
 		const INPUT_NAME = "Anya"
 		const EXPECTED_STATUS = 200
-		const EXPECTED_BODY = "Character: " + INPUT_NAME + " Forger"
+		const EXPECTED_BODY = `{"firstName":"Anya","lastName":"Forger","fullName":"Anya Forger","family":"Forger"}`
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			SpyFamilyData: map[string]string{
-				"Anya": "Forger",
-			},
-		})
+		processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.SpyFamilyCollection, map[string]string{
+			"Anya": "Forger",
+		}))
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -48,13 +51,11 @@ func Test_FindSpyFamilyCharacterName(t *testing.T) {
 	t.Run("Find Character That Does Not Exist", func(t *testing.T) {
 		const INPUT_NAME = "Andy"
 		const EXPECTED_STATUS = 404
-		const EXPECTED_BODY = "[" + INPUT_NAME + "] not found in SPYxFAMILY"
+		const EXPECTED_DETAIL = "[" + INPUT_NAME + "] not found in SPYxFAMILY"
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			SpyFamilyData: map[string]string{
-				"Anya": "Forger",
-			},
-		})
+		processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.SpyFamilyCollection, map[string]string{
+			"Anya": "Forger",
+		}))
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -64,8 +65,128 @@ func Test_FindSpyFamilyCharacterName(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
+		assertProblem(t, httpRecorder, "DATA_NOT_FOUND", EXPECTED_DETAIL)
+	})
+}
+
+func Test_SpyFamilyDirectory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	processorNf := processor.NewMockProcessorNf(mockCtrl)
+	p, err := processor.NewProcessor(processorNf)
+	require.NoError(t, err)
+	nfCtx := newStorageContext(storage.NewMemoryStorage())
+	require.NoError(t, nfCtx.Seed())
+	processorNf.EXPECT().Context().Return(nfCtx).AnyTimes()
+
+	list := func(t *testing.T, target string) []processor.SpyFamilyCharacterResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		ginCtx.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		p.ListSpyFamilyCharacters(ginCtx)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var characters []processor.SpyFamilyCharacterResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &characters))
+		return characters
+	}
+	firstNames := func(characters []processor.SpyFamilyCharacterResponse) []string {
+		names := make([]string, 0, len(characters))
+		for _, character := range characters {
+			names = append(names, character.FirstName)
 		}
+		return names
+	}
+
+	t.Run("Search", func(t *testing.T) {
+		assert.Len(t, list(t, "/spyfamily/characters"), 15, "the seed data is stored")
+		assert.Equal(t, []string{"Anya", "Bond", "Loid", "Yor"}, firstNames(list(t, "/spyfamily/characters?family=forger")))
+		assert.Equal(t, []string{"Fiona", "Franky", "Loid", "Sylvia"}, firstNames(list(t, "/spyfamily/characters?affiliation=WISE")))
+		assert.Equal(t, []string{"Fiona"}, firstNames(list(t, "/spyfamily/characters?q=night")))
+		assert.Equal(t, []string{"Yor"}, firstNames(list(t, "/spyfamily/characters?q=yor%20forger")))
+
+		characters := list(t, "/spyfamily/characters?q=twilight")
+		require.Len(t, characters, 1)
+		assert.Equal(t, processor.SpyFamilyCharacterResponse{
+			FirstName:   "Loid",
+			LastName:    "Forger",
+			FullName:    "Loid Forger",
+			Family:      "Forger",
+			Affiliation: "WISE",
+			Codename:    "Twilight",
+		}, characters[0])
+	})
+
+	t.Run("Add", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		p.AddSpyFamilyCharacter(ginCtx, processor.SpyFamilyCharacterRequest{
+			FirstName: "Donovan", LastName: "Desmond", Affiliation: "National Unity Party",
+		})
+		require.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, []string{"Damian", "Donovan"}, firstNames(list(t, "/spyfamily/characters?family=Desmond")))
+
+		rec = httptest.NewRecorder()
+		ginCtx, _ = gin.CreateTestContext(rec)
+		p.AddSpyFamilyCharacter(ginCtx, processor.SpyFamilyCharacterRequest{FirstName: "Anya", LastName: "Desmond"})
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assertProblem(t, rec, "DATA_CONFLICT", "[Anya] already exists in SPYxFAMILY")
+
+		rec = httptest.NewRecorder()
+		ginCtx, _ = gin.CreateTestContext(rec)
+		p.AddSpyFamilyCharacter(ginCtx, processor.SpyFamilyCharacterRequest{LastName: "Nobody"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assertProblem(t, rec, "MANDATORY_IE_MISSING", "No firstName provided")
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		p.UpdateSpyFamilyCharacter(ginCtx, "Yuri", processor.SpyFamilyCharacterRequest{
+			LastName: "Briar", Affiliation: "SSS", Codename: "Lieutenant",
+		})
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"Yuri"}, firstNames(list(t, "/spyfamily/characters?affiliation=sss")))
+
+		rec = httptest.NewRecorder()
+		ginCtx, _ = gin.CreateTestContext(rec)
+		p.UpdateSpyFamilyCharacter(ginCtx, "Yuri", processor.SpyFamilyCharacterRequest{FirstName: "Yor", LastName: "Briar"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assertProblem(t, rec, "MANDATORY_IE_INCORRECT", "firstName Yor does not match the character Yuri")
+
+		rec = httptest.NewRecorder()
+		ginCtx, _ = gin.CreateTestContext(rec)
+		p.UpdateSpyFamilyCharacter(ginCtx, "Andy", processor.SpyFamilyCharacterRequest{LastName: "Anderson"})
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assertProblem(t, rec, "DATA_NOT_FOUND", "[Andy] not found in SPYxFAMILY")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		p.DeleteSpyFamilyCharacter(ginCtx, "Donovan")
+		assert.Equal(t, http.StatusNoContent, ginCtx.Writer.Status())
+
+		rec = httptest.NewRecorder()
+		ginCtx, _ = gin.CreateTestContext(rec)
+		p.DeleteSpyFamilyCharacter(ginCtx, "Donovan")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Legacy Last Names", func(t *testing.T) {
+		processorNf := processor.NewMockProcessorNf(mockCtrl)
+		p, err := processor.NewProcessor(processorNf)
+		require.NoError(t, err)
+		processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.SpyFamilyCollection, map[string]string{
+			"Anya": "Forger",
+		}))
+
+		rec := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(rec)
+		ginCtx.Request = httptest.NewRequest(http.MethodGet, "/spyfamily/characters", nil)
+		p.ListSpyFamilyCharacters(ginCtx)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `[{"firstName":"Anya","lastName":"Forger","fullName":"Anya Forger","family":"Forger"}]`, rec.Body.String())
 	})
 }
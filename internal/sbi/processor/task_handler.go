@@ -1,35 +1,311 @@
 package processor
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
-	"sync/atomic"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+)
+
+const (
+	TaskMinPriority = 0
+	TaskMaxPriority = 5
 )
 
+// TaskRequest is the body of task creation and full replacement.
+type TaskRequest struct {
+	Name        string             `json:"name" binding:"required"`
+	Description string             `json:"description"`
+	Status      context.TaskStatus `json:"status"`
+	Priority    int                `json:"priority"`
+	DueDate     *time.Time         `json:"dueDate"`
+}
+
+// TaskPatchRequest only updates the fields present in the body.
+type TaskPatchRequest struct {
+	Name        *string             `json:"name"`
+	Description *string             `json:"description"`
+	Status      *context.TaskStatus `json:"status"`
+	Priority    *int                `json:"priority"`
+	// DueDate is cleared by an explicit null
+	DueDate NullableTime `json:"dueDate"`
+}
+
+// NullableTime tells a field absent from a patch body from a field set to null.
+type NullableTime struct {
+	// Set is true when the field is in the body
+	Set bool
+	// Time is nil when the field is null
+	Time *time.Time
+}
+
+func (n *NullableTime) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Time = nil
+		return nil
+	}
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	n.Time = &t
+	return nil
+}
+
+func (NullableTime) OpenAPISchema() map[string]any {
+	return map[string]any{"type": "string", "format": "date-time", "nullable": true}
+}
+
+func validateTask(task *context.Task) error {
+	if strings.TrimSpace(task.Name) == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	if !task.Status.IsValid() {
+		return fmt.Errorf("invalid status %q, must be one of todo, doing, done", task.Status)
+	}
+	if task.Priority < TaskMinPriority || task.Priority > TaskMaxPriority {
+		return fmt.Errorf("priority must be between %d and %d", TaskMinPriority, TaskMaxPriority)
+	}
+	return nil
+}
+
+func (r TaskRequest) apply(task *context.Task) {
+	task.Name = r.Name
+	task.Description = r.Description
+	task.Status = r.Status
+	if task.Status == "" {
+		task.Status = context.TaskStatusTodo
+	}
+	task.Priority = r.Priority
+	task.DueDate = r.DueDate
+}
+
+func (r TaskPatchRequest) apply(task *context.Task) {
+	if r.Name != nil {
+		task.Name = *r.Name
+	}
+	if r.Description != nil {
+		task.Description = *r.Description
+	}
+	if r.Status != nil {
+		task.Status = *r.Status
+	}
+	if r.Priority != nil {
+		task.Priority = *r.Priority
+	}
+	if r.DueDate.Set {
+		task.DueDate = r.DueDate.Time
+	}
+}
+
 func (p *Processor) CreateNewTask(c *gin.Context) {
-	var newTask context.Task
-	if err := c.ShouldBindJSON(&newTask); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	var req TaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, "Invalid request body: "+err.Error())
 		return
 	}
 
-	// next ID
-	newID := atomic.AddUint64(&p.Context().NextTaskID, 1)
-	newTask.ID = int(newID)
+	var newTask context.Task
+	req.apply(&newTask)
+	if err := validateTask(&newTask); err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, err.Error())
+		return
+	}
 
-	p.Context().TaskMutex.Lock()
-	p.Context().Tasks = append(p.Context().Tasks, newTask)
-	p.Context().TaskMutex.Unlock()
+	// the ID is the next sequence number, also used as the key
+	newTask, err := p.Context().Tasks.Append(func(seq uint64) context.Task {
+		newTask.ID = int(seq)
+		newTask.CreatedAt = time.Now().UTC()
+		newTask.UpdatedAt = newTask.CreatedAt
+		return newTask
+	})
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
 
 	c.JSON(http.StatusCreated, newTask)
 }
 
+var taskSortFields = map[string]func(a, b *context.Task) int{
+	"id": func(a, b *context.Task) int { return a.ID - b.ID },
+	"name": func(a, b *context.Task) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	},
+	"status":    func(a, b *context.Task) int { return strings.Compare(string(a.Status), string(b.Status)) },
+	"priority":  func(a, b *context.Task) int { return a.Priority - b.Priority },
+	"createdAt": func(a, b *context.Task) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"updatedAt": func(a, b *context.Task) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+	"dueDate": func(a, b *context.Task) int {
+		// tasks without a due date go last
+		switch {
+		case a.DueDate == nil && b.DueDate == nil:
+			return 0
+		case a.DueDate == nil:
+			return 1
+		case b.DueDate == nil:
+			return -1
+		}
+		return a.DueDate.Compare(*b.DueDate)
+	},
+}
+
+// GetAllTasks lists the tasks. Query parameters:
+//
+//	status: comma separated statuses to keep
+//	sort:   id, name, status, priority, dueDate, createdAt or updatedAt, prefixed with "-" for descending order
+//	offset, limit: pagination, the total number of matching tasks is returned in X-Total-Count
 func (p *Processor) GetAllTasks(c *gin.Context) {
-	p.Context().TaskMutex.RLock()
-	tasksCopy := make([]context.Task, len(p.Context().Tasks))
-	copy(tasksCopy, p.Context().Tasks)
-	p.Context().TaskMutex.RUnlock()
-	c.JSON(http.StatusOK, tasksCopy)
+	statuses := make(map[context.TaskStatus]bool)
+	if raw := c.Query("status"); raw != "" {
+		for _, s := range strings.Split(raw, ",") {
+			status := context.TaskStatus(strings.TrimSpace(s))
+			if !status.IsValid() {
+				util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, fmt.Sprintf("invalid status %q", status),
+					models.InvalidParam{Param: "status", Reason: "must be one of todo, doing, done"})
+				return
+			}
+			statuses[status] = true
+		}
+	}
+
+	sortBy := c.DefaultQuery("sort", "id")
+	desc := strings.HasPrefix(sortBy, "-")
+	compare, ok := taskSortFields[strings.TrimPrefix(sortBy, "-")]
+	if !ok {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, fmt.Sprintf("invalid sort field %q", sortBy),
+			models.InvalidParam{Param: "sort"})
+		return
+	}
+
+	offset, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+
+	tasks, err := p.Context().Tasks.All()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+
+	filtered := make([]context.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if len(statuses) == 0 || statuses[task.Status] {
+			filtered = append(filtered, task)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if desc {
+			return compare(&filtered[j], &filtered[i]) < 0
+		}
+		return compare(&filtered[i], &filtered[j]) < 0
+	})
+
+	c.JSON(http.StatusOK, paginate(c, filtered, offset, limit))
+}
+
+func taskKey(c *gin.Context) (string, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, "Invalid task ID")
+		return "", false
+	}
+	return strconv.Itoa(id), true
+}
+
+func (p *Processor) GetTask(c *gin.Context) {
+	key, ok := taskKey(c)
+	if !ok {
+		return
+	}
+
+	task, found, err := p.Context().Tasks.Get(key)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !found {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, "Task not found")
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+func (p *Processor) UpdateTask(c *gin.Context) {
+	key, ok := taskKey(c)
+	if !ok {
+		return
+	}
+	var req TaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, "Invalid request body: "+err.Error())
+		return
+	}
+
+	p.modifyTask(c, key, req.apply)
+}
+
+func (p *Processor) PatchTask(c *gin.Context) {
+	key, ok := taskKey(c)
+	if !ok {
+		return
+	}
+	var req TaskPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidMsgFormat, "Invalid request body: "+err.Error())
+		return
+	}
+
+	p.modifyTask(c, key, req.apply)
+}
+
+func (p *Processor) modifyTask(c *gin.Context, key string, apply func(*context.Task)) {
+	var invalid error
+	task, found, err := p.Context().Tasks.Update(key, func(task *context.Task) error {
+		apply(task)
+		if invalid = validateTask(task); invalid != nil {
+			return invalid
+		}
+		task.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+	switch {
+	case invalid != nil:
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, invalid.Error())
+	case err != nil:
+		util.SendSystemFailure(c, err)
+	case !found:
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, "Task not found")
+	default:
+		c.JSON(http.StatusOK, task)
+	}
+}
+
+func (p *Processor) DeleteTask(c *gin.Context) {
+	key, ok := taskKey(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := p.Context().Tasks.Delete(key)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !deleted {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, "Task not found")
+		return
+	}
+	c.Status(http.StatusNoContent)
 }
@@ -5,11 +5,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"sync"
 	"testing"
+	"time"
 
 	"github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/storage"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
@@ -23,11 +24,8 @@ func Test_TaskHandlers(t *testing.T) {
 
 	mockNfApp := processor.NewMockProcessorNf(mockCtrl)
 
-	nfContext := &context.NFContext{
-		Tasks:      make([]context.Task, 0),
-		TaskMutex:  sync.RWMutex{},
-		NextTaskID: 0,
-	}
+	nfContext := &context.NFContext{}
+	nfContext.UseStorage(storage.NewMemoryStorage())
 	mockNfApp.EXPECT().Context().Return(nfContext).AnyTimes()
 
 	proc, err := processor.NewProcessor(mockNfApp)
@@ -75,4 +73,200 @@ func Test_TaskHandlers(t *testing.T) {
 		assert.Len(t, tasks, 1)
 		assert.Equal(t, "Test Task", tasks[0].Name)
 	})
+	t.Run("Create Task With Invalid Status", func(t *testing.T) {
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+
+		body := []byte(`{"name": "Bad Task", "status": "blocked"}`)
+		ginCtx.Request, err = http.NewRequest(http.MethodPost, "/task/tasks", bytes.NewReader(body))
+		assert.NoError(t, err)
+		ginCtx.Request.Header.Set("Content-Type", "application/json")
+
+		proc.CreateNewTask(ginCtx)
+
+		assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+		assertProblem(t, httpRecorder, "MANDATORY_IE_INCORRECT", `invalid status "blocked", must be one of todo, doing, done`)
+	})
+
+	t.Run("Get Task", func(t *testing.T) {
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		ginCtx.Params = gin.Params{{Key: "id", Value: "1"}}
+
+		proc.GetTask(ginCtx)
+
+		assert.Equal(t, http.StatusOK, httpRecorder.Code)
+
+		var task context.Task
+		err = json.Unmarshal(httpRecorder.Body.Bytes(), &task)
+		assert.NoError(t, err)
+		assert.Equal(t, context.TaskStatusTodo, task.Status)
+		assert.False(t, task.CreatedAt.IsZero())
+	})
+
+	t.Run("Get Non-Existing Task", func(t *testing.T) {
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		ginCtx.Params = gin.Params{{Key: "id", Value: "42"}}
+
+		proc.GetTask(ginCtx)
+
+		assert.Equal(t, http.StatusNotFound, httpRecorder.Code)
+		assertProblem(t, httpRecorder, "DATA_NOT_FOUND", "Task not found")
+	})
+
+	t.Run("Update Task", func(t *testing.T) {
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		ginCtx.Params = gin.Params{{Key: "id", Value: "1"}}
+
+		body := []byte(`{"name": "Renamed Task", "status": "doing", "priority": 2, "dueDate": "2030-01-02T03:04:05Z"}`)
+		ginCtx.Request, err = http.NewRequest(http.MethodPut, "/task/tasks/1", bytes.NewReader(body))
+		assert.NoError(t, err)
+		ginCtx.Request.Header.Set("Content-Type", "application/json")
+
+		proc.UpdateTask(ginCtx)
+
+		assert.Equal(t, http.StatusOK, httpRecorder.Code)
+
+		var task context.Task
+		err = json.Unmarshal(httpRecorder.Body.Bytes(), &task)
+		assert.NoError(t, err)
+		assert.Equal(t, "Renamed Task", task.Name)
+		assert.Equal(t, context.TaskStatusDoing, task.Status)
+		assert.Equal(t, 2, task.Priority)
+		assert.NotNil(t, task.DueDate)
+	})
+
+	t.Run("Patch Task", func(t *testing.T) {
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		ginCtx.Params = gin.Params{{Key: "id", Value: "1"}}
+
+		body := []byte(`{"status": "done"}`)
+		ginCtx.Request, err = http.NewRequest(http.MethodPatch, "/task/tasks/1", bytes.NewReader(body))
+		assert.NoError(t, err)
+		ginCtx.Request.Header.Set("Content-Type", "application/json")
+
+		proc.PatchTask(ginCtx)
+
+		assert.Equal(t, http.StatusOK, httpRecorder.Code)
+
+		var task context.Task
+		err = json.Unmarshal(httpRecorder.Body.Bytes(), &task)
+		assert.NoError(t, err)
+		assert.Equal(t, "Renamed Task", task.Name)
+		assert.Equal(t, context.TaskStatusDone, task.Status)
+		assert.Equal(t, 2, task.Priority)
+		assert.NotNil(t, task.DueDate, "a field absent from the body is kept")
+	})
+
+	t.Run("Patch Task Due Date", func(t *testing.T) {
+		patch := func(body string) context.Task {
+			httpRecorder := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(httpRecorder)
+			ginCtx.Params = gin.Params{{Key: "id", Value: "1"}}
+			ginCtx.Request, err = http.NewRequest(http.MethodPatch, "/task/tasks/1", bytes.NewReader([]byte(body)))
+			assert.NoError(t, err)
+			ginCtx.Request.Header.Set("Content-Type", "application/json")
+
+			proc.PatchTask(ginCtx)
+
+			assert.Equal(t, http.StatusOK, httpRecorder.Code)
+			var task context.Task
+			assert.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &task))
+			return task
+		}
+
+		task := patch(`{"dueDate": null}`)
+		assert.Nil(t, task.DueDate, "an explicit null clears the due date")
+		assert.Equal(t, "Renamed Task", task.Name)
+
+		task = patch(`{"dueDate": "2030-01-02T03:04:05Z"}`)
+		if assert.NotNil(t, task.DueDate) {
+			assert.Equal(t, "2030-01-02T03:04:05Z", task.DueDate.Format(time.RFC3339))
+		}
+
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		ginCtx.Params = gin.Params{{Key: "id", Value: "1"}}
+		ginCtx.Request, err = http.NewRequest(http.MethodPatch, "/task/tasks/1", bytes.NewReader([]byte(`{"dueDate": "tomorrow"}`)))
+		assert.NoError(t, err)
+		ginCtx.Request.Header.Set("Content-Type", "application/json")
+		proc.PatchTask(ginCtx)
+		assert.Equal(t, http.StatusBadRequest, httpRecorder.Code)
+	})
+
+	t.Run("List Tasks With Filter, Sort And Pagination", func(t *testing.T) {
+		for _, body := range []string{
+			`{"name": "Low", "priority": 1}`,
+			`{"name": "High", "priority": 5}`,
+			`{"name": "Mid", "priority": 3, "status": "doing"}`,
+		} {
+			httpRecorder := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(httpRecorder)
+			ginCtx.Request, err = http.NewRequest(http.MethodPost, "/task/tasks", bytes.NewReader([]byte(body)))
+			assert.NoError(t, err)
+			ginCtx.Request.Header.Set("Content-Type", "application/json")
+			proc.CreateNewTask(ginCtx)
+			assert.Equal(t, http.StatusCreated, httpRecorder.Code)
+		}
+
+		tests := []struct {
+			name          string
+			query         string
+			expectedCode  int
+			expectedNames []string
+			expectedTotal string
+		}{
+			{"Filter by status", "status=todo", http.StatusOK, []string{"Low", "High"}, "2"},
+			{"Sort by priority desc", "sort=-priority", http.StatusOK, []string{"High", "Mid", "Renamed Task", "Low"}, "4"},
+			{"Paginate", "sort=name&offset=1&limit=2", http.StatusOK, []string{"Low", "Mid"}, "4"},
+			{"Invalid status", "status=blocked", http.StatusBadRequest, nil, ""},
+			{"Invalid sort", "sort=color", http.StatusBadRequest, nil, ""},
+			{"Invalid limit", "limit=0", http.StatusBadRequest, nil, ""},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				httpRecorder := httptest.NewRecorder()
+				ginCtx, _ := gin.CreateTestContext(httpRecorder)
+				ginCtx.Request, err = http.NewRequest(http.MethodGet, "/task/tasks?"+tt.query, nil)
+				assert.NoError(t, err)
+
+				proc.GetAllTasks(ginCtx)
+
+				assert.Equal(t, tt.expectedCode, httpRecorder.Code)
+				if tt.expectedCode != http.StatusOK {
+					return
+				}
+				assert.Equal(t, tt.expectedTotal, httpRecorder.Header().Get("X-Total-Count"))
+
+				var tasks []context.Task
+				err = json.Unmarshal(httpRecorder.Body.Bytes(), &tasks)
+				assert.NoError(t, err)
+				names := make([]string, 0, len(tasks))
+				for _, task := range tasks {
+					names = append(names, task.Name)
+				}
+				assert.Equal(t, tt.expectedNames, names)
+			})
+		}
+	})
+
+	t.Run("Delete Task", func(t *testing.T) {
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		ginCtx.Params = gin.Params{{Key: "id", Value: "1"}}
+
+		proc.DeleteTask(ginCtx)
+		assert.Equal(t, http.StatusNoContent, ginCtx.Writer.Status())
+
+		httpRecorder = httptest.NewRecorder()
+		ginCtx, _ = gin.CreateTestContext(httpRecorder)
+		ginCtx.Params = gin.Params{{Key: "id", Value: "1"}}
+
+		proc.DeleteTask(ginCtx)
+		assert.Equal(t, http.StatusNotFound, httpRecorder.Code)
+	})
 }
@@ -4,18 +4,182 @@ package processor
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	// embed the IANA database so zones resolve on hosts without zoneinfo
+	_ "time/tzdata"
 
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
 )
 
+// CityTime is the local time of a city at a given instant.
+type CityTime struct {
+	City         string    `json:"city"`
+	TimeZone     string    `json:"timeZone"`
+	LocalTime    time.Time `json:"localTime"`
+	UTCOffset    string    `json:"utcOffset"`
+	Abbreviation string    `json:"abbreviation"`
+	DST          bool      `json:"dst"`
+}
+
+// TimeConversion is the same instant seen from two cities.
+type TimeConversion struct {
+	From CityTime `json:"from"`
+	To   CityTime `json:"to"`
+}
+
+// OffsetCities lists the cities observing utcOffset at the given instant.
+type OffsetCities struct {
+	UTCOffset string    `json:"utcOffset"`
+	At        time.Time `json:"at"`
+	Cities    []string  `json:"cities"`
+}
+
+// loadZone accepts IANA zone names only, "Local" depends on the host and is rejected.
+func loadZone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("invalid IANA time zone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IANA time zone %q", name)
+	}
+	return loc, nil
+}
+
+func newCityTime(city string, t time.Time) CityTime {
+	abbreviation, _ := t.Zone()
+	return CityTime{
+		City:         city,
+		TimeZone:     t.Location().String(),
+		LocalTime:    t,
+		UTCOffset:    t.Format("-07:00"),
+		Abbreviation: abbreviation,
+		DST:          t.IsDST(),
+	}
+}
+
+// parseUTCOffset accepts "+08:00", "+0800", "+8", "UTC+8" and "Z", and returns the offset in seconds.
+func parseUTCOffset(raw string) (int, error) {
+	offset := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(raw)), "UTC")
+	if offset == "" || offset == "Z" {
+		return 0, nil
+	}
+
+	sign := 1
+	switch offset[0] {
+	case '+':
+	case '-':
+		sign = -1
+	default:
+		return 0, fmt.Errorf("invalid UTC offset %q", raw)
+	}
+	offset = offset[1:]
+
+	hours, minutes, found := strings.Cut(offset, ":")
+	if !found && len(offset) == 4 {
+		hours, minutes = offset[:2], offset[2:]
+	}
+	if !isOffsetPart(hours) || (found && !isOffsetPart(minutes)) {
+		return 0, fmt.Errorf("invalid UTC offset %q", raw)
+	}
+	h, _ := strconv.Atoi(hours)
+	if h > 14 {
+		return 0, fmt.Errorf("invalid UTC offset %q", raw)
+	}
+	m := 0
+	if minutes != "" {
+		if m, _ = strconv.Atoi(minutes); m >= 60 {
+			return 0, fmt.Errorf("invalid UTC offset %q", raw)
+		}
+	}
+	return sign * (h*3600 + m*60), nil
+}
+
+// isOffsetPart reports whether part is the 1 or 2 digits of offset hours or minutes, without sign.
+func isOffsetPart(part string) bool {
+	if len(part) == 0 || len(part) > 2 {
+		return false
+	}
+	for _, r := range part {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// loadStoredZone also reads the UTC±hh[:mm] values stored before zones were validated,
+// as a fixed offset without daylight saving time.
+func loadStoredZone(name string) (*time.Location, error) {
+	loc, err := loadZone(name)
+	if err == nil {
+		return loc, nil
+	}
+	if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(name)), "UTC") {
+		return nil, err
+	}
+	seconds, offsetErr := parseUTCOffset(name)
+	if offsetErr != nil {
+		return nil, err
+	}
+	return time.FixedZone("UTC"+time.Unix(0, 0).In(time.FixedZone("", seconds)).Format("-07:00"), seconds), nil
+}
+
+// parseInstant reads an RFC 3339 timestamp, a timestamp without offset is local to loc.
+// An empty value is the current time.
+func parseInstant(raw string, loc *time.Location) (time.Time, error) {
+	if raw == "" {
+		return time.Now(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", raw, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339", raw)
+	}
+	return t, nil
+}
+
+// loadCityZone answers the problem itself when the city is unknown or its zone is unusable.
+func loadCityZone(c *gin.Context, zones *nf_context.Store[string], city string) (*time.Location, bool) {
+	tz, ok, err := zones.Get(city)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return nil, false
+	}
+	if !ok {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("City '%s' not found", city))
+		return nil, false
+	}
+	loc, err := loadStoredZone(tz)
+	if err != nil {
+		util.SendProblem(c, http.StatusConflict, util.CauseDataConflict,
+			fmt.Sprintf("City '%s' has time zone %q, which is neither an IANA time zone nor a UTC offset, reset it", city, tz))
+		return nil, false
+	}
+	return loc, true
+}
+
 // HandleGetTimeZone 查詢時區
 func (p *Processor) HandleGetTimeZone(c *gin.Context, city string) {
-	tzData := p.Context().TimeZoneData
-	if tz, ok := tzData[city]; ok {
+	tz, ok, err := p.Context().TimeZones.Get(city)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if ok {
 		c.String(http.StatusOK, tz)
 		return
 	}
-	c.String(http.StatusNotFound, fmt.Sprintf("[%s] not found", city))
+	util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("[%s] not found", city))
 }
 
 // TimeZoneRequest used for POST /city
@@ -26,30 +190,135 @@ type TimeZoneRequest struct {
 
 // HandleAddNewCityTimeZone 新增城市時區
 func (p *Processor) HandleAddNewCityTimeZone(c *gin.Context, req TimeZoneRequest) {
-	if _, ok := p.Context().TimeZoneData[req.City]; ok {
-		c.String(http.StatusConflict, fmt.Sprintf("City '%s' already exists", req.City))
+	if _, err := loadZone(req.TimeZone); err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, err.Error(),
+			models.InvalidParam{Param: "TimeZone", Reason: "must be an IANA time zone such as Europe/Paris"})
+		return
+	}
+
+	_, inserted, err := p.Context().TimeZones.Insert(req.City, req.TimeZone)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !inserted {
+		util.SendProblem(c, http.StatusConflict, util.CauseDataConflict, fmt.Sprintf("City '%s' already exists", req.City))
 		return
 	}
-	p.Context().TimeZoneData[req.City] = req.TimeZone
 	c.String(http.StatusOK, fmt.Sprintf("Time zone of %s is set to %s", req.City, req.TimeZone))
 }
 
 // HTTPResetCityTimeZone 重設時區
 func (p *Processor) HandleResetCityTimeZone(c *gin.Context, city string, newTZ string) {
-	if _, ok := p.Context().TimeZoneData[city]; !ok {
-		c.String(http.StatusNotFound, fmt.Sprintf("City '%s' not found", city))
+	if _, err := loadZone(newTZ); err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, err.Error(),
+			models.InvalidParam{Param: "TimeZone", Reason: "must be an IANA time zone such as Europe/Paris"})
+		return
+	}
+
+	_, found, err := p.Context().TimeZones.Update(city, func(tz *string) error {
+		*tz = newTZ
+		return nil
+	})
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !found {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("City '%s' not found", city))
 		return
 	}
-	p.Context().TimeZoneData[city] = newTZ
 	c.String(http.StatusOK, fmt.Sprintf("Time zone of %s is reset to %s", city, newTZ))
 }
 
 // HTTPDeleteCityTimeZone 刪除城市時區
 func (p *Processor) HandleDeleteCityTimeZone(c *gin.Context, city string) {
-	if _, ok := p.Context().TimeZoneData[city]; !ok {
-		c.String(http.StatusNotFound, fmt.Sprintf("City '%s' not found", city))
+	deleted, err := p.Context().TimeZones.Delete(city)
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	if !deleted {
+		util.SendProblem(c, http.StatusNotFound, util.CauseDataNotFound, fmt.Sprintf("City '%s' not found", city))
 		return
 	}
-	delete(p.Context().TimeZoneData, city)
 	c.String(http.StatusOK, fmt.Sprintf("City '%s' has been removed", city))
 }
+
+// HandleGetCityTime 查詢城市當地時間, at defaults to now
+func (p *Processor) HandleGetCityTime(c *gin.Context, city string, at string) {
+	loc, ok := loadCityZone(c, p.Context().TimeZones, city)
+	if !ok {
+		return
+	}
+	t, err := parseInstant(at, loc)
+	if err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, err.Error(),
+			models.InvalidParam{Param: "at", Reason: "must be an RFC 3339 timestamp"})
+		return
+	}
+	c.JSON(http.StatusOK, newCityTime(city, t.In(loc)))
+}
+
+// HandleConvertTime 轉換兩城市間的時間, a timestamp without offset is local to the from city
+func (p *Processor) HandleConvertTime(c *gin.Context, from string, to string, at string) {
+	zones := p.Context().TimeZones
+	fromLoc, ok := loadCityZone(c, zones, from)
+	if !ok {
+		return
+	}
+	toLoc, ok := loadCityZone(c, zones, to)
+	if !ok {
+		return
+	}
+	t, err := parseInstant(at, fromLoc)
+	if err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, err.Error(),
+			models.InvalidParam{Param: "time", Reason: "must be an RFC 3339 timestamp"})
+		return
+	}
+	c.JSON(http.StatusOK, TimeConversion{
+		From: newCityTime(from, t.In(fromLoc)),
+		To:   newCityTime(to, t.In(toLoc)),
+	})
+}
+
+// HandleGetCitiesByOffset 列出同一時差的城市, the offset is evaluated at the given instant
+func (p *Processor) HandleGetCitiesByOffset(c *gin.Context, offset string, at string) {
+	seconds, err := parseUTCOffset(offset)
+	if err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseMandatoryIeIncorrect, err.Error(),
+			models.InvalidParam{Param: "offset", Reason: "must look like +08:00"})
+		return
+	}
+	t, err := parseInstant(at, time.UTC)
+	if err != nil {
+		util.SendProblem(c, http.StatusBadRequest, util.CauseInvalidQueryParam, err.Error(),
+			models.InvalidParam{Param: "at", Reason: "must be an RFC 3339 timestamp"})
+		return
+	}
+
+	entries, err := p.Context().TimeZones.Entries()
+	if err != nil {
+		util.SendSystemFailure(c, err)
+		return
+	}
+	cities := []string{}
+	for _, entry := range entries {
+		loc, err := loadStoredZone(entry.Value)
+		if err != nil {
+			// entries that are neither a zone nor an offset cannot be evaluated, they never match
+			continue
+		}
+		if _, cityOffset := t.In(loc).Zone(); cityOffset == seconds {
+			cities = append(cities, entry.Key)
+		}
+	}
+	sort.Strings(cities)
+
+	c.JSON(http.StatusOK, OffsetCities{
+		UTCOffset: time.Unix(0, 0).In(time.FixedZone("", seconds)).Format("-07:00"),
+		At:        t.UTC(),
+		Cities:    cities,
+	})
+}
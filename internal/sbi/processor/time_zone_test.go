@@ -1,13 +1,20 @@
 package processor_test
 
 import (
+	"encoding/json"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	nf_context "github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
+	"github.com/Alonza0314/nf-example/internal/storage"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	gomock "go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi/models"
 )
 
 func setupTimeZoneProcessor(t *testing.T) (*processor.Processor, *processor.MockProcessorNf) {
@@ -28,14 +35,12 @@ func Test_HandleGetTimeZone(t *testing.T) {
 	t.Run("Get TimeZone for Existing City", func(t *testing.T) {
 		const INPUT_CITY = "Taipei"
 		const EXPECTED_STATUS = 200
-		const EXPECTED_BODY = "UTC+8"
+		const EXPECTED_BODY = "Asia/Taipei"
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			TimeZoneData: map[string]string{
-				"Taipei": "UTC+8",
-				"Tokyo":  "UTC+9",
-			},
-		})
+		processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.TimeZoneCollection, map[string]string{
+			"Taipei": "Asia/Taipei",
+			"Tokyo":  "Asia/Tokyo",
+		}))
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -53,14 +58,12 @@ func Test_HandleGetTimeZone(t *testing.T) {
 	t.Run("Get TimeZone for Non-Existing City", func(t *testing.T) {
 		const INPUT_CITY = "Unknown"
 		const EXPECTED_STATUS = 404
-		const EXPECTED_BODY = "[Unknown] not found"
+		const EXPECTED_DETAIL = "[Unknown] not found"
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			TimeZoneData: map[string]string{
-				"Taipei": "UTC+8",
-				"Tokyo":  "UTC+9",
-			},
-		})
+		processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.TimeZoneCollection, map[string]string{
+			"Taipei": "Asia/Taipei",
+			"Tokyo":  "Asia/Tokyo",
+		}))
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -70,9 +73,7 @@ func Test_HandleGetTimeZone(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
-		}
+		assertProblem(t, httpRecorder, "DATA_NOT_FOUND", EXPECTED_DETAIL)
 	})
 }
 
@@ -81,21 +82,20 @@ func Test_HandleAddNewCityTimeZone(t *testing.T) {
 
 	t.Run("Add New City Successfully", func(t *testing.T) {
 		const EXPECTED_STATUS = 200
-		const EXPECTED_BODY = "Time zone of Chicago is set to UTC-6"
+		const EXPECTED_BODY = "Time zone of Chicago is set to America/Chicago"
 
 		req := processor.TimeZoneRequest{
 			City:     "Chicago",
-			TimeZone: "UTC-6",
+			TimeZone: "America/Chicago",
 		}
 
 		timeZoneData := map[string]string{
-			"Taipei": "UTC+8",
-			"Tokyo":  "UTC+9",
+			"Taipei": "Asia/Taipei",
+			"Tokyo":  "Asia/Tokyo",
 		}
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			TimeZoneData: timeZoneData,
-		}).Times(2) // Called twice: once to check if city exists, once to set timezone
+		mockCtx := newTestContext(t, nf_context.TimeZoneCollection, timeZoneData)
+		processorNf.EXPECT().Context().Return(mockCtx).Times(1)
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -110,26 +110,24 @@ func Test_HandleAddNewCityTimeZone(t *testing.T) {
 		}
 
 		// Verify city was added to the data
-		if timeZoneData["Chicago"] != "UTC-6" {
-			t.Errorf("Expected Chicago to be added with timezone UTC-6, but was not found")
+		if tz, _, _ := storage.Load[string](mockCtx.Storage, nf_context.TimeZoneCollection, "Chicago"); tz != "America/Chicago" {
+			t.Errorf("Expected Chicago to be added with timezone America/Chicago, but was not found")
 		}
 	})
 
 	t.Run("Add Existing City - Conflict", func(t *testing.T) {
 		const EXPECTED_STATUS = 409
-		const EXPECTED_BODY = "City 'Taipei' already exists"
+		const EXPECTED_DETAIL = "City 'Taipei' already exists"
 
 		req := processor.TimeZoneRequest{
 			City:     "Taipei",
-			TimeZone: "UTC+7",
+			TimeZone: "Asia/Bangkok",
 		}
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			TimeZoneData: map[string]string{
-				"Taipei": "UTC+8",
-				"Tokyo":  "UTC+9",
-			},
-		}).Times(1) // Called once to check if city exists (conflict case)
+		processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.TimeZoneCollection, map[string]string{
+			"Taipei": "Asia/Taipei",
+			"Tokyo":  "Asia/Tokyo",
+		})).Times(1) // Called once to check if city exists (conflict case)
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -139,9 +137,7 @@ func Test_HandleAddNewCityTimeZone(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
-		}
+		assertProblem(t, httpRecorder, "DATA_CONFLICT", EXPECTED_DETAIL)
 	})
 }
 
@@ -150,18 +146,17 @@ func Test_HandleResetCityTimeZone(t *testing.T) {
 
 	t.Run("Reset Existing City TimeZone", func(t *testing.T) {
 		const INPUT_CITY = "Taipei"
-		const NEW_TIMEZONE = "UTC+7"
+		const NEW_TIMEZONE = "Asia/Bangkok"
 		const EXPECTED_STATUS = 200
-		const EXPECTED_BODY = "Time zone of Taipei is reset to UTC+7"
+		const EXPECTED_BODY = "Time zone of Taipei is reset to Asia/Bangkok"
 
 		timeZoneData := map[string]string{
-			"Taipei": "UTC+8",
-			"Tokyo":  "UTC+9",
+			"Taipei": "Asia/Taipei",
+			"Tokyo":  "Asia/Tokyo",
 		}
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			TimeZoneData: timeZoneData,
-		}).Times(2) // Called twice: once to check if city exists, once to update timezone
+		mockCtx := newTestContext(t, nf_context.TimeZoneCollection, timeZoneData)
+		processorNf.EXPECT().Context().Return(mockCtx).Times(1)
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -176,23 +171,21 @@ func Test_HandleResetCityTimeZone(t *testing.T) {
 		}
 
 		// Verify timezone was updated
-		if timeZoneData["Taipei"] != NEW_TIMEZONE {
-			t.Errorf("Expected Taipei timezone to be updated to %s, got %s", NEW_TIMEZONE, timeZoneData["Taipei"])
+		if tz, _, _ := storage.Load[string](mockCtx.Storage, nf_context.TimeZoneCollection, "Taipei"); tz != NEW_TIMEZONE {
+			t.Errorf("Expected Taipei timezone to be updated to %s, got %s", NEW_TIMEZONE, tz)
 		}
 	})
 
 	t.Run("Reset Non-Existing City TimeZone", func(t *testing.T) {
 		const INPUT_CITY = "Unknown"
-		const NEW_TIMEZONE = "UTC+0"
+		const NEW_TIMEZONE = "Etc/UTC"
 		const EXPECTED_STATUS = 404
-		const EXPECTED_BODY = "City 'Unknown' not found"
+		const EXPECTED_DETAIL = "City 'Unknown' not found"
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			TimeZoneData: map[string]string{
-				"Taipei": "UTC+8",
-				"Tokyo":  "UTC+9",
-			},
-		}).Times(1) // Called once to check if city exists (not found case)
+		processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.TimeZoneCollection, map[string]string{
+			"Taipei": "Asia/Taipei",
+			"Tokyo":  "Asia/Tokyo",
+		})).Times(1) // Called once to check if city exists (not found case)
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -202,9 +195,7 @@ func Test_HandleResetCityTimeZone(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
-		}
+		assertProblem(t, httpRecorder, "DATA_NOT_FOUND", EXPECTED_DETAIL)
 	})
 }
 
@@ -217,13 +208,12 @@ func Test_HandleDeleteCityTimeZone(t *testing.T) {
 		const EXPECTED_BODY = "City 'Tokyo' has been removed"
 
 		timeZoneData := map[string]string{
-			"Taipei": "UTC+8",
-			"Tokyo":  "UTC+9",
+			"Taipei": "Asia/Taipei",
+			"Tokyo":  "Asia/Tokyo",
 		}
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			TimeZoneData: timeZoneData,
-		}).Times(2) // Called twice: once to check if city exists, once to delete from map
+		mockCtx := newTestContext(t, nf_context.TimeZoneCollection, timeZoneData)
+		processorNf.EXPECT().Context().Return(mockCtx).Times(1)
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -238,7 +228,7 @@ func Test_HandleDeleteCityTimeZone(t *testing.T) {
 		}
 
 		// Verify city was deleted
-		if _, exists := timeZoneData["Tokyo"]; exists {
+		if _, exists, _ := storage.Load[string](mockCtx.Storage, nf_context.TimeZoneCollection, "Tokyo"); exists {
 			t.Errorf("Expected Tokyo to be deleted, but it still exists")
 		}
 	})
@@ -246,14 +236,12 @@ func Test_HandleDeleteCityTimeZone(t *testing.T) {
 	t.Run("Delete Non-Existing City", func(t *testing.T) {
 		const INPUT_CITY = "Unknown"
 		const EXPECTED_STATUS = 404
-		const EXPECTED_BODY = "City 'Unknown' not found"
+		const EXPECTED_DETAIL = "City 'Unknown' not found"
 
-		processorNf.EXPECT().Context().Return(&nf_context.NFContext{
-			TimeZoneData: map[string]string{
-				"Taipei": "UTC+8",
-				"Tokyo":  "UTC+9",
-			},
-		}).Times(1) // Called once to check if city exists (not found case)
+		processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.TimeZoneCollection, map[string]string{
+			"Taipei": "Asia/Taipei",
+			"Tokyo":  "Asia/Tokyo",
+		})).Times(1) // Called once to check if city exists (not found case)
 
 		httpRecorder := httptest.NewRecorder()
 		ginCtx, _ := gin.CreateTestContext(httpRecorder)
@@ -263,8 +251,230 @@ func Test_HandleDeleteCityTimeZone(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", EXPECTED_STATUS, httpRecorder.Code)
 		}
 
-		if httpRecorder.Body.String() != EXPECTED_BODY {
-			t.Errorf("Expected body %s, got %s", EXPECTED_BODY, httpRecorder.Body.String())
-		}
+		assertProblem(t, httpRecorder, "DATA_NOT_FOUND", EXPECTED_DETAIL)
 	})
 }
+
+func Test_HandleAddNewCityTimeZone_InvalidZone(t *testing.T) {
+	proc, _ := setupTimeZoneProcessor(t)
+
+	for _, zone := range []string{"UTC+8", "Local", "Mars/Olympus"} {
+		httpRecorder := httptest.NewRecorder()
+		ginCtx, _ := gin.CreateTestContext(httpRecorder)
+		proc.HandleAddNewCityTimeZone(ginCtx, processor.TimeZoneRequest{City: "Chicago", TimeZone: zone})
+
+		assert.Equal(t, 400, httpRecorder.Code)
+		assertProblem(t, httpRecorder, "MANDATORY_IE_INCORRECT", `invalid IANA time zone "`+zone+`"`)
+	}
+}
+
+var testCityZones = map[string]string{
+	"Taipei":  "Asia/Taipei",
+	"NewYork": "America/New_York",
+	"Paris":   "Europe/Paris",
+	"London":  "Europe/London",
+	"Berlin":  "Europe/Berlin",
+	"Legacy":  "UTC+1",
+	"Broken":  "Somewhere/Else",
+}
+
+func Test_HandleGetCityTime(t *testing.T) {
+	proc, processorNf := setupTimeZoneProcessor(t)
+
+	tests := []struct {
+		name           string
+		city           string
+		at             string
+		expectedStatus int
+		expectedCause  string
+		expected       processor.CityTime
+	}{
+		{
+			name:           "Paris in summer",
+			city:           "Paris",
+			at:             "2025-07-01T12:00:00Z",
+			expectedStatus: 200,
+			expected: processor.CityTime{
+				City: "Paris", TimeZone: "Europe/Paris", UTCOffset: "+02:00", Abbreviation: "CEST", DST: true,
+			},
+		},
+		{
+			name:           "Paris in winter",
+			city:           "Paris",
+			at:             "2025-01-15T12:00:00Z",
+			expectedStatus: 200,
+			expected: processor.CityTime{
+				City: "Paris", TimeZone: "Europe/Paris", UTCOffset: "+01:00", Abbreviation: "CET", DST: false,
+			},
+		},
+		{
+			name:           "Unknown city",
+			city:           "Atlantis",
+			expectedStatus: 404,
+			expectedCause:  "DATA_NOT_FOUND",
+		},
+		{
+			name:           "Invalid time",
+			city:           "Paris",
+			at:             "yesterday",
+			expectedStatus: 400,
+			expectedCause:  "INVALID_QUERY_PARAM",
+		},
+		{
+			name:           "Legacy offset zone",
+			city:           "Legacy",
+			at:             "2025-07-01T12:00:00Z",
+			expectedStatus: 200,
+			expected: processor.CityTime{
+				City: "Legacy", TimeZone: "UTC+01:00", UTCOffset: "+01:00", Abbreviation: "UTC+01:00", DST: false,
+			},
+		},
+		{
+			name:           "Unusable zone",
+			city:           "Broken",
+			expectedStatus: 409,
+			expectedCause:  "DATA_CONFLICT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.TimeZoneCollection, testCityZones)).Times(1)
+
+			httpRecorder := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(httpRecorder)
+			proc.HandleGetCityTime(ginCtx, tt.city, tt.at)
+
+			require.Equal(t, tt.expectedStatus, httpRecorder.Code)
+			if tt.expectedCause != "" {
+				var problem models.ProblemDetails
+				require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &problem))
+				assert.Equal(t, tt.expectedCause, problem.Cause)
+				return
+			}
+
+			var got processor.CityTime
+			require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &got))
+			at, _ := time.Parse(time.RFC3339, tt.at)
+			assert.True(t, at.Equal(got.LocalTime), "local time %s is not %s", got.LocalTime, at)
+			got.LocalTime = time.Time{}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func Test_HandleConvertTime(t *testing.T) {
+	proc, processorNf := setupTimeZoneProcessor(t)
+	processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.TimeZoneCollection, testCityZones)).Times(1)
+
+	httpRecorder := httptest.NewRecorder()
+	ginCtx, _ := gin.CreateTestContext(httpRecorder)
+	// noon in Taipei is still the evening before in New York, a few hours before DST starts
+	proc.HandleConvertTime(ginCtx, "Taipei", "NewYork", "2025-03-09T12:00:00")
+
+	require.Equal(t, 200, httpRecorder.Code)
+	var got processor.TimeConversion
+	require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &got))
+	assert.Equal(t, "2025-03-09T12:00:00+08:00", got.From.LocalTime.Format(time.RFC3339))
+	assert.Equal(t, "2025-03-08T23:00:00-05:00", got.To.LocalTime.Format(time.RFC3339))
+	assert.False(t, got.To.DST)
+	assert.Equal(t, "America/New_York", got.To.TimeZone)
+}
+
+func Test_HandleGetCitiesByOffset(t *testing.T) {
+	proc, processorNf := setupTimeZoneProcessor(t)
+
+	tests := []struct {
+		name           string
+		offset         string
+		at             string
+		expectedStatus int
+		expectedOffset string
+		expectedCities []string
+	}{
+		{
+			name:           "London joins CET in summer",
+			offset:         "+01:00",
+			at:             "2025-07-01T00:00:00Z",
+			expectedStatus: 200,
+			expectedOffset: "+01:00",
+			expectedCities: []string{"Legacy", "London"},
+		},
+		{
+			name:           "Paris and Berlin in winter",
+			offset:         "UTC+1",
+			at:             "2025-01-15T00:00:00Z",
+			expectedStatus: 200,
+			expectedOffset: "+01:00",
+			expectedCities: []string{"Berlin", "Legacy", "Paris"},
+		},
+		{
+			name:           "Compact offset",
+			offset:         "+0800",
+			at:             "2025-01-15T00:00:00Z",
+			expectedStatus: 200,
+			expectedOffset: "+08:00",
+			expectedCities: []string{"Taipei"},
+		},
+		{
+			name:           "No city",
+			offset:         "-03:30",
+			at:             "2025-01-15T00:00:00Z",
+			expectedStatus: 200,
+			expectedOffset: "-03:30",
+			expectedCities: []string{},
+		},
+		{
+			name:           "Invalid offset",
+			offset:         "tomorrow",
+			expectedStatus: 400,
+		},
+		{
+			name:           "Signed hours",
+			offset:         "+-3",
+			expectedStatus: 400,
+		},
+		{
+			name:           "Signed minutes",
+			offset:         "+05:-30",
+			expectedStatus: 400,
+		},
+		{
+			name:           "Double sign",
+			offset:         "-+2",
+			expectedStatus: 400,
+		},
+		{
+			name:           "Three digit hours",
+			offset:         "+123",
+			expectedStatus: 400,
+		},
+		{
+			name:           "Hours out of range",
+			offset:         "-15",
+			expectedStatus: 400,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.expectedStatus == 200 {
+				processorNf.EXPECT().Context().Return(newTestContext(t, nf_context.TimeZoneCollection, testCityZones)).Times(1)
+			}
+
+			httpRecorder := httptest.NewRecorder()
+			ginCtx, _ := gin.CreateTestContext(httpRecorder)
+			proc.HandleGetCitiesByOffset(ginCtx, tt.offset, tt.at)
+
+			require.Equal(t, tt.expectedStatus, httpRecorder.Code)
+			if tt.expectedStatus != 200 {
+				assertProblem(t, httpRecorder, "MANDATORY_IE_INCORRECT", `invalid UTC offset "`+tt.offset+`"`)
+				return
+			}
+			var got processor.OffsetCities
+			require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &got))
+			assert.Equal(t, tt.expectedOffset, got.UTCOffset)
+			assert.Equal(t, tt.expectedCities, got.Cities)
+		})
+	}
+}
@@ -2,10 +2,14 @@ package sbi
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/util"
 	"github.com/Alonza0314/nf-example/pkg/app"
+	"github.com/Alonza0314/nf-example/pkg/factory"
 	"github.com/gin-gonic/gin"
 
 	"github.com/free5gc/util/httpwrapper"
@@ -17,63 +21,147 @@ type Route struct {
 	Method  string
 	Pattern string
 	APIFunc gin.HandlerFunc
+	// Scopes required in the OAuth2 access token, defaults to the service name of the group
+	Scopes []string
+
+	// Request and Response are zero values of the body types, they describe the route in
+	// the OpenAPI document. A nil Response documents a response without body.
+	Request  any
+	Response any
+	// Status of a successful response, http.StatusOK when zero
+	Status int
+	// Query lists the query parameters accepted by the route
+	Query []string
 }
 
-func applyRoutes(group *gin.RouterGroup, routes []Route) {
+func (s *Server) applyRoutes(group *gin.RouterGroup, serviceName string, routes []Route) {
 	for _, route := range routes {
+		handlers := []gin.HandlerFunc{route.APIFunc}
+		if s.tokenVerifier != nil {
+			scopes := route.Scopes
+			if len(scopes) == 0 {
+				scopes = []string{serviceName}
+			}
+			handlers = append([]gin.HandlerFunc{s.authorize(scopes)}, handlers...)
+		}
+		if s.clientVerifier != nil {
+			handlers = append([]gin.HandlerFunc{s.authenticateClient(serviceName)}, handlers...)
+		}
+		if serviceName != factory.ServiceNameManagement {
+			handlers = append([]gin.HandlerFunc{s.serviceEnabled(serviceName)}, handlers...)
+		}
+		if s.metrics != nil {
+			// outermost, so rejected requests are counted too
+			handlers = append([]gin.HandlerFunc{s.metrics.Middleware(route.Name)}, handlers...)
+		}
+
 		switch route.Method {
 		case "GET":
-			group.GET(route.Pattern, route.APIFunc)
+			group.GET(route.Pattern, handlers...)
 		case "POST":
-			group.POST(route.Pattern, route.APIFunc)
+			group.POST(route.Pattern, handlers...)
 		case "PUT":
-			group.PUT(route.Pattern, route.APIFunc)
+			group.PUT(route.Pattern, handlers...)
 		case "PATCH":
-			group.PATCH(route.Pattern, route.APIFunc)
+			group.PATCH(route.Pattern, handlers...)
 		case "DELETE":
-			group.DELETE(route.Pattern, route.APIFunc)
+			group.DELETE(route.Pattern, handlers...)
 		}
 	}
 }
 
-func newRouter(s *Server) *gin.Engine {
-	router := logger_util.NewGinWithLogrus(logger.GinLog)
-
-	// Add routes to each api group
-	defaultGroup := router.Group("/default")
-	applyRoutes(defaultGroup, s.getDefaultRoute())
-
-	myPutGetMessageGroup := router.Group("/message")
-	applyRoutes(myPutGetMessageGroup, s.myPutGetMessageRoute())
-
-	spyFamilyGroup := router.Group("/spyfamily")
-	applyRoutes(spyFamilyGroup, s.getSpyFamilyRoute())
-
-	onePieceGroup := router.Group("/onepiece")
-	applyRoutes(onePieceGroup, s.getOnePieceRoute())
-	attendanceGroup := router.Group("/attendance")
-	applyRoutes(attendanceGroup, s.getAttendanceRoute())
-	taskGroup := router.Group("/task")
-	applyRoutes(taskGroup, s.getTaskRoute())
-
-	messageGroup := router.Group("/msg") // add for lab6
-	applyRoutes(messageGroup, s.getMessageRoute())
+type routeGroup struct {
+	ServiceName string
+	Prefix      string
+	Routes      []Route
+}
 
-	dragonBallGroup := router.Group("/dragonball")
-	applyRoutes(dragonBallGroup, s.getDragonBallRoute())
+func (s *Server) getRouteGroups() []routeGroup {
+	return []routeGroup{
+		{factory.ServiceNameDefault, "/default", s.getDefaultRoute()},
+		{factory.ServiceNameMessage, "/message", s.myPutGetMessageRoute()},
+		{factory.ServiceNameSpyFamily, "/spyfamily", s.getSpyFamilyRoute()},
+		{factory.ServiceNameOnePiece, "/onepiece", s.getOnePieceRoute()},
+		{factory.ServiceNameAttendance, "/attendance", s.getAttendanceRoute()},
+		{factory.ServiceNameTask, "/task", s.getTaskRoute()},
+		{factory.ServiceNameMsg, "/msg", s.getMessageRoute()}, // add for lab6
+		{factory.ServiceNameDragonBall, "/dragonball", s.getDragonBallRoute()},
+		{factory.ServiceNameFortune, "/fortune", s.getFortuneRoute()},
+		{factory.ServiceNameTimeZone, "/timezone", s.getTimeZoneRoute()},
+	}
+}
 
-	fortuneGroup := router.Group("/fortune")
-	applyRoutes(fortuneGroup, s.getFortuneRoute())
+func newRouter(s *Server) *gin.Engine {
+	router := logger_util.NewGinWithLogrus(logger.GinLog)
 
-	timeZoneGroup := router.Group("/timezone")
-	applyRoutes(timeZoneGroup, s.getTimeZoneRoute())
+	// Add routes to each api group, disabled groups answer 404 until serviceNameList enables them
+	for _, group := range s.getRouteGroups() {
+		if !s.Config().IsServiceEnabled(group.ServiceName) {
+			logger.SBILog.Infof("Service [%s] is disabled", group.ServiceName)
+		}
+		s.applyRoutes(router.Group(group.Prefix), group.ServiceName, group.Routes)
+	}
+	s.applyRoutes(router.Group("/nf-management"), factory.ServiceNameManagement, s.getManagementRoute())
+
+	// Use
+	// curl -X GET http://127.0.0.163:8000/openapi.json -w "\n"
+	router.GET("/openapi.json", s.HTTPGetOpenAPI)
+
+	// Probes of the orchestrator, served whatever serviceNameList and OAuth2 say
+	// curl -X GET http://127.0.0.163:8000/healthz -w "\n"
+	// curl -X GET http://127.0.0.163:8000/readyz -w "\n"
+	router.GET("/healthz", s.HTTPGetHealth)
+	router.GET("/readyz", s.HTTPGetReadiness)
+
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		util.SendProblem(c, http.StatusNotFound, util.CauseResourceUriNotFound,
+			fmt.Sprintf("no resource at %s", c.Request.URL.Path))
+	})
+	router.NoMethod(func(c *gin.Context) {
+		util.SendProblem(c, http.StatusMethodNotAllowed, util.CauseMethodNotAllowed,
+			fmt.Sprintf("method %s is not allowed on %s", c.Request.Method, c.Request.URL.Path))
+	})
 
 	return router
 }
 
-func bindRouter(nf app.App, router *gin.Engine, tlsKeyLogPath string) (*http.Server, error) {
+// serviceEnabled rejects requests to a route group that serviceNameList does not enable.
+// It is checked per request so a config reload can toggle route groups.
+func (s *Server) serviceEnabled(serviceName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.Config().IsServiceEnabled(serviceName) {
+			util.SendProblem(c, http.StatusNotFound, util.CauseResourceUriNotFound,
+				fmt.Sprintf("service %s is disabled", serviceName))
+			return
+		}
+		c.Next()
+	}
+}
+
+// bindRouter returns the SBI server and the TCP addresses it listens on, one per binding IP,
+// none when the SBI is only served on its Unix socket.
+func bindRouter(nf app.App, router *gin.Engine, tlsKeyLogPath string) (*http.Server, []string, error) {
 	sbiConfig := nf.Config().Configuration.Sbi
-	bindAddr := fmt.Sprintf("%s:%d", sbiConfig.BindingIPv4, sbiConfig.Port)
+	var bindAddrs []string
+	if !sbiConfig.UnixSocket.IsOnly() {
+		port := strconv.Itoa(sbiConfig.Port)
+		bindAddrs = []string{net.JoinHostPort("", port)}
+		if ips := sbiConfig.GetBindingIPs(); len(ips) > 0 {
+			bindAddrs = make([]string, 0, len(ips))
+			for _, ip := range ips {
+				bindAddrs = append(bindAddrs, net.JoinHostPort(ip, port))
+			}
+		}
+	}
+
+	var serverAddr string
+	if len(bindAddrs) > 0 {
+		serverAddr = bindAddrs[0]
+	} else {
+		serverAddr = "unix:" + sbiConfig.UnixSocket.Path
+	}
 	// Use http2 for all SBI communication
-	return httpwrapper.NewHttp2Server(bindAddr, tlsKeyLogPath, router)
+	server, err := httpwrapper.NewHttp2Server(serverAddr, tlsKeyLogPath, router)
+	return server, bindAddrs, err
 }
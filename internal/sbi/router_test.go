@@ -0,0 +1,158 @@
+package sbi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi/models"
+)
+
+func Test_RouterProblemDetails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	nfApp := NewMocknfApp(mockCtrl)
+	nfApp.EXPECT().Config().Return(&factory.Config{
+		Configuration: &factory.Configuration{
+			Sbi: &factory.Sbi{Port: 8000},
+		},
+	}).AnyTimes()
+	server := NewServer(nfApp, "")
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedCause  string
+	}{
+		{
+			name:           "Unknown path",
+			method:         http.MethodGet,
+			path:           "/unknown",
+			expectedStatus: http.StatusNotFound,
+			expectedCause:  "RESOURCE_URI_STRUCTURE_NOT_FOUND",
+		},
+		{
+			name:           "Method not allowed",
+			method:         http.MethodDelete,
+			path:           "/fortune/",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCause:  "METHOD_NOT_ALLOWED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			server.router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+			var problem models.ProblemDetails
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+			assert.Equal(t, tt.expectedCause, problem.Cause)
+			assert.Equal(t, tt.path, problem.Instance)
+		})
+	}
+}
+
+func Test_RouterServiceGating(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	nfApp := NewMocknfApp(mockCtrl)
+	cfg := &factory.Config{
+		Configuration: &factory.Configuration{
+			Sbi:             &factory.Sbi{Port: 8000},
+			ServiceNameList: []string{factory.ServiceNameTimeZone},
+		},
+	}
+	nfApp.EXPECT().Config().Return(cfg).AnyTimes()
+	server := NewServer(nfApp, "")
+
+	serve := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodGet, "/default/", nil)
+		require.NoError(t, err)
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := serve()
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	var problem models.ProblemDetails
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(t, "RESOURCE_URI_STRUCTURE_NOT_FOUND", problem.Cause)
+
+	// A reload enabling the service opens the route group without rebuilding the router
+	cfg.SetServiceNameList([]string{factory.ServiceNameDefault})
+	assert.Equal(t, http.StatusOK, serve().Code)
+}
+
+func Test_ListenEveryBindingIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	nfApp := NewMocknfApp(mockCtrl)
+	nfApp.EXPECT().Config().Return(&factory.Config{
+		Configuration: &factory.Configuration{
+			Sbi: &factory.Sbi{
+				Scheme:          "http",
+				BindingIPv4:     "127.0.0.1",
+				BindingIPv6:     "::1",
+				ListenAddresses: []string{"127.0.0.1"},
+				Port:            0,
+			},
+		},
+	}).AnyTimes()
+	server := NewServer(nfApp, "")
+	assert.Equal(t, []string{"127.0.0.1:0", "[::1]:0"}, server.bindAddrs)
+
+	listeners, err := server.listen()
+	require.NoError(t, err)
+	require.Len(t, listeners, 2)
+	for _, listener := range listeners {
+		go func() {
+			_ = server.serve(listener)
+		}()
+	}
+	defer server.shutdownHttpServer()
+
+	for _, listener := range listeners {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/healthz")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, listener.Addr().String())
+	}
+}
+
+func Test_MetricsBindAddrIPv6(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	nfApp := NewMocknfApp(mockCtrl)
+	nfApp.EXPECT().Config().Return(&factory.Config{
+		Configuration: &factory.Configuration{
+			Sbi:     &factory.Sbi{Port: 8000},
+			Metrics: &factory.Metrics{BindingIPv4: "::1"},
+		},
+	}).AnyTimes()
+	nfApp.EXPECT().Context().Return(&nf_context.NFContext{}).AnyTimes()
+	server := NewServer(nfApp, "")
+
+	require.NotNil(t, server.metricsServer)
+	assert.Equal(t, "[::1]:9091", server.metricsServer.Addr)
+}
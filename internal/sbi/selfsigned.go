@@ -0,0 +1,142 @@
+package sbi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+)
+
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// ensureSelfSignedCert generates a self-signed certificate for the NF name and the binding
+// addresses at the pem and key paths of tlsCfg, unless both files exist already. A binding
+// address is an IP or a hostname, the unspecified addresses are left out.
+func ensureSelfSignedCert(tlsCfg *factory.Tls, nfName string, bindingIPs []string) error {
+	pemPath, keyPath := tlsCfg.GetPem(), tlsCfg.GetKey()
+	pemExists, err := fileExists(pemPath)
+	if err != nil {
+		return err
+	}
+	keyExists, err := fileExists(keyPath)
+	if err != nil {
+		return err
+	}
+	if pemExists && keyExists {
+		return nil
+	}
+	if pemExists || keyExists {
+		return fmt.Errorf("only one of [%s] and [%s] exists, remove it to generate a self-signed certificate",
+			pemPath, keyPath)
+	}
+
+	certPEM, keyPEM, fingerprint, err := newSelfSignedCert(nfName, bindingIPs, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := writeNewFile(keyPath, keyPEM, 0o600); err != nil {
+		return err
+	}
+	if err := writeNewFile(pemPath, certPEM, 0o644); err != nil {
+		return err
+	}
+
+	logger.SBILog.Infof("Generated self-signed certificate [%s] (SHA-256 fingerprint %s)", pemPath, fingerprint)
+	return nil
+}
+
+// newSelfSignedCert returns the PEM certificate and PKCS #8 key, and the SHA-256 fingerprint of the certificate.
+func newSelfSignedCert(nfName string, bindingIPs []string, now time.Time) ([]byte, []byte, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("generate key: %+v", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("generate serial number: %+v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: nfName},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(selfSignedValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, bindingIP := range bindingIPs {
+		if ip := net.ParseIP(bindingIP); ip != nil {
+			if !ip.IsUnspecified() {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			}
+		} else {
+			template.DNSNames = append(template.DNSNames, bindingIP)
+		}
+	}
+	if nfName != "" {
+		template.DNSNames = append(template.DNSNames, nfName)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("create certificate: %+v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("marshal key: %+v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, certFingerprint(der), nil
+}
+
+// certFingerprint formats the SHA-256 digest of a DER certificate as colon separated hex.
+func certFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+func fileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return false, err
+}
+
+func writeNewFile(path string, content []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,110 @@
+package sbi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func Test_EnsureSelfSignedCert(t *testing.T) {
+	dir := t.TempDir()
+	tlsCfg := &factory.Tls{
+		Pem:        filepath.Join(dir, "cert", "nf.pem"),
+		Key:        filepath.Join(dir, "cert", "nf.key"),
+		SelfSigned: true,
+	}
+
+	require.NoError(t, ensureSelfSignedCert(tlsCfg, "ANYA", []string{"127.0.0.163", "::1"}))
+
+	pair, err := tls.LoadX509KeyPair(tlsCfg.Pem, tlsCfg.Key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "ANYA", cert.Subject.CommonName)
+	assert.Equal(t, []string{"ANYA"}, cert.DNSNames)
+	require.Len(t, cert.IPAddresses, 2)
+	assert.True(t, cert.IPAddresses[0].Equal(net.ParseIP("127.0.0.163")))
+	assert.True(t, cert.IPAddresses[1].Equal(net.ParseIP("::1")))
+	assert.NoError(t, cert.VerifyHostname("127.0.0.163"))
+
+	info, err := os.Stat(tlsCfg.Key)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	t.Run("Existing Certificate Is Kept", func(t *testing.T) {
+		before, err := os.ReadFile(tlsCfg.Pem)
+		require.NoError(t, err)
+		require.NoError(t, ensureSelfSignedCert(tlsCfg, "ANYA", []string{"127.0.0.163", "::1"}))
+		after, err := os.ReadFile(tlsCfg.Pem)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("Lone Key", func(t *testing.T) {
+		require.NoError(t, os.Remove(tlsCfg.Pem))
+		err := ensureSelfSignedCert(tlsCfg, "ANYA", []string{"127.0.0.163", "::1"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only one of")
+	})
+}
+
+func Test_NewSelfSignedCertHostname(t *testing.T) {
+	certPEM, keyPEM, fingerprint, err := newSelfSignedCert("ANYA", []string{"anya.local"}, time.Now())
+	require.NoError(t, err)
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"anya.local", "ANYA"}, cert.DNSNames)
+	assert.Empty(t, cert.IPAddresses)
+	assert.Equal(t, certFingerprint(pair.Certificate[0]), fingerprint)
+	assert.Len(t, fingerprint, 32*3-1)
+}
+
+func Test_NewServerSelfSignedUsesResolvedAddresses(t *testing.T) {
+	dir := t.TempDir()
+	tlsCfg := &factory.Tls{
+		Pem:        filepath.Join(dir, "nf.pem"),
+		Key:        filepath.Join(dir, "nf.key"),
+		SelfSigned: true,
+	}
+
+	mockCtrl := gomock.NewController(t)
+	nfApp := NewMocknfApp(mockCtrl)
+	nfApp.EXPECT().Config().Return(&factory.Config{
+		Configuration: &factory.Configuration{
+			NfName: "ANYA",
+			Sbi: &factory.Sbi{
+				Scheme: "https",
+				// the name of the environment variable holding the address
+				BindingIPv4: "POD_IP",
+				Port:        8000,
+				Tls:         tlsCfg,
+			},
+		},
+	}).AnyTimes()
+	nfApp.EXPECT().Context().Return(&nf_context.NFContext{
+		BindingIPv4: "10.0.0.7",
+		BindingIPs:  []string{"10.0.0.7", "anya.local", "0.0.0.0"},
+	}).AnyTimes()
+	NewServer(nfApp, "")
+
+	pair, err := tls.LoadX509KeyPair(tlsCfg.Pem, tlsCfg.Key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	require.Len(t, cert.IPAddresses, 1)
+	assert.True(t, cert.IPAddresses[0].Equal(net.ParseIP("10.0.0.7")))
+	assert.Equal(t, []string{"anya.local", "ANYA"}, cert.DNSNames)
+}
@@ -2,12 +2,15 @@ package sbi
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/internal/metrics"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
 	"github.com/Alonza0314/nf-example/pkg/app"
 	"github.com/Alonza0314/nf-example/pkg/factory"
@@ -17,28 +20,78 @@ import (
 type nfApp interface {
 	app.App
 	Processor() *processor.Processor
+	ReloadConfig() (*factory.ReloadResult, error)
+	Readiness() app.Readiness
 }
 
 type Server struct {
 	nfApp
 
-	httpServer *http.Server
-	router     *gin.Engine
+	// httpServer serves the SBI on every address of bindAddrs
+	httpServer    *http.Server
+	bindAddrs     []string
+	router        *gin.Engine
+	tokenVerifier *tokenVerifier
+	// clientVerifier is nil unless tls.clientCa enables mutual TLS
+	clientVerifier *clientVerifier
+	// certWatchDone stops the certificate watch of the https server on Shutdown
+	certWatchDone chan struct{}
+
+	// metrics is nil when configuration.metrics is omitted
+	metrics       *metrics.Metrics
+	metricsServer *http.Server
 }
 
 func NewServer(nf nfApp, tlsKeyLogPath string) *Server {
 	s := &Server{
-		nfApp: nf,
+		nfApp:         nf,
+		certWatchDone: make(chan struct{}),
+	}
+
+	if oauth2 := nf.Config().Configuration.Sbi.OAuth2; oauth2 != nil {
+		verifier, err := newTokenVerifier(oauth2)
+		if err != nil {
+			logger.SBILog.Errorf("OAuth2 setup Error: %+v", err)
+			panic("Server initialization failed")
+		}
+		s.tokenVerifier = verifier
+	}
+
+	if sbiConfig := nf.Config().Configuration.Sbi; sbiConfig.Scheme == "https" && sbiConfig.Tls != nil && sbiConfig.Tls.SelfSigned {
+		// the addresses resolved from the environment are the ones announced to the NRF and dialed by peers
+		if err := ensureSelfSignedCert(sbiConfig.Tls, nf.Config().Configuration.NfName, nf.Context().BindingIPs); err != nil {
+			logger.SBILog.Errorf("Self-signed certificate Error: %+v", err)
+			panic("Server initialization failed")
+		}
+	}
+
+	if sbiConfig := nf.Config().Configuration.Sbi; sbiConfig.Scheme == "https" &&
+		sbiConfig.Tls.GetClientAuth() != factory.TlsClientAuthNone {
+		verifier, err := newClientVerifier(sbiConfig.Tls)
+		if err != nil {
+			logger.SBILog.Errorf("Mutual TLS setup Error: %+v", err)
+			panic("Server initialization failed")
+		}
+		s.clientVerifier = verifier
+	}
+
+	if addr := nf.Config().GetMetricsBindAddr(); addr != "" {
+		s.metrics = metrics.NewMetrics(nf.Config().GetMetricsNamespace(), nf.Context())
+		s.metricsServer = newMetricsServer(addr, s.metrics)
 	}
 
 	s.router = newRouter(s)
 
-	server, err := bindRouter(nf, s.router, tlsKeyLogPath)
+	server, bindAddrs, err := bindRouter(nf, s.router, tlsKeyLogPath)
 	s.httpServer = server
+	s.bindAddrs = bindAddrs
 	if err != nil {
 		logger.SBILog.Errorf("bind Router Error: %+v", err)
 		panic("Server initialization failed")
 	}
+	if s.clientVerifier != nil {
+		s.clientVerifier.apply(s.httpServer)
+	}
 
 	return s
 }
@@ -46,54 +99,132 @@ func NewServer(nf nfApp, tlsKeyLogPath string) *Server {
 func (s *Server) Run(wg *sync.WaitGroup) {
 	logger.SBILog.Info("Starting server...")
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		logger.SBILog.Infof("Start SBI server (listen on %s)", s.httpServer.Addr)
+	listeners, err := s.listen()
+	if err != nil {
+		logger.SBILog.Panicf("HTTP server setup failed: %+v", err)
+	}
+	for _, listener := range listeners {
+		wg.Add(1)
+		go func(listener net.Listener) {
+			defer wg.Done()
+			logger.SBILog.Infof("Start SBI server (listen on %s)", listener.Addr())
+
+			err := s.serve(listener)
+			if err != http.ErrServerClosed {
+				logger.SBILog.Panicf("HTTP server setup failed: %+v", err)
+			}
+			logger.SBILog.Infof("SBI server (listen on %s) stopped", listener.Addr())
+		}(listener)
+	}
 
-		err := s.serve()
-		if err != http.ErrServerClosed {
-			logger.SBILog.Panicf("HTTP server setup failed: %+v", err)
-		}
-		logger.SBILog.Infof("SBI server (listen on %s) stopped", s.httpServer.Addr)
-	}()
-}
+	if s.metricsServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.MetricsLog.Infof("Start metrics server (listen on %s)", s.metricsServer.Addr)
 
-func (s *Server) unsecureServe() error {
-	return s.httpServer.ListenAndServe()
+			err := s.metricsServer.ListenAndServe()
+			if err != http.ErrServerClosed {
+				logger.MetricsLog.Errorf("Metrics server failed: %+v", err)
+			}
+			logger.MetricsLog.Infof("Metrics server (listen on %s) stopped", s.metricsServer.Addr)
+		}()
+	}
 }
 
-func (s *Server) secureServe() error {
-	sbiConfig := s.Config().Configuration.Sbi
+func newMetricsServer(addr string, m *metrics.Metrics) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	return &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
 
-	pemPath := sbiConfig.Tls.Pem
-	if pemPath == "" {
-		pemPath = factory.NfDefaultCertPemPath
+// listen opens a listener per SBI address and the Unix socket, and loads the certificate in https,
+// so an address in use or a bad certificate fails the start. The listeners share httpServer and its router.
+func (s *Server) listen() ([]net.Listener, error) {
+	switch scheme := s.Config().Configuration.Sbi.Scheme; scheme {
+	case "http":
+	case "https":
+		if err := s.setupCertificate(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid SBI scheme: %s", scheme)
 	}
 
-	keyPath := sbiConfig.Tls.Key
-	if keyPath == "" {
-		keyPath = factory.NfDefaultPrivateKeyPath
+	listeners := make([]net.Listener, 0, len(s.bindAddrs)+1)
+	fail := func(err error) ([]net.Listener, error) {
+		for _, opened := range listeners {
+			opened.Close()
+		}
+		return nil, err
 	}
+	for _, addr := range s.bindAddrs {
+		listener, err := net.Listen(listenNetwork(addr), addr)
+		if err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, listener)
+	}
+	if unixSocket := s.Config().Configuration.Sbi.UnixSocket; unixSocket != nil {
+		listener, err := listenUnix(unixSocket)
+		if err != nil {
+			return fail(err)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
 
-	return s.httpServer.ListenAndServeTLS(pemPath, keyPath)
+// listenNetwork keeps an IPv4 and an IPv6 wildcard listener on the same port apart, tcp6 only
+// accepts IPv6 connections. Host names resolve to either family.
+func listenNetwork(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "tcp"
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return "tcp"
+	case ip.To4() != nil:
+		return "tcp4"
+	default:
+		return "tcp6"
+	}
 }
 
-func (s *Server) serve() error {
-	sbiConfig := s.Config().Configuration.Sbi
+func (s *Server) setupCertificate() error {
+	tlsConfig := s.Config().Configuration.Sbi.Tls
+	reloader, err := newCertReloader(tlsConfig.GetPem(), tlsConfig.GetKey())
+	if err != nil {
+		return err
+	}
+	go reloader.watch(s.certWatchDone)
 
-	switch sbiConfig.Scheme {
-	case "http":
-		return s.unsecureServe()
-	case "https":
-		return s.secureServe()
-	default:
-		return fmt.Errorf("invalid SBI scheme: %s", sbiConfig.Scheme)
+	if s.httpServer.TLSConfig == nil {
+		s.httpServer.TLSConfig = &tls.Config{}
 	}
+	// the certificate comes from GetCertificate, so it follows pem and key changes without restart
+	s.httpServer.TLSConfig.GetCertificate = reloader.GetCertificate
+	return nil
+}
+
+func (s *Server) serve(listener net.Listener) error {
+	if s.Config().Configuration.Sbi.Scheme == "https" {
+		return s.httpServer.ServeTLS(listener, "", "")
+	}
+	return s.httpServer.Serve(listener)
 }
 
 func (s *Server) Shutdown() {
+	close(s.certWatchDone)
 	s.shutdownHttpServer()
+	s.removeUnixSocket()
+	s.shutdownMetricsServer()
 }
 
 func (s *Server) shutdownHttpServer() {
@@ -112,3 +243,18 @@ func (s *Server) shutdownHttpServer() {
 		logger.SBILog.Errorf("HTTP server shutdown failed: %+v", err)
 	}
 }
+
+func (s *Server) shutdownMetricsServer() {
+	const shutdownTimeout time.Duration = 2 * time.Second
+
+	if s.metricsServer == nil {
+		return
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := s.metricsServer.Shutdown(shutdownCtx); err != nil {
+		logger.MetricsLog.Errorf("Metrics server shutdown failed: %+v", err)
+	}
+}
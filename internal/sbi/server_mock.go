@@ -14,6 +14,7 @@ import (
 
 	context "github.com/Alonza0314/nf-example/internal/context"
 	processor "github.com/Alonza0314/nf-example/internal/sbi/processor"
+	app "github.com/Alonza0314/nf-example/pkg/app"
 	factory "github.com/Alonza0314/nf-example/pkg/factory"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -83,6 +84,35 @@ func (mr *MocknfAppMockRecorder) Processor() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Processor", reflect.TypeOf((*MocknfApp)(nil).Processor))
 }
 
+// Readiness mocks base method.
+func (m *MocknfApp) Readiness() app.Readiness {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Readiness")
+	ret0, _ := ret[0].(app.Readiness)
+	return ret0
+}
+
+// Readiness indicates an expected call of Readiness.
+func (mr *MocknfAppMockRecorder) Readiness() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Readiness", reflect.TypeOf((*MocknfApp)(nil).Readiness))
+}
+
+// ReloadConfig mocks base method.
+func (m *MocknfApp) ReloadConfig() (*factory.ReloadResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReloadConfig")
+	ret0, _ := ret[0].(*factory.ReloadResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReloadConfig indicates an expected call of ReloadConfig.
+func (mr *MocknfAppMockRecorder) ReloadConfig() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReloadConfig", reflect.TypeOf((*MocknfApp)(nil).ReloadConfig))
+}
+
 // SetLogEnable mocks base method.
 func (m *MocknfApp) SetLogEnable(enable bool) {
 	m.ctrl.T.Helper()
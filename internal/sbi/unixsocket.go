@@ -0,0 +1,80 @@
+package sbi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+)
+
+// listenUnix opens the Unix socket of the SBI. A socket file left behind by a crashed run is
+// replaced, a file of another kind or a socket still accepting connections is not.
+//
+// The socket is bound in a private directory and moved to its path once it has its mode,
+// so it is never reachable with the permissions the umask gives it.
+func listenUnix(unixSocket *factory.UnixSocket) (net.Listener, error) {
+	path := unixSocket.Path
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != os.ModeSocket {
+			return nil, fmt.Errorf("unix socket [%s] exists and is not a socket", path)
+		}
+		if conn, dialErr := net.Dial("unix", path); dialErr == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unix socket [%s] is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+		logger.SBILog.Infof("Removed stale unix socket [%s]", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	// MkdirTemp creates the directory with mode 0700
+	tmpDir, err := os.MkdirTemp(dir, ".sbi-sock-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpPath := filepath.Join(tmpDir, "sock")
+	listener, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	// closing would unlink tmpPath, the socket at path is removed by removeUnixSocket
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, unixSocket.GetMode()); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// removeUnixSocket deletes the socket file once the SBI server is down.
+func (s *Server) removeUnixSocket() {
+	unixSocket := s.Config().Configuration.Sbi.UnixSocket
+	if unixSocket == nil {
+		return
+	}
+
+	info, err := os.Lstat(unixSocket.Path)
+	if err != nil || info.Mode().Type() != os.ModeSocket {
+		return
+	}
+	if err := os.Remove(unixSocket.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.SBILog.Errorf("Remove unix socket [%s] failed: %+v", unixSocket.Path, err)
+	}
+}
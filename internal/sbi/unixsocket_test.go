@@ -0,0 +1,111 @@
+package sbi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func setupUnixSocketServer(t *testing.T, unixSocket *factory.UnixSocket) *Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mockCtrl := gomock.NewController(t)
+	nfApp := NewMocknfApp(mockCtrl)
+	nfApp.EXPECT().Config().Return(&factory.Config{
+		Configuration: &factory.Configuration{
+			Sbi: &factory.Sbi{
+				Scheme:     "http",
+				Port:       8000,
+				UnixSocket: unixSocket,
+			},
+		},
+	}).AnyTimes()
+	return NewServer(nfApp, "")
+}
+
+func newUnixSocketClient(path string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+}
+
+func Test_UnixSocketListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "anya.sock")
+	server := setupUnixSocketServer(t, &factory.UnixSocket{Path: path, Mode: "0600", Only: true})
+	assert.Empty(t, server.bindAddrs)
+
+	listeners, err := server.listen()
+	require.NoError(t, err)
+	require.Len(t, listeners, 1)
+	go func() {
+		_ = server.serve(listeners[0])
+	}()
+
+	info, err := os.Lstat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSocket, info.Mode().Type())
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "the private bind directory is removed")
+	assert.Equal(t, "anya.sock", entries[0].Name())
+
+	resp, err := newUnixSocketClient(path).Get("http://anya/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	t.Run("Socket In Use", func(t *testing.T) {
+		_, err := listenUnix(&factory.UnixSocket{Path: path})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is in use")
+	})
+
+	server.Shutdown()
+	_, err = os.Lstat(path)
+	assert.True(t, os.IsNotExist(err), "socket file is removed on Shutdown")
+}
+
+func Test_ListenUnixStaleFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Stale Socket", func(t *testing.T) {
+		path := filepath.Join(dir, "stale.sock")
+		stale, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		require.NoError(t, stale.Close())
+
+		listener, err := listenUnix(&factory.UnixSocket{Path: path})
+		require.NoError(t, err)
+		defer listener.Close()
+
+		info, err := os.Lstat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(factory.NfDefaultUnixSocketMode), info.Mode().Perm())
+	})
+
+	t.Run("Regular File", func(t *testing.T) {
+		path := filepath.Join(dir, "anya.sock")
+		require.NoError(t, os.WriteFile(path, []byte("keep me"), 0o600))
+
+		_, err := listenUnix(&factory.UnixSocket{Path: path})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is not a socket")
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "keep me", string(content))
+	})
+}
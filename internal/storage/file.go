@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	opPut      = "put"
+	opDelete   = "delete"
+	opSequence = "seq"
+)
+
+// logRecord is one line of the append-only JSON log.
+type logRecord struct {
+	Op         string          `json:"op"`
+	Collection string          `json:"collection"`
+	Key        string          `json:"key,omitempty"`
+	Value      json.RawMessage `json:"value,omitempty"`
+	Sequence   uint64          `json:"seq,omitempty"`
+}
+
+// fileStorage serves reads from memory and appends every write to a JSON log,
+// which is replayed and compacted when the storage is opened.
+type fileStorage struct {
+	mu   sync.Mutex
+	mem  *memoryStorage
+	path string
+	file *os.File
+}
+
+var _ Storage = &fileStorage{}
+
+func NewFileStorage(path string) (Storage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o775); err != nil {
+		return nil, fmt.Errorf("create storage directory: %w", err)
+	}
+
+	f := &fileStorage{
+		mem:  newMemoryStorage(),
+		path: path,
+	}
+	if err := f.replay(); err != nil {
+		return nil, err
+	}
+	if err := f.compact(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open storage file: %w", err)
+	}
+	f.file = file
+	return f, nil
+}
+
+func (f *fileStorage) replay() error {
+	file, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("open storage file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var record logRecord
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("storage file %s line %d: %w", f.path, line, err)
+		}
+		switch record.Op {
+		case opPut:
+			f.mem.put(record.Collection, record.Key, record.Value)
+		case opDelete:
+			f.mem.delete(record.Collection, record.Key)
+		case opSequence:
+			f.mem.sequences[record.Collection] = record.Sequence
+		default:
+			return fmt.Errorf("storage file %s line %d: unknown op %q", f.path, line, record.Op)
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the log so it only holds the current state.
+func (f *fileStorage) compact() error {
+	tmpPath := f.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("create storage snapshot: %w", err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for collection, sequence := range f.mem.sequences {
+		if err = encoder.Encode(logRecord{Op: opSequence, Collection: collection, Sequence: sequence}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	for name, collection := range f.mem.collections {
+		for _, key := range collection.keys {
+			record := logRecord{Op: opPut, Collection: name, Key: key, Value: collection.values[key]}
+			if err = encoder.Encode(record); err != nil {
+				tmp.Close()
+				return err
+			}
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, f.path)
+}
+
+func (f *fileStorage) append(record logRecord) error {
+	if f.file == nil {
+		return errors.New("storage is closed")
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err = f.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write storage file: %w", err)
+	}
+	return f.file.Sync()
+}
+
+func (f *fileStorage) Get(collection, key string) ([]byte, bool, error) {
+	return f.mem.Get(collection, key)
+}
+
+func (f *fileStorage) Put(collection, key string, value []byte) error {
+	if !json.Valid(value) {
+		return fmt.Errorf("put %s/%s: value is not valid JSON", collection, key)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.append(logRecord{Op: opPut, Collection: collection, Key: key, Value: value}); err != nil {
+		return err
+	}
+	return f.mem.Put(collection, key, value)
+}
+
+func (f *fileStorage) Delete(collection, key string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok, _ := f.mem.Get(collection, key); !ok {
+		return false, nil
+	}
+	if err := f.append(logRecord{Op: opDelete, Collection: collection, Key: key}); err != nil {
+		return false, err
+	}
+	return f.mem.Delete(collection, key)
+}
+
+func (f *fileStorage) List(collection string) ([]Entry, error) {
+	return f.mem.List(collection)
+}
+
+func (f *fileStorage) Len(collection string) (int, error) {
+	return f.mem.Len(collection)
+}
+
+func (f *fileStorage) NextSequence(collection string) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sequence, err := f.mem.NextSequence(collection)
+	if err != nil {
+		return 0, err
+	}
+	if err = f.append(logRecord{Op: opSequence, Collection: collection, Sequence: sequence}); err != nil {
+		return 0, err
+	}
+	return sequence, nil
+}
+
+// Ping checks the log file is still open and present, writes fail once it was removed or closed.
+func (f *fileStorage) Ping() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return errors.New("storage is closed")
+	}
+	if _, err := os.Stat(f.path); err != nil {
+		return fmt.Errorf("storage file: %w", err)
+	}
+	return nil
+}
+
+func (f *fileStorage) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
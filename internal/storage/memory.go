@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"slices"
+	"sync"
+)
+
+type memoryCollection struct {
+	keys   []string
+	values map[string][]byte
+}
+
+type memoryStorage struct {
+	mu          sync.RWMutex
+	collections map[string]*memoryCollection
+	sequences   map[string]uint64
+}
+
+var _ Storage = &memoryStorage{}
+
+func NewMemoryStorage() Storage {
+	return newMemoryStorage()
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		collections: make(map[string]*memoryCollection),
+		sequences:   make(map[string]uint64),
+	}
+}
+
+func (m *memoryStorage) Get(collection, key string) ([]byte, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.collections[collection]
+	if !ok {
+		return nil, false, nil
+	}
+	value, ok := c.values[key]
+	if !ok {
+		return nil, false, nil
+	}
+	return slices.Clone(value), true, nil
+}
+
+func (m *memoryStorage) Put(collection, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.put(collection, key, value)
+	return nil
+}
+
+func (m *memoryStorage) put(collection, key string, value []byte) {
+	c, ok := m.collections[collection]
+	if !ok {
+		c = &memoryCollection{values: make(map[string][]byte)}
+		m.collections[collection] = c
+	}
+	if _, exists := c.values[key]; !exists {
+		c.keys = append(c.keys, key)
+	}
+	c.values[key] = slices.Clone(value)
+}
+
+func (m *memoryStorage) Delete(collection, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.delete(collection, key), nil
+}
+
+func (m *memoryStorage) delete(collection, key string) bool {
+	c, ok := m.collections[collection]
+	if !ok {
+		return false
+	}
+	if _, ok = c.values[key]; !ok {
+		return false
+	}
+	delete(c.values, key)
+	c.keys = slices.DeleteFunc(c.keys, func(k string) bool { return k == key })
+	return true
+}
+
+func (m *memoryStorage) List(collection string) ([]Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.collections[collection]
+	if !ok {
+		return []Entry{}, nil
+	}
+	entries := make([]Entry, 0, len(c.keys))
+	for _, key := range c.keys {
+		entries = append(entries, Entry{Key: key, Value: slices.Clone(c.values[key])})
+	}
+	return entries, nil
+}
+
+func (m *memoryStorage) Len(collection string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if c, ok := m.collections[collection]; ok {
+		return len(c.keys), nil
+	}
+	return 0, nil
+}
+
+func (m *memoryStorage) NextSequence(collection string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sequences[collection]++
+	return m.sequences[collection], nil
+}
+
+func (m *memoryStorage) Ping() error {
+	return nil
+}
+
+func (m *memoryStorage) Close() error {
+	return nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Alonza0314/nf-example/pkg/factory"
+)
+
+// Entry is a single key/value pair of a collection.
+type Entry struct {
+	Key   string
+	Value []byte
+}
+
+// Storage keeps the NF data in named collections. Entries of a collection are
+// listed in the order their keys were first written. Implementations are safe
+// for concurrent use.
+type Storage interface {
+	Get(collection, key string) ([]byte, bool, error)
+	Put(collection, key string, value []byte) error
+	Delete(collection, key string) (bool, error)
+	List(collection string) ([]Entry, error)
+	Len(collection string) (int, error)
+	// NextSequence returns a monotonically increasing number for the collection, starting at 1.
+	NextSequence(collection string) (uint64, error)
+	// Ping reports an error when the backend can no longer serve the data.
+	Ping() error
+	Close() error
+}
+
+// New opens the backend selected in the storage configuration, defaulting to memory.
+func New(cfg *factory.Storage) (Storage, error) {
+	if cfg == nil {
+		return NewMemoryStorage(), nil
+	}
+
+	switch cfg.Type {
+	case "", factory.StorageTypeMemory:
+		return NewMemoryStorage(), nil
+	case factory.StorageTypeFile:
+		path := cfg.Path
+		if path == "" {
+			path = factory.NfDefaultStoragePath
+		}
+		return NewFileStorage(path)
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", cfg.Type)
+	}
+}
+
+// Load decodes the value stored under key.
+func Load[T any](s Storage, collection, key string) (T, bool, error) {
+	var value T
+	raw, ok, err := s.Get(collection, key)
+	if err != nil || !ok {
+		return value, ok, err
+	}
+	if err = json.Unmarshal(raw, &value); err != nil {
+		return value, false, fmt.Errorf("decode %s/%s: %w", collection, key, err)
+	}
+	return value, true, nil
+}
+
+// LoadAll decodes every value of the collection in insertion order.
+func LoadAll[T any](s Storage, collection string) ([]T, error) {
+	entries, err := s.List(collection)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]T, 0, len(entries))
+	for _, entry := range entries {
+		var value T
+		if err = json.Unmarshal(entry.Value, &value); err != nil {
+			return nil, fmt.Errorf("decode %s/%s: %w", collection, entry.Key, err)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// Store encodes value and writes it under key.
+func Store(s Storage, collection, key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encode %s/%s: %w", collection, key, err)
+	}
+	return s.Put(collection, key, raw)
+}
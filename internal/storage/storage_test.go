@@ -0,0 +1,121 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Alonza0314/nf-example/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBackends(t *testing.T) map[string]storage.Storage {
+	t.Helper()
+	file, err := storage.NewFileStorage(filepath.Join(t.TempDir(), "anya.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+
+	return map[string]storage.Storage{
+		"memory": storage.NewMemoryStorage(),
+		"file":   file,
+	}
+}
+
+func Test_StorageOperations(t *testing.T) {
+	for name, st := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, storage.Store(st, "c", "b", "second"))
+			require.NoError(t, storage.Store(st, "c", "a", "first"))
+			require.NoError(t, storage.Store(st, "c", "b", "updated"))
+
+			values, err := storage.LoadAll[string](st, "c")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"updated", "first"}, values)
+
+			value, ok, err := storage.Load[string](st, "c", "a")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, "first", value)
+
+			_, ok, err = storage.Load[string](st, "c", "missing")
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			deleted, err := st.Delete("c", "b")
+			require.NoError(t, err)
+			assert.True(t, deleted)
+			deleted, err = st.Delete("c", "b")
+			require.NoError(t, err)
+			assert.False(t, deleted)
+
+			count, err := st.Len("c")
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+
+			entries, err := st.List("empty")
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+
+			for want := uint64(1); want <= 3; want++ {
+				seq, err := st.NextSequence("c")
+				require.NoError(t, err)
+				assert.Equal(t, want, seq)
+			}
+		})
+	}
+}
+
+func Test_FileStorageReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anya.db")
+
+	st, err := storage.NewFileStorage(path)
+	require.NoError(t, err)
+	require.NoError(t, storage.Store(st, "c", "x", 1))
+	require.NoError(t, storage.Store(st, "c", "y", 2))
+	require.NoError(t, storage.Store(st, "c", "x", 3))
+	_, err = st.Delete("c", "y")
+	require.NoError(t, err)
+	_, err = st.NextSequence("c")
+	require.NoError(t, err)
+	require.NoError(t, st.Ping())
+	require.NoError(t, st.Close())
+	assert.Error(t, storage.Store(st, "c", "z", 4))
+	assert.EqualError(t, st.Ping(), "storage is closed")
+
+	st, err = storage.NewFileStorage(path)
+	require.NoError(t, err)
+	defer st.Close()
+
+	values, err := storage.LoadAll[int](st, "c")
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, values)
+
+	seq, err := st.NextSequence("c")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), seq)
+
+	// The log is compacted on open, so only the current state is kept.
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), `"delete"`)
+}
+
+func Test_FileStorageCorruptLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anya.db")
+	require.NoError(t, os.WriteFile(path, []byte("not json\n"), 0o600))
+
+	_, err := storage.NewFileStorage(path)
+	assert.Error(t, err)
+}
+
+func Test_FileStoragePingRemovedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anya.db")
+	st, err := storage.NewFileStorage(path)
+	require.NoError(t, err)
+	defer st.Close()
+
+	require.NoError(t, st.Ping())
+	require.NoError(t, os.Remove(path))
+	assert.ErrorIs(t, st.Ping(), os.ErrNotExist)
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+)
+
+const ProblemDetailsContentType = "application/problem+json"
+
+// Causes carried in ProblemDetails.Cause, clients branch on these instead of the detail text.
+const (
+	CauseInvalidMsgFormat     = "INVALID_MSG_FORMAT"
+	CauseMandatoryIeMissing   = "MANDATORY_IE_MISSING"
+	CauseMandatoryIeIncorrect = "MANDATORY_IE_INCORRECT"
+	CauseInvalidQueryParam    = "INVALID_QUERY_PARAM"
+	CauseDataNotFound         = "DATA_NOT_FOUND"
+	CauseDataConflict         = "DATA_CONFLICT"
+	CauseResourceUriNotFound  = "RESOURCE_URI_STRUCTURE_NOT_FOUND"
+	CauseMethodNotAllowed     = "METHOD_NOT_ALLOWED"
+	CauseInvalidToken         = "INVALID_TOKEN"
+	CauseInsufficientScope    = "INSUFFICIENT_SCOPE"
+	CauseClientNotAllowed     = "CLIENT_NOT_ALLOWED"
+	CauseSystemFailure        = "SYSTEM_FAILURE"
+	CauseInvalidConfiguration = "INVALID_CONFIGURATION"
+)
+
+func NewProblemDetails(status int, cause string, detail string, invalidParams ...models.InvalidParam) *models.ProblemDetails {
+	return &models.ProblemDetails{
+		Title:         http.StatusText(status),
+		Status:        int32(status),
+		Detail:        detail,
+		Cause:         cause,
+		InvalidParams: invalidParams,
+	}
+}
+
+// SendProblemDetails writes the problem as application/problem+json and aborts the handler chain.
+func SendProblemDetails(c *gin.Context, problem *models.ProblemDetails) {
+	if problem.Instance == "" && c.Request != nil {
+		problem.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", ProblemDetailsContentType)
+	c.AbortWithStatusJSON(int(problem.Status), problem)
+}
+
+func SendProblem(c *gin.Context, status int, cause string, detail string, invalidParams ...models.InvalidParam) {
+	SendProblemDetails(c, NewProblemDetails(status, cause, detail, invalidParams...))
+}
+
+// SendSystemFailure answers 500 for unexpected errors such as storage failures.
+func SendSystemFailure(c *gin.Context, err error) {
+	SendProblem(c, http.StatusInternalServerError, CauseSystemFailure, err.Error())
+}
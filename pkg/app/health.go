@@ -0,0 +1,23 @@
+package app
+
+// Readiness reports whether the NF can take traffic, it is ready when every check is.
+type Readiness struct {
+	Ready  bool          `json:"ready"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// HealthCheck is one condition of the readiness, Detail tells why it is not ready.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Ready  bool   `json:"ready"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// NewReadiness is ready when every check is.
+func NewReadiness(checks ...HealthCheck) Readiness {
+	readiness := Readiness{Ready: true, Checks: checks}
+	for _, check := range checks {
+		readiness.Ready = readiness.Ready && check.Ready
+	}
+	return readiness
+}
@@ -1,8 +1,16 @@
 package factory
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/Alonza0314/nf-example/internal/logger"
 	"github.com/asaskevich/govalidator"
@@ -11,17 +19,68 @@ import (
 )
 
 const (
-	NfDefaultConfigPath     = "./config/nfcfg.yaml"
-	NfDefaultTLSKeyLogPath  = "./log/nfsslkey.log"
-	NfDefaultCertPemPath    = "./cert/nf.pem"
-	NfDefaultPrivateKeyPath = "./cert/nf.key"
+	NfDefaultConfigPath       = "./config/nfcfg.yaml"
+	NfDefaultTLSKeyLogPath    = "./log/nfsslkey.log"
+	NfDefaultCertPemPath      = "./cert/nf.pem"
+	NfDefaultPrivateKeyPath   = "./cert/nf.key"
+	NfDefaultHeartbeatTimer   = 10
+	NfDefaultStoragePath      = "./data/anya.db"
+	NfDefaultMetricsPort      = 9091
+	NfDefaultMetricsNamespace = "anya"
+	NfDefaultUnixSocketMode   = 0o660
 )
 
+const (
+	TlsClientAuthNone     = "none"
+	TlsClientAuthOptional = "optional"
+	TlsClientAuthRequire  = "require"
+)
+
+// TlsClientAllowAny is the identity of a TlsClient matching every verified client certificate.
+const TlsClientAllowAny = "*"
+
+const (
+	StorageTypeMemory = "memory"
+	StorageTypeFile   = "file"
+)
+
+const (
+	ServiceNameDefault    = "nanya-default"
+	ServiceNameMessage    = "nanya-message"
+	ServiceNameSpyFamily  = "nanya-spyfamily"
+	ServiceNameOnePiece   = "nanya-onepiece"
+	ServiceNameAttendance = "nanya-attendance"
+	ServiceNameTask       = "nanya-task"
+	ServiceNameMsg        = "nanya-msg"
+	ServiceNameDragonBall = "nanya-dragonball"
+	ServiceNameFortune    = "nanya-fortune"
+	ServiceNameTimeZone   = "nanya-timezone"
+	// ServiceNameManagement is always served and cannot be disabled by serviceNameList
+	ServiceNameManagement = "nanya-management"
+)
+
+// AllServiceNames lists every route group the NF can serve, in registration order.
+var AllServiceNames = []string{
+	ServiceNameDefault,
+	ServiceNameMessage,
+	ServiceNameSpyFamily,
+	ServiceNameOnePiece,
+	ServiceNameAttendance,
+	ServiceNameTask,
+	ServiceNameMsg,
+	ServiceNameDragonBall,
+	ServiceNameFortune,
+	ServiceNameTimeZone,
+}
+
 type Config struct {
 	Info          *Info          `yaml:"info" valid:"required"`
 	Configuration *Configuration `yaml:"configuration" valid:"required"`
 	Logger        *Logger        `yaml:"logger" valid:"required"`
 	sync.RWMutex
+
+	// path is the file the config was read from, reloads read it again
+	path string
 }
 
 type Info struct {
@@ -30,8 +89,14 @@ type Info struct {
 }
 
 type Configuration struct {
-	NfName string `yaml:"nfName,omitempty"`
-	Sbi    *Sbi   `yaml:"sbi"`
+	NfName          string   `yaml:"nfName,omitempty"`
+	Sbi             *Sbi     `yaml:"sbi"`
+	ServiceNameList []string `yaml:"serviceNameList,omitempty" valid:"optional"`
+	NrfUri          string   `yaml:"nrfUri,omitempty" valid:"url,optional"`
+	HeartbeatTimer  int32    `yaml:"heartbeatTimer,omitempty" valid:"optional"`
+	Storage         *Storage `yaml:"storage,omitempty" valid:"optional"`
+	Metrics         *Metrics `yaml:"metrics,omitempty" valid:"optional"`
+	Seed            *Seed    `yaml:"seed,omitempty" valid:"optional"`
 }
 
 type Logger struct {
@@ -42,14 +107,72 @@ type Logger struct {
 
 type Sbi struct {
 	Scheme      models.UriScheme `yaml:"scheme"`
-	BindingIPv4 string           `yaml:"bindingIPv4,omitempty" valid:"host,required"`
-	Port        int              `yaml:"port"`
-	Tls         *Tls             `yaml:"tls,omitempty" valid:"optional"`
+	BindingIPv4 string           `yaml:"bindingIPv4,omitempty" valid:"host,optional"`
+	BindingIPv6 string           `yaml:"bindingIPv6,omitempty" valid:"ipv6,optional"`
+	// ListenAddresses are more IPv4 or IPv6 addresses of the SBI, every address gets a listener on Port
+	ListenAddresses []string    `yaml:"listenAddresses,omitempty" valid:"-"`
+	Port            int         `yaml:"port"`
+	UnixSocket      *UnixSocket `yaml:"unixSocket,omitempty" valid:"optional"`
+	// DrainPeriod is how long /readyz fails before the server shuts down, a Go duration
+	DrainPeriod string  `yaml:"drainPeriod,omitempty" valid:"type(string),optional"`
+	Tls         *Tls    `yaml:"tls,omitempty" valid:"optional"`
+	OAuth2      *OAuth2 `yaml:"oauth2,omitempty" valid:"optional"`
+}
+
+// UnixSocket serves the SBI on a Unix domain socket too, for the clients running next to the NF.
+type UnixSocket struct {
+	Path string `yaml:"path,omitempty" valid:"type(string),minstringlength(1),required"`
+	// Mode is the octal permission of the socket file, NfDefaultUnixSocketMode when omitted
+	Mode string `yaml:"mode,omitempty" valid:"type(string),optional"`
+	// Only serves the SBI on the socket alone, without the TCP listeners
+	Only bool `yaml:"only,omitempty" valid:"type(bool)"`
 }
 
 type Tls struct {
 	Pem string `yaml:"pem,omitempty" valid:"type(string),minstringlength(1),required"`
 	Key string `yaml:"key,omitempty" valid:"type(string),minstringlength(1),required"`
+	// SelfSigned generates a self-signed certificate at pem and key when both are missing
+	SelfSigned bool `yaml:"selfSigned,omitempty" valid:"type(bool)"`
+	// ClientCa is the CA bundle verifying client certificates, it enables mutual TLS
+	ClientCa string `yaml:"clientCa,omitempty" valid:"type(string),optional"`
+	// ClientAuth is none, optional or require (default when clientCa is set)
+	ClientAuth string `yaml:"clientAuth,omitempty" valid:"in(none|optional|require),optional"`
+	// ClientAllowList restricts the route groups to the listed client certificates when present
+	ClientAllowList []TlsClient `yaml:"clientAllowList,omitempty" valid:"-"`
+}
+
+// TlsClient lets the client certificates matching Identity, a subject common name or a DNS,
+// URI, email or IP subject alternative name, use the route groups of Services.
+type TlsClient struct {
+	Identity string   `yaml:"identity"`
+	Services []string `yaml:"services"`
+}
+
+type Storage struct {
+	Type string `yaml:"type" valid:"required,in(memory|file)"`
+	Path string `yaml:"path,omitempty" valid:"type(string),optional"`
+}
+
+// Metrics enables the Prometheus metrics listener when present.
+type Metrics struct {
+	BindingIPv4 string `yaml:"bindingIPv4,omitempty" valid:"host,required"`
+	Port        int    `yaml:"port,omitempty" valid:"port,optional"`
+	Namespace   string `yaml:"namespace,omitempty" valid:"type(string),optional"`
+}
+
+// Seed points to the YAML or JSON data files stored in the empty domain collections,
+// a domain without a file gets the built-in data.
+type Seed struct {
+	SpyFamily  string `yaml:"spyFamily,omitempty" valid:"type(string),optional"`
+	DragonBall string `yaml:"dragonBall,omitempty" valid:"type(string),optional"`
+	Fortune    string `yaml:"fortune,omitempty" valid:"type(string),optional"`
+	TimeZone   string `yaml:"timeZone,omitempty" valid:"type(string),optional"`
+}
+
+// OAuth2 enables access token validation on every route group when present.
+type OAuth2 struct {
+	Issuer    string `yaml:"issuer,omitempty" valid:"type(string),minstringlength(1),required"`
+	PublicKey string `yaml:"publicKey,omitempty" valid:"type(string),minstringlength(1),required"`
 }
 
 func (c *Config) Validate() (bool, error) {
@@ -69,10 +192,52 @@ func (c *Configuration) validate() (bool, error) {
 			return result, err
 		}
 	}
+
+	if storage := c.Storage; storage != nil {
+		if result, err := storage.validate(); err != nil {
+			return result, err
+		}
+	}
+
+	if metrics := c.Metrics; metrics != nil {
+		if result, err := metrics.validate(); err != nil {
+			return result, err
+		}
+	}
+
+	if seed := c.Seed; seed != nil {
+		if result, err := seed.validate(); err != nil {
+			return result, err
+		}
+	}
+
+	var errs govalidator.Errors
+	for _, serviceName := range c.ServiceNameList {
+		if !isKnownServiceName(serviceName) {
+			errs = append(errs, errors.New("invalid serviceNameList: "+serviceName))
+		}
+	}
+	if len(errs) > 0 {
+		return false, error(errs)
+	}
+
+	if c.HeartbeatTimer < 0 {
+		return false, error(govalidator.Errors{errors.New("invalid heartbeatTimer: should not be negative")})
+	}
+
 	result, err := govalidator.ValidateStruct(c)
 	return result, appendInvalid(err)
 }
 
+func isKnownServiceName(serviceName string) bool {
+	for _, name := range AllServiceNames {
+		if name == serviceName {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Sbi) validate() (bool, error) {
 	govalidator.TagMap["scheme"] = govalidator.Validator(func(str string) bool {
 		return str == "https" || str == "http"
@@ -82,6 +247,39 @@ func (s *Sbi) validate() (bool, error) {
 		if result, err := tls.validate(); err != nil {
 			return result, err
 		}
+		if tls.ClientCa != "" && s.Scheme != models.UriScheme_HTTPS {
+			return false, error(govalidator.Errors{errors.New("invalid tls.clientCa: mutual TLS requires scheme https")})
+		}
+	}
+
+	if unixSocket := s.UnixSocket; unixSocket != nil {
+		if result, err := unixSocket.validate(); err != nil {
+			return result, err
+		}
+	}
+
+	var errs govalidator.Errors
+	if s.DrainPeriod != "" {
+		if period, err := time.ParseDuration(s.DrainPeriod); err != nil || period < 0 {
+			errs = append(errs, fmt.Errorf("invalid sbi.drainPeriod: %s is not a non-negative duration", s.DrainPeriod))
+		}
+	}
+	if s.BindingIPv4 == "" && s.BindingIPv6 == "" && len(s.ListenAddresses) == 0 && !s.UnixSocket.IsOnly() {
+		errs = append(errs, errors.New("invalid sbi: bindingIPv4, bindingIPv6 or listenAddresses is required"))
+	}
+	for _, addr := range s.ListenAddresses {
+		if net.ParseIP(addr) == nil {
+			errs = append(errs, fmt.Errorf("invalid sbi.listenAddresses: %s is not an IP address", addr))
+		}
+	}
+	if len(errs) > 0 {
+		return false, error(errs)
+	}
+
+	if oauth2 := s.OAuth2; oauth2 != nil {
+		if result, err := oauth2.validate(); err != nil {
+			return result, err
+		}
 	}
 
 	result, err := govalidator.ValidateStruct(s)
@@ -89,10 +287,141 @@ func (s *Sbi) validate() (bool, error) {
 }
 
 func (t *Tls) validate() (bool, error) {
+	var errs govalidator.Errors
+	if t.ClientCa == "" {
+		if t.ClientAuth != "" && t.ClientAuth != TlsClientAuthNone {
+			errs = append(errs, fmt.Errorf("invalid tls.clientAuth: %s requires tls.clientCa", t.ClientAuth))
+		}
+		if len(t.ClientAllowList) > 0 {
+			errs = append(errs, errors.New("invalid tls.clientAllowList: requires tls.clientCa"))
+		}
+	} else if t.ClientAuth == TlsClientAuthNone && len(t.ClientAllowList) > 0 {
+		errs = append(errs, errors.New("invalid tls.clientAllowList: client certificates are not requested"))
+	}
+	for i, client := range t.ClientAllowList {
+		if strings.TrimSpace(client.Identity) == "" {
+			errs = append(errs, fmt.Errorf("invalid tls.clientAllowList[%d]: no identity", i))
+		}
+		if len(client.Services) == 0 {
+			errs = append(errs, fmt.Errorf("invalid tls.clientAllowList[%d]: no services", i))
+		}
+		for _, serviceName := range client.Services {
+			if serviceName != ServiceNameManagement && !isKnownServiceName(serviceName) {
+				errs = append(errs, fmt.Errorf("invalid tls.clientAllowList[%d]: unknown service %s", i, serviceName))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return false, error(errs)
+	}
+
 	result, err := govalidator.ValidateStruct(t)
 	return result, err
 }
 
+// GetBindingIPs returns the addresses of the SBI listeners, bindingIPv4 and bindingIPv6 first.
+func (s *Sbi) GetBindingIPs() []string {
+	var ips []string
+	for _, ip := range append([]string{s.BindingIPv4, s.BindingIPv6}, s.ListenAddresses...) {
+		if ip != "" && !slices.Contains(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+func (u *UnixSocket) validate() (bool, error) {
+	if u.Mode != "" {
+		if mode, err := strconv.ParseUint(u.Mode, 8, 32); err != nil || mode > 0o777 {
+			return false, error(govalidator.Errors{fmt.Errorf("invalid unixSocket.mode: %s is not an octal permission", u.Mode)})
+		}
+	}
+
+	result, err := govalidator.ValidateStruct(u)
+	return result, appendInvalid(err)
+}
+
+// GetMode returns the permission of the socket file.
+func (u *UnixSocket) GetMode() os.FileMode {
+	if u.Mode == "" {
+		return NfDefaultUnixSocketMode
+	}
+	// validate has checked the mode
+	mode, _ := strconv.ParseUint(u.Mode, 8, 32)
+	return os.FileMode(mode)
+}
+
+// IsOnly reports whether the SBI is only served on the Unix socket.
+func (u *UnixSocket) IsOnly() bool {
+	return u != nil && u.Only
+}
+
+// GetPem returns the certificate path of the SBI, NfDefaultCertPemPath when tls is omitted.
+func (t *Tls) GetPem() string {
+	if t == nil || t.Pem == "" {
+		return NfDefaultCertPemPath
+	}
+	return t.Pem
+}
+
+// GetKey returns the private key path of the SBI, NfDefaultPrivateKeyPath when tls is omitted.
+func (t *Tls) GetKey() string {
+	if t == nil || t.Key == "" {
+		return NfDefaultPrivateKeyPath
+	}
+	return t.Key
+}
+
+// GetClientAuth returns the client certificate policy of the SBI, none when mutual TLS is off.
+func (t *Tls) GetClientAuth() string {
+	if t == nil || t.ClientCa == "" {
+		return TlsClientAuthNone
+	}
+	if t.ClientAuth == "" {
+		return TlsClientAuthRequire
+	}
+	return t.ClientAuth
+}
+
+func (s *Storage) validate() (bool, error) {
+	result, err := govalidator.ValidateStruct(s)
+	return result, appendInvalid(err)
+}
+
+func (m *Metrics) validate() (bool, error) {
+	result, err := govalidator.ValidateStruct(m)
+	return result, appendInvalid(err)
+}
+
+func (s *Seed) validate() (bool, error) {
+	var errs govalidator.Errors
+	for _, file := range []struct{ field, path string }{
+		{"spyFamily", s.SpyFamily},
+		{"dragonBall", s.DragonBall},
+		{"fortune", s.Fortune},
+		{"timeZone", s.TimeZone},
+	} {
+		switch strings.ToLower(filepath.Ext(file.path)) {
+		case ".yaml", ".yml", ".json":
+			continue
+		}
+		if file.path != "" {
+			errs = append(errs, fmt.Errorf("invalid seed.%s: %s is not a .yaml, .yml or .json file", file.field, file.path))
+		}
+	}
+	if len(errs) > 0 {
+		return false, error(errs)
+	}
+
+	result, err := govalidator.ValidateStruct(s)
+	return result, appendInvalid(err)
+}
+
+func (o *OAuth2) validate() (bool, error) {
+	result, err := govalidator.ValidateStruct(o)
+	return result, err
+}
+
 func appendInvalid(err error) error {
 	var errs govalidator.Errors
 
@@ -108,6 +437,12 @@ func appendInvalid(err error) error {
 	return error(errs)
 }
 
+func (c *Config) Path() string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.path
+}
+
 func (c *Config) GetVersion() string {
 	c.RLock()
 	defer c.RUnlock()
@@ -191,3 +526,113 @@ func (c *Config) GetLogReportCaller() bool {
 	}
 	return c.Logger.ReportCaller
 }
+
+// IsServiceEnabled reports whether the route group is listed in serviceNameList.
+// An empty list enables every service.
+func (c *Config) IsServiceEnabled(serviceName string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration == nil || len(c.Configuration.ServiceNameList) == 0 {
+		return true
+	}
+	for _, name := range c.Configuration.ServiceNameList {
+		if name == serviceName {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) GetServiceNameList() []string {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration == nil {
+		return nil
+	}
+	return slices.Clone(c.Configuration.ServiceNameList)
+}
+
+func (c *Config) SetServiceNameList(serviceNameList []string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Configuration == nil {
+		logger.CfgLog.Warnf("Configuration should not be nil")
+		return
+	}
+	c.Configuration.ServiceNameList = slices.Clone(serviceNameList)
+}
+
+func (c *Config) GetNrfUri() string {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration == nil {
+		return ""
+	}
+	return c.Configuration.NrfUri
+}
+
+func (c *Config) GetHeartbeatTimer() int32 {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration == nil || c.Configuration.HeartbeatTimer == 0 {
+		return NfDefaultHeartbeatTimer
+	}
+	return c.Configuration.HeartbeatTimer
+}
+
+// GetDrainPeriod returns how long the SBI keeps serving with /readyz failing on termination,
+// 0 when drainPeriod is omitted.
+func (c *Config) GetDrainPeriod() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration == nil || c.Configuration.Sbi == nil || c.Configuration.Sbi.DrainPeriod == "" {
+		return 0
+	}
+	// validate has checked the duration
+	period, _ := time.ParseDuration(c.Configuration.Sbi.DrainPeriod)
+	return period
+}
+
+// GetSeed returns a copy of the seed files, nil when every domain uses the built-in data.
+func (c *Config) GetSeed() *Seed {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration == nil || c.Configuration.Seed == nil {
+		return nil
+	}
+	seed := *c.Configuration.Seed
+	return &seed
+}
+
+func (c *Config) SetSeed(seed *Seed) {
+	c.Lock()
+	defer c.Unlock()
+	if c.Configuration == nil {
+		logger.CfgLog.Warnf("Configuration should not be nil")
+		return
+	}
+	c.Configuration.Seed = seed
+}
+
+// GetMetricsBindAddr returns the address of the metrics listener, empty when metrics are disabled.
+func (c *Config) GetMetricsBindAddr() string {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration == nil || c.Configuration.Metrics == nil {
+		return ""
+	}
+	port := c.Configuration.Metrics.Port
+	if port == 0 {
+		port = NfDefaultMetricsPort
+	}
+	return net.JoinHostPort(c.Configuration.Metrics.BindingIPv4, strconv.Itoa(port))
+}
+
+func (c *Config) GetMetricsNamespace() string {
+	c.RLock()
+	defer c.RUnlock()
+	if c.Configuration == nil || c.Configuration.Metrics == nil || c.Configuration.Metrics.Namespace == "" {
+		return NfDefaultMetricsNamespace
+	}
+	return c.Configuration.Metrics.Namespace
+}
@@ -11,7 +11,6 @@ import (
 
 var NfConfig *Config
 
-// TODO: Support configuration update from REST api
 func InitConfigFactory(f string, cfg *Config) error {
 	if f == "" {
 		// Use default config path
@@ -31,7 +30,10 @@ func InitConfigFactory(f string, cfg *Config) error {
 }
 
 func ReadConfig(cfgPath string) (*Config, error) {
-	cfg := &Config{}
+	if cfgPath == "" {
+		cfgPath = NfDefaultConfigPath
+	}
+	cfg := &Config{path: cfgPath}
 	if err := InitConfigFactory(cfgPath, cfg); err != nil {
 		return nil, fmt.Errorf("ReadConfig [%s] Error: %+v", cfgPath, err)
 	}
@@ -41,7 +43,7 @@ func ReadConfig(cfgPath string) (*Config, error) {
 			logger.CfgLog.Errorf("%+v", validErr)
 		}
 		logger.CfgLog.Errorf("[-- PLEASE REFER TO SAMPLE CONFIG FILE COMMENTS --]")
-		return nil, fmt.Errorf("Config validate Error")
+		return nil, fmt.Errorf("Config validate Error: %s", err)
 	}
 	return cfg, nil
 }
@@ -0,0 +1,54 @@
+package factory
+
+import "reflect"
+
+// ReloadResult reports the outcome of a configuration reload.
+type ReloadResult struct {
+	// Applied lists the changed fields that took effect immediately
+	Applied []string `json:"applied"`
+	// RestartRequired lists the changed fields that only take effect after a restart
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// InvalidConfigError reports a reload refused because the config file or its seed files
+// are invalid, the running config is kept.
+type InvalidConfigError struct {
+	Err error
+}
+
+func (e *InvalidConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidConfigError) Unwrap() error {
+	return e.Err
+}
+
+// RestartRequiredChanges returns the fields of newCfg that differ from c and
+// cannot be applied while the NF is running.
+func (c *Config) RestartRequiredChanges(newCfg *Config) []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	var changed []string
+	compare := func(field string, oldValue, newValue any) {
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changed = append(changed, field)
+		}
+	}
+
+	compare("info", c.Info, newCfg.Info)
+
+	oldConfiguration, newConfiguration := c.Configuration, newCfg.Configuration
+	if oldConfiguration == nil || newConfiguration == nil {
+		compare("configuration", oldConfiguration, newConfiguration)
+		return changed
+	}
+	compare("configuration.nfName", oldConfiguration.NfName, newConfiguration.NfName)
+	compare("configuration.sbi", oldConfiguration.Sbi, newConfiguration.Sbi)
+	compare("configuration.nrfUri", oldConfiguration.NrfUri, newConfiguration.NrfUri)
+	compare("configuration.heartbeatTimer", oldConfiguration.HeartbeatTimer, newConfiguration.HeartbeatTimer)
+	compare("configuration.storage", oldConfiguration.Storage, newConfiguration.Storage)
+	compare("configuration.metrics", oldConfiguration.Metrics, newConfiguration.Metrics)
+	return changed
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"github.com/Alonza0314/nf-example/pkg/app"
+)
+
+// Readiness reports whether ANYA can take traffic. It turns not ready as soon as the
+// termination starts, so load balancers stop routing to ANYA before the server shuts down.
+func (a *NfApp) Readiness() app.Readiness {
+	checks := []app.HealthCheck{
+		a.lifecycleCheck(),
+		newHealthCheck("config", a.cfg != nil && a.cfg.Configuration != nil, "config is not loaded"),
+		newHealthCheck("context", a.nfCtx != nil && a.nfCtx.Storage != nil, "context is not initialized"),
+		a.storageCheck(),
+	}
+	if a.nfCtx != nil && a.nfCtx.NrfUri != "" {
+		checks = append(checks, newHealthCheck("nrf", a.consumer != nil && a.consumer.IsRegistered(),
+			"not registered to the NRF"))
+	}
+	return app.NewReadiness(checks...)
+}
+
+func newHealthCheck(name string, ready bool, detail string) app.HealthCheck {
+	check := app.HealthCheck{Name: name, Ready: ready}
+	if !ready {
+		check.Detail = detail
+	}
+	return check
+}
+
+func (a *NfApp) lifecycleCheck() app.HealthCheck {
+	switch {
+	case a.terminating.Load():
+		return newHealthCheck("lifecycle", false, "terminating")
+	case !a.started.Load():
+		return newHealthCheck("lifecycle", false, "starting")
+	}
+	return newHealthCheck("lifecycle", true, "")
+}
+
+func (a *NfApp) storageCheck() app.HealthCheck {
+	if a.nfCtx == nil || a.nfCtx.Storage == nil {
+		return newHealthCheck("storage", false, "storage is not open")
+	}
+	if err := a.nfCtx.Storage.Ping(); err != nil {
+		return newHealthCheck("storage", false, err.Error())
+	}
+	return newHealthCheck("storage", true, "")
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/sbi"
+	"github.com/Alonza0314/nf-example/internal/sbi/consumer"
+	"github.com/Alonza0314/nf-example/internal/storage"
+	"github.com/Alonza0314/nf-example/pkg/app"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Readiness(t *testing.T) {
+	st, err := storage.NewFileStorage(filepath.Join(t.TempDir(), "anya.db"))
+	require.NoError(t, err)
+	nfCtx := &nf_context.NFContext{}
+	nfCtx.UseStorage(st)
+	a := &NfApp{
+		cfg:   &factory.Config{Configuration: &factory.Configuration{}},
+		nfCtx: nfCtx,
+	}
+
+	notReady := func(readiness app.Readiness) map[string]string {
+		failed := make(map[string]string)
+		for _, check := range readiness.Checks {
+			if !check.Ready {
+				failed[check.Name] = check.Detail
+			}
+		}
+		return failed
+	}
+
+	readiness := a.Readiness()
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, map[string]string{"lifecycle": "starting"}, notReady(readiness))
+
+	a.started.Store(true)
+	readiness = a.Readiness()
+	assert.True(t, readiness.Ready)
+	assert.Len(t, readiness.Checks, 4, "the NRF is only checked when nrfUri is set")
+
+	a.terminating.Store(true)
+	require.NoError(t, st.Close())
+	readiness = a.Readiness()
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, map[string]string{"lifecycle": "terminating", "storage": "storage is closed"}, notReady(readiness))
+}
+
+// newTestApp returns an app with the SBI on a free local port and the readyz URL of the SBI,
+// its server is not started yet.
+func newTestApp(t *testing.T, drainPeriod string, st storage.Storage) (*NfApp, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	nfCtx := &nf_context.NFContext{}
+	nfCtx.UseStorage(st)
+	nfCtx.MessageFeed = nf_context.NewMessageFeed(nf_context.MessageFeedCapacity)
+	a := &NfApp{
+		cfg: &factory.Config{Configuration: &factory.Configuration{
+			Sbi: &factory.Sbi{
+				Scheme:      "http",
+				BindingIPv4: "127.0.0.1",
+				Port:        port,
+				DrainPeriod: drainPeriod,
+			},
+		}},
+		nfCtx:    nfCtx,
+		drainCut: make(chan struct{}, 1),
+	}
+	a.consumer, err = consumer.NewConsumer(a)
+	require.NoError(t, err)
+	a.sbiServer = sbi.NewServer(a, "")
+	return a, fmt.Sprintf("http://127.0.0.1:%d/readyz", port)
+}
+
+func readyzStatus(readyz string) int {
+	resp, err := http.Get(readyz)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func Test_TerminateDrainsWithReadinessFailing(t *testing.T) {
+	a, readyz := newTestApp(t, "500ms", storage.NewMemoryStorage())
+	a.sbiServer.Run(&a.wg)
+	a.started.Store(true)
+
+	require.Eventually(t, func() bool {
+		return readyzStatus(readyz) == http.StatusOK
+	}, 2*time.Second, 20*time.Millisecond)
+
+	terminated := make(chan struct{})
+	go func() {
+		a.terminateProcedure()
+		close(terminated)
+	}()
+
+	// the listener is still up during the drain period and reports not ready
+	assert.Eventually(t, func() bool {
+		return readyzStatus(readyz) == http.StatusServiceUnavailable
+	}, 400*time.Millisecond, 20*time.Millisecond)
+
+	<-terminated
+	a.wg.Wait()
+	_, err := http.Get(readyz)
+	assert.Error(t, err, "the server is down after the drain period")
+}
+
+func Test_EndDrainCutsTheDrainPeriodShort(t *testing.T) {
+	a, readyz := newTestApp(t, "1h", storage.NewMemoryStorage())
+	a.sbiServer.Run(&a.wg)
+	a.started.Store(true)
+	require.Eventually(t, func() bool {
+		return readyzStatus(readyz) == http.StatusOK
+	}, 2*time.Second, 20*time.Millisecond)
+
+	terminated := make(chan struct{})
+	go func() {
+		a.terminateProcedure()
+		close(terminated)
+	}()
+	require.Eventually(t, func() bool {
+		return readyzStatus(readyz) == http.StatusServiceUnavailable
+	}, 2*time.Second, 20*time.Millisecond)
+
+	a.EndDrain()
+	select {
+	case <-terminated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the drain period is not cut short")
+	}
+	a.wg.Wait()
+}
+
+func Test_StartReturnsAfterStorageClose(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "anya.db")
+	st, err := storage.NewFileStorage(dbPath)
+	require.NoError(t, err)
+	a, readyz := newTestApp(t, "", st)
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	stopped := make(chan struct{})
+	go func() {
+		a.Start()
+		close(stopped)
+	}()
+	require.Eventually(t, func() bool {
+		return readyzStatus(readyz) == http.StatusOK
+	}, 2*time.Second, 20*time.Millisecond)
+	require.NoError(t, st.Put("tasks", "1", []byte(`"write the report"`)))
+
+	a.Terminate()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start does not return after Terminate")
+	}
+
+	// Start only returns once the file backend is closed and its records are on disk
+	assert.EqualError(t, st.Ping(), "storage is closed")
+	reopened, err := storage.NewFileStorage(dbPath)
+	require.NoError(t, err)
+	defer reopened.Close()
+	value, found, err := reopened.Get("tasks", "1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, `"write the report"`, string(value))
+}
+
+func Test_StartReturnsAfterUnixSocketRemoval(t *testing.T) {
+	a, readyz := newTestApp(t, "", storage.NewMemoryStorage())
+	socketPath := filepath.Join(t.TempDir(), "anya.sock")
+	a.cfg.Configuration.Sbi.UnixSocket = &factory.UnixSocket{Path: socketPath}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	stopped := make(chan struct{})
+	go func() {
+		a.Start()
+		close(stopped)
+	}()
+	require.Eventually(t, func() bool {
+		return readyzStatus(readyz) == http.StatusOK
+	}, 2*time.Second, 20*time.Millisecond)
+	_, err := os.Lstat(socketPath)
+	require.NoError(t, err)
+
+	a.Terminate()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start does not return after Terminate")
+	}
+	_, err = os.Lstat(socketPath)
+	assert.True(t, os.IsNotExist(err), "the socket file is removed before Start returns")
+}
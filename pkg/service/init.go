@@ -6,10 +6,13 @@ import (
 	"os"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	nf_context "github.com/Alonza0314/nf-example/internal/context"
 	"github.com/Alonza0314/nf-example/internal/logger"
 	"github.com/Alonza0314/nf-example/internal/sbi"
+	"github.com/Alonza0314/nf-example/internal/sbi/consumer"
 	"github.com/Alonza0314/nf-example/internal/sbi/processor"
 	"github.com/Alonza0314/nf-example/pkg/app"
 	"github.com/Alonza0314/nf-example/pkg/factory"
@@ -24,19 +27,32 @@ type NfApp struct {
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 
+	// reloadMu serializes config reloads from SIGHUP and the management API
+	reloadMu sync.Mutex
+
+	// started and terminating drive the lifecycle check of Readiness
+	started     atomic.Bool
+	terminating atomic.Bool
+	// drainCut ends the drain period early, see EndDrain
+	drainCut chan struct{}
+
 	sbiServer *sbi.Server
 	processor *processor.Processor
+	consumer  *consumer.Consumer
 }
 
 var _ app.App = &NfApp{}
 
 func NewApp(ctx context.Context, cfg *factory.Config, tlsKeyLogPath string) (*NfApp, error) {
-	nf_context.InitNfContext()
+	if err := nf_context.InitNfContext(); err != nil {
+		return nil, err
+	}
 
 	nf := &NfApp{
-		cfg:   cfg,
-		wg:    sync.WaitGroup{},
-		nfCtx: nf_context.GetSelf(),
+		cfg:      cfg,
+		wg:       sync.WaitGroup{},
+		nfCtx:    nf_context.GetSelf(),
+		drainCut: make(chan struct{}, 1),
 	}
 
 	nf.SetLogEnable(cfg.GetLogEnable())
@@ -54,6 +70,12 @@ func NewApp(ctx context.Context, cfg *factory.Config, tlsKeyLogPath string) (*Nf
 	}
 	nf.processor = processor
 
+	consumer, err := consumer.NewConsumer(nf)
+	if err != nil {
+		return nf, err
+	}
+	nf.consumer = consumer
+
 	return nf, nil
 }
 
@@ -69,6 +91,10 @@ func (a *NfApp) Processor() *processor.Processor {
 	return a.processor
 }
 
+func (a *NfApp) Consumer() *consumer.Consumer {
+	return a.consumer
+}
+
 func (a *NfApp) SetLogEnable(enable bool) {
 	logger.MainLog.Infof("Log enable is set to [%v]", enable)
 	if enable && logger.Log.Out == os.Stderr {
@@ -115,12 +141,25 @@ func (a *NfApp) Start() {
 	}()
 
 	a.sbiServer.Run(&a.wg)
+	go a.listenReloadSignal(a.ctx)
+	a.started.Store(true)
+
+	if a.nfCtx.NrfUri != "" {
+		if err := a.consumer.RegisterNFInstance(a.ctx); err != nil {
+			logger.InitLog.Errorf("Register NF instance to NRF failed: %+v", err)
+		} else {
+			a.consumer.StartHeartbeat(a.ctx, &a.wg)
+		}
+	}
 
+	// tracked by wg, so Wait only returns once the termination procedure is over
+	a.wg.Add(1)
 	go a.listenShutdown(a.ctx)
 	a.Wait()
 }
 
 func (a *NfApp) listenShutdown(ctx context.Context) {
+	defer a.wg.Done()
 	<-ctx.Done()
 	a.terminateProcedure()
 }
@@ -129,9 +168,48 @@ func (a *NfApp) Terminate() {
 	a.cancel()
 }
 
+// EndDrain cuts the drain period of the termination short, the server shuts down right away.
+func (a *NfApp) EndDrain() {
+	select {
+	case a.drainCut <- struct{}{}:
+	default:
+	}
+}
+
 func (a *NfApp) terminateProcedure() {
 	logger.MainLog.Infof("Terminating ANYA...")
+	// /readyz fails from now on, the server keeps serving for the drain period so the
+	// orchestrator sees it and sends new traffic elsewhere while the open requests finish
+	a.terminating.Store(true)
+	if a.consumer.IsRegistered() {
+		a.deregisterFromNrf()
+	}
+	if drainPeriod := a.cfg.GetDrainPeriod(); drainPeriod > 0 {
+		logger.MainLog.Infof("Draining for %s before shutdown", drainPeriod)
+		select {
+		case <-time.After(drainPeriod):
+		case <-a.drainCut:
+			logger.MainLog.Infof("Drain period cut short")
+		}
+	}
+	// end the /msg streams, the server waits for open requests on shutdown
+	a.nfCtx.MessageFeed.Close()
 	a.sbiServer.Shutdown()
+	if err := a.nfCtx.Storage.Close(); err != nil {
+		logger.MainLog.Errorf("Close storage failed: %+v", err)
+	}
+}
+
+func (a *NfApp) deregisterFromNrf() {
+	const deregisterTimeout time.Duration = 2 * time.Second
+
+	// a.ctx is already cancelled at this point
+	ctx, cancel := context.WithTimeout(context.Background(), deregisterTimeout)
+	defer cancel()
+
+	if err := a.consumer.SendDeregisterNFInstance(ctx); err != nil {
+		logger.MainLog.Errorf("Deregister NF instance from NRF failed: %+v", err)
+	}
 }
 
 func (a *NfApp) Wait() {
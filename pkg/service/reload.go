@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"syscall"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/logger"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+)
+
+// ReloadConfig reads the config file again and applies the fields that can change at
+// runtime. Nothing is applied when the new config is invalid or its seed cannot be stored.
+func (a *NfApp) ReloadConfig() (*factory.ReloadResult, error) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	logger.CfgLog.Infof("Reload config from [%s]", a.cfg.Path())
+	newCfg, err := factory.ReadConfig(a.cfg.Path())
+	if err != nil {
+		return nil, &factory.InvalidConfigError{Err: err}
+	}
+	// the seed files are read before anything is applied, a bad file keeps the running config
+	var seedData *nf_context.SeedData
+	seed := newCfg.GetSeed()
+	seedChanged := !reflect.DeepEqual(seed, a.cfg.GetSeed())
+	if seedChanged {
+		if seedData, err = nf_context.LoadSeedData(seed); err != nil {
+			return nil, &factory.InvalidConfigError{Err: err}
+		}
+	}
+
+	result := &factory.ReloadResult{
+		Applied:         []string{},
+		RestartRequired: []string{},
+	}
+	result.RestartRequired = append(result.RestartRequired, a.cfg.RestartRequiredChanges(newCfg)...)
+
+	// seeding is the only step that can fail, it goes first so a failure leaves the
+	// running config as it was. The new seed files only fill the collections that are empty
+	if seedChanged {
+		if err = a.nfCtx.SeedWith(seedData); err != nil {
+			return nil, err
+		}
+		a.cfg.SetSeed(seed)
+		result.Applied = append(result.Applied, "configuration.seed")
+	}
+
+	if enable := newCfg.GetLogEnable(); enable != a.cfg.GetLogEnable() {
+		a.SetLogEnable(enable)
+		result.Applied = append(result.Applied, "logger.enable")
+	}
+	if level := newCfg.GetLogLevel(); level != a.cfg.GetLogLevel() {
+		a.SetLogLevel(level)
+		result.Applied = append(result.Applied, "logger.level")
+	}
+	if reportCaller := newCfg.GetLogReportCaller(); reportCaller != a.cfg.GetLogReportCaller() {
+		a.SetReportCaller(reportCaller)
+		result.Applied = append(result.Applied, "logger.reportCaller")
+	}
+	// Route groups are gated per request, the services in the NRF profile stay as registered
+	if serviceNameList := newCfg.GetServiceNameList(); !slices.Equal(serviceNameList, a.cfg.GetServiceNameList()) {
+		a.cfg.SetServiceNameList(serviceNameList)
+		result.Applied = append(result.Applied, "configuration.serviceNameList")
+	}
+
+	for _, field := range result.RestartRequired {
+		logger.CfgLog.Warnf("Config [%s] changed, restart ANYA to apply it", field)
+	}
+	logger.CfgLog.Infof("Config reloaded, applied %v", result.Applied)
+	return result, nil
+}
+
+func (a *NfApp) listenReloadSignal(ctx context.Context) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+			logger.MainLog.Infof("Received SIGHUP")
+			if _, err := a.ReloadConfig(); err != nil {
+				logger.CfgLog.Errorf("Reload config failed: %+v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	nf_context "github.com/Alonza0314/nf-example/internal/context"
+	"github.com/Alonza0314/nf-example/internal/storage"
+	"github.com/Alonza0314/nf-example/pkg/factory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeReloadConfig writes a config file logging at level, extra is added to its configuration section.
+func writeReloadConfig(t *testing.T, path string, level string, extra string) {
+	t.Helper()
+	content := `info:
+  version: 1.0.0
+configuration:
+  nfName: ANYA
+  sbi:
+    scheme: http
+    bindingIPv4: 127.0.0.163
+    port: 8000
+` + extra + `logger:
+  enable: true
+  level: ` + level + `
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func Test_ReloadConfigSeed(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "nfcfg.yaml")
+	writeReloadConfig(t, cfgPath, "info", "")
+	cfg, err := factory.ReadConfig(cfgPath)
+	require.NoError(t, err)
+
+	st, err := storage.NewFileStorage(filepath.Join(dir, "anya.db"))
+	require.NoError(t, err)
+	nfCtx := &nf_context.NFContext{}
+	nfCtx.UseStorage(st)
+	a := &NfApp{cfg: cfg, nfCtx: nfCtx}
+
+	serviceNameList := cfg.GetServiceNameList()
+	// a failed reload applies none of the changed fields
+	assertUnchanged := func(t *testing.T) {
+		t.Helper()
+		assert.Equal(t, "info", a.cfg.GetLogLevel())
+		assert.Equal(t, serviceNameList, a.cfg.GetServiceNameList())
+	}
+
+	// a closed storage fails any seeding, so a successful reload did not seed
+	require.NoError(t, st.Close())
+
+	t.Run("Unchanged Seed Is Not Applied", func(t *testing.T) {
+		result, err := a.ReloadConfig()
+		require.NoError(t, err)
+		assert.Empty(t, result.Applied)
+	})
+
+	t.Run("Seeding Failure", func(t *testing.T) {
+		seedPath := filepath.Join(dir, "spyfamily.json")
+		require.NoError(t, os.WriteFile(seedPath,
+			[]byte(`[{"firstName": "Franky", "lastName": "Franklin"}]`), 0o600))
+		writeReloadConfig(t, cfgPath, "debug", "  serviceNameList:\n    - nanya-default\n  seed:\n    spyFamily: "+seedPath+"\n")
+
+		_, err := a.ReloadConfig()
+		require.ErrorContains(t, err, "storage is closed")
+		var invalid *factory.InvalidConfigError
+		assert.False(t, errors.As(err, &invalid), "a storage failure is not an invalid config")
+		assert.Nil(t, a.cfg.GetSeed(), "the seed is only recorded once it is applied")
+		assertUnchanged(t)
+	})
+
+	t.Run("Invalid Seed File", func(t *testing.T) {
+		seedPath := filepath.Join(dir, "broken.json")
+		require.NoError(t, os.WriteFile(seedPath, []byte(`[{"firstName": "Franky"}]`), 0o600))
+		writeReloadConfig(t, cfgPath, "debug", "  serviceNameList:\n    - nanya-default\n  seed:\n    spyFamily: "+seedPath+"\n")
+
+		_, err := a.ReloadConfig()
+		var invalid *factory.InvalidConfigError
+		require.ErrorAs(t, err, &invalid)
+		assert.Contains(t, err.Error(), "firstName and lastName are required")
+		assertUnchanged(t)
+	})
+
+	t.Run("Invalid Config", func(t *testing.T) {
+		writeReloadConfig(t, cfgPath, "info", "  serviceNameList:\n    - nanya-unknown\n")
+
+		_, err := a.ReloadConfig()
+		var invalid *factory.InvalidConfigError
+		require.ErrorAs(t, err, &invalid)
+	})
+}